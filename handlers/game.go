@@ -28,6 +28,10 @@ func SetupGameRoutes(app *fiber.App, gameService *services.GameService) {
 
 	secured.Put("/games/:id/featured/:action", gameService.SetGameFeatured)
 
+	// 🔒 Admin-only routes
+	admin := secured.Group("/admin")
+	admin.Post("/games/:id/webhook-secret", middleware.RequireRole("admin"), gameService.RotateWebhookSecret)
+
 	// ✅ Review routes — auth required
 	secured.Post("/games/:id/reviews", gameService.CreateReview)
 	secured.Get("/games/:id/reviews", gameService.GetReviewsByGame)
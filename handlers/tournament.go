@@ -6,13 +6,23 @@ import (
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupTournamentRoutes(app *fiber.App, tournamentService *services.TournamentService, pairingService *services.PairingService) {
+func SetupTournamentRoutes(app *fiber.App, tournamentService *services.TournamentService, pairingService *services.PairingService, standingsService *services.StandingsService) {
 	// 🔓 Public routes for users (only published tournaments)
 	// Add /api/v1 prefix to match your frontend calls
 	app.Get("/tournaments/published", tournamentService.GetAllPublishedTournaments) // NEW
 	app.Get("/tournaments/published/:id", tournamentService.GetPublishedTournamentByID) // NEW
+	app.Get("/tournaments/published/:id/leaderboard", standingsService.GetTournamentLeaderboard)
+	app.Get("/tournaments/published/:id/leaderboard/stream", standingsService.StreamLeaderboardSSE) // SSE, supports Last-Event-ID
+	app.Get("/tournaments/published/:id/prizes", tournamentService.GetPublishedTournamentPrizes)
+	app.Get("/tournaments/published/:id/matches/:match_id/bracket", pairingService.GetPublishedMatchBracket)
 	app.Get("/match-types", tournamentService.GetSupportedMatchTypes)
+	app.Get("/players/:user_id/ratings", tournamentService.GetPlayerRatings)
+	app.Get("/games/:game_id/ratings", tournamentService.GetGameRatingLeaderboard)
 	app.Get("/users/search", tournamentService.SearchUsers)
+	app.Get("/currencies", tournamentService.GetCurrencies) // supported currencies and display rates
+
+	// 🔏 Game server score webhook (HMAC signed, no user context)
+	app.Post("/webhooks/games/:game_id/scores", tournamentService.ReceiveScoreWebhook)
 
 	// 🔐 Authenticated routes
 	secured := app.Group("/", middleware.UserContextMiddleware())
@@ -34,15 +44,42 @@ func SetupTournamentRoutes(app *fiber.App, tournamentService *services.Tournamen
 	secured.Post("/tournaments/:id/publish/now", tournamentService.PublishNow) // Publish immediately
 	secured.Post("/tournaments/:id/publish/schedule", tournamentService.SchedulePublish) // Schedule for later
 	secured.Post("/tournaments/:id/publish/cancel", tournamentService.CancelScheduledPublish) // Cancel scheduled publish
+	secured.Post("/tournaments/:id/finalize", tournamentService.FinalizeTournamentEndpoint) // Write participations + XP (idempotent)
+
+	// Prize table
+	secured.Get("/tournaments/:id/prizes", tournamentService.GetTournamentPrizes)
+	secured.Put("/tournaments/:id/prizes", tournamentService.SetTournamentPrizes)
 	
 	// Tournament subscriptions
 	secured.Post("/tournaments/:id/subscribe", tournamentService.SubscribeToTournament)
+	secured.Post("/tournaments/:id/verify-payment", tournamentService.VerifySubscriptionPayment)
 	secured.Get("/tournaments/:id/subscribers", tournamentService.GetTournamentSubscribers)
 	
+	// Teams (captain manages the roster; team-based tournaments take team subscriptions)
+	secured.Post("/teams", tournamentService.CreateTeam)
+	secured.Get("/teams/:team_id", tournamentService.GetTeam)
+	secured.Put("/teams/:team_id", tournamentService.UpdateTeam)
+	secured.Post("/teams/:team_id/members", tournamentService.AddTeamMember)
+	secured.Delete("/teams/:team_id/members/:user_id", tournamentService.RemoveTeamMember)
+	secured.Patch("/teams/:team_id/captain", tournamentService.TransferTeamCaptain)
+	secured.Get("/users/me/teams", tournamentService.GetMyTeams)
+	secured.Get("/users/me/ratings", tournamentService.GetPlayerRatings)
+	
 	// Subscription management
 	secured.Patch("/tournaments/:tournament_id/subscribers/:user_id/suspend", tournamentService.SuspendSubscription)
 	secured.Post("/tournaments/:tournament_id/subscribers/:user_id/revoke", tournamentService.RevokeSubscription)
 	secured.Post("/tournaments/:tournament_id/subscribers/:user_id/refund", tournamentService.RefundSubscription)
+	secured.Post("/tournaments/:tournament_id/subscribers/:user_id/payment-failed", tournamentService.FailSubscriptionPayment)
+	secured.Get("/tournaments/:id/refunds", tournamentService.GetTournamentRefunds)
+	secured.Post("/tournaments/:id/refunds/bulk", tournamentService.RefundAllSubscriptions) // cancelled tournaments only
+
+	// Ledger (double-entry; amounts in minor units)
+	secured.Get("/tournaments/:id/ledger", tournamentService.GetTournamentLedger)
+	secured.Get("/users/me/ledger", tournamentService.GetMyLedger)
+
+	// Waitlist (full tournaments queue subscribers; freed slots are offered in order)
+	secured.Get("/tournaments/:id/waitlist", tournamentService.GetWaitlist)
+	secured.Delete("/tournaments/:id/waitlist", tournamentService.LeaveWaitlist)
 
 	// Structure: Batches
 	secured.Get("/tournaments/:id/structure", tournamentService.GetTournamentStructure)
@@ -63,6 +100,9 @@ func SetupTournamentRoutes(app *fiber.App, tournamentService *services.Tournamen
 	secured.Put("/tournaments/:id/rounds/:round_id", tournamentService.UpdateRound)
 	secured.Delete("/tournaments/:id/rounds/:round_id", tournamentService.DeleteRound)
 
+	// Scores: Leaderboard submissions per round
+	secured.Post("/tournaments/:id/rounds/:round_id/scores", tournamentService.SubmitRoundScore)
+
 	// Pairing endpoints
 	secured.Post("/matches/:match_id/pairings", pairingService.GeneratePairings)
 	secured.Put("/pairings/:pairing_id", pairingService.UpdatePairings)
@@ -71,6 +111,33 @@ func SetupTournamentRoutes(app *fiber.App, tournamentService *services.Tournamen
 	secured.Post("/pairings/:pairing_id/reject", pairingService.RejectPairings)
 	secured.Get("/matches/:match_id/pairings/status", pairingService.GetPairingStatus)
 	secured.Get("/matches/:match_id/pairings/history", pairingService.GetPairingHistory)
+	secured.Get("/pairings/:pairing_id/results", pairingService.GetPairingResults)
+	secured.Post("/pairings/:pairing_id/results", pairingService.RecordPairingResult)
+	secured.Get("/matches/:match_id/swiss/standings", pairingService.GetSwissStandings)
+
+	// Check-in
+	secured.Post("/tournaments/:id/check-in", tournamentService.CheckIn)
+	secured.Delete("/tournaments/:id/check-in", tournamentService.UndoCheckIn)
+	secured.Get("/tournaments/:id/check-ins", tournamentService.GetCheckIns)
+
+	// Seeding endpoints
+	secured.Get("/tournaments/:id/seeding", pairingService.GetSeeding)
+	secured.Post("/tournaments/:id/seeding/generate", pairingService.GenerateSeeding)
+	secured.Put("/tournaments/:id/seeding/:user_id", pairingService.OverrideSeed)
+	secured.Delete("/tournaments/:id/seeding/:user_id", pairingService.ClearSeedOverride)
+
+	// Head-to-head result reporting (both players report; conflicts go to admin)
+	secured.Post("/matches/:match_id/results", pairingService.ReportMatchResult)
+	secured.Get("/matches/:match_id/results", pairingService.GetMatchResults)
+
+	// Elimination brackets
+	secured.Get("/matches/:match_id/bracket", pairingService.GetMatchBracket)
+	secured.Post("/brackets/:bracket_id/nodes/:node_id/result", pairingService.ReportBracketNodeResult)
+
+	// Free-for-all lobbies
+	secured.Get("/matches/:match_id/lobbies", pairingService.GetMatchLobbies)
+	secured.Get("/matches/:match_id/entrants", pairingService.GetMatchEntrants)
+	secured.Post("/lobbies/:lobby_id/results", pairingService.RecordLobbyResult)
 
 	// Waiver endpoints
 	secured.Get("/users/me/waivers", tournamentService.GetUserWaiversEndpoint)
@@ -87,4 +154,32 @@ func SetupTournamentRoutes(app *fiber.App, tournamentService *services.Tournamen
 	admin.Get("/waivers", tournamentService.GetAllWaivers)
 	admin.Put("/waivers/:id", tournamentService.UpdateWaiver)
 	admin.Delete("/waivers/:id", tournamentService.DeleteWaiver)
+
+	// Match type configs
+	admin.Get("/match-types", tournamentService.GetMatchTypeConfigs)
+	admin.Post("/match-types", tournamentService.CreateMatchTypeConfig)
+	admin.Put("/match-types/:id", tournamentService.UpdateMatchTypeConfig)
+	admin.Delete("/match-types/:id", tournamentService.DeleteMatchTypeConfig)
+
+	// Score webhook dead letters
+	admin.Get("/webhooks/dead-letters", tournamentService.GetScoreWebhookDeadLetters)
+
+	// Refund payouts
+	admin.Post("/refunds/:refund_id/retry", tournamentService.RetryRefund)
+
+	// Ledger accounts and statements (?from=&to= as YYYY-MM-DD)
+	admin.Get("/ledger/accounts", tournamentService.GetLedgerAccounts)
+	admin.Get("/ledger/accounts/:account_id/statement", tournamentService.GetLedgerStatement)
+
+	// Payment reconciliation (?date=YYYY-MM-DD, default yesterday UTC)
+	admin.Get("/reconciliation", tournamentService.GetReconciliationReports)
+	admin.Post("/reconciliation/run", tournamentService.RunReconciliation)
+
+	// Display-only currency conversion rates
+	admin.Put("/currency-rates", tournamentService.SetCurrencyRate)
+	admin.Delete("/currency-rates/:rate_id", tournamentService.DeleteCurrencyRate)
+
+	// Result disputes
+	admin.Get("/results/disputes", pairingService.GetResultDisputes)
+	admin.Post("/results/:result_id/resolve", pairingService.ResolveMatchResult)
 }
\ No newline at end of file
@@ -74,11 +74,17 @@ func main() {
 		log.Fatal("failed to initialize R2 client:", err)
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	// TranslateError maps unique violations to gorm.ErrDuplicatedKey (used by the subscribe race guard)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("failed to connect to database:", err)
 	}
 
+	// Unique indexes can't be created over existing duplicates
+	if err := models.DedupeUniqueIndexes(db); err != nil {
+		log.Fatal("failed to dedupe before migration:", err)
+	}
+
 	// 🔧 UPDATED: Include all new models for migration
 	if err := db.AutoMigrate(
 		&models.Game{},
@@ -94,6 +100,9 @@ func main() {
 		&models.TournamentMatch{},       // Note: Changed from &models.Match{}
 		&models.TournamentRound{},
 		&models.LeaderboardEntry{},
+		&models.ScoreWebhookNonce{},
+		&models.ScoreWebhookDeadLetter{},
+		&models.TournamentPrize{},
 		
 		// User Models
 		&models.UserWaiver{},
@@ -112,9 +121,33 @@ func main() {
 		&models.MatchTypeConfig{},
 		&models.MatchPairing{},
 		&models.PlayerSeeding{},
+		&models.Bracket{},
+		&models.BracketNode{},
+		&models.PairingResult{},
+		&models.MatchResult{},
+		&models.MatchResultReport{},
+		&models.Team{},
+		&models.TeamMember{},
+		&models.Lobby{},
+		&models.LobbyPlayer{},
+		&models.MatchEntrant{},
+		&models.PlayerRating{},
+		&models.RatingEvent{},
+		&models.CheckIn{},
+		&models.WaitlistEntry{},
+		&models.ReconciliationReport{},
+		&models.SubscriptionRefund{},
+		&models.LedgerAccount{},
+		&models.LedgerTransaction{},
+		&models.LedgerPosting{},
+		&models.CurrencyRate{},
 	); err != nil {
 		log.Fatal("failed to migrate database:", err)
 	}
+	// Amounts moved from float columns to integer minor units
+	if err := models.BackfillMinorUnits(db); err != nil {
+		log.Fatal("failed to backfill minor-unit amounts:", err)
+	}
 
 	if err := utils.EnsureUploadDir(); err != nil {
 		log.Fatal("failed to ensure upload dir:", err)
@@ -123,10 +156,14 @@ func main() {
 	// Initialize services
 	gameService := services.NewGameService(db)
 	tournamentService := services.NewTournamentService(db)
+	if err := tournamentService.SeedMatchTypeConfigs(); err != nil {
+		log.Fatal("failed to seed match types:", err)
+	}
 	
 	// 🔧 NEW: Initialize Pairing Service
 	pairingService := services.NewPairingService(db)
 	
+	standingsService := services.NewStandingsService(db)
 	progressionService := services.NewProgressionService(db)
 	badgeService := services.NewBadgeService(db)
 	rewardService := services.NewRewardService(db)
@@ -159,11 +196,29 @@ func main() {
 	// Start Game Service Publish Scheduler
 	gameService.StartPublishScheduler()
 
+	// Start Tournament Lifecycle Scheduler (publish → activate → complete)
+	tournamentService.StartLifecycleScheduler()
+
+	// Start result confirmation timeout (auto-confirms lone result reports)
+	pairingService.StartResultConfirmationScheduler()
+
+	// Start check-in scheduler (forfeits no-shows when a check-in window closes)
+	tournamentService.StartCheckInScheduler()
+
+	// Start waitlist scheduler (expires held slots and promotes the next in line)
+	tournamentService.StartWaitlistScheduler()
+
+	// Start payment reconciliation (expires stale pending payments, writes daily reports)
+	tournamentService.StartPaymentReconciliationScheduler()
+
+	// Start score webhook cleanup (prunes nonces that have left the replay window)
+	tournamentService.StartScoreWebhookCleanupScheduler()
+
 	// ✅ Setup routes — now with pairing service
 	handlers.SetupGameRoutes(app, gameService)
 	
-	// 🔧 UPDATED: Pass both tournamentService and pairingService
-	handlers.SetupTournamentRoutes(app, tournamentService, pairingService)
+	// 🔧 UPDATED: Pass tournament, pairing and standings services
+	handlers.SetupTournamentRoutes(app, tournamentService, pairingService, standingsService)
 	
 	handlers.SetupProgressionRoutes(app, progressionService, badgeService)
 
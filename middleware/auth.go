@@ -51,4 +51,23 @@ func UserContextMiddleware() fiber.Handler {
 
 		return c.Next() // Proceed to the next handler/route
 	}
+}
+
+// RequireRole allows the request only if UserContextMiddleware found one of roles (case-insensitive)
+// in X-User-Roles. Chain it after UserContextMiddleware.
+func RequireRole(roles ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRoles, _ := c.Locals("user_roles").([]string)
+		for _, have := range userRoles {
+			for _, want := range roles {
+				if strings.EqualFold(have, want) {
+					return c.Next()
+				}
+			}
+		}
+		log.Printf("[PUB-SVC] [USER_CTX] UserID=%v lacks role %v for %s", c.Locals("user_id"), roles, c.Path())
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "insufficient role",
+		})
+	}
 }
\ No newline at end of file
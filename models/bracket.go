@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// Bracket sections
+const (
+	BracketSectionWinners         = "winners"
+	BracketSectionLosers          = "losers"
+	BracketSectionGrandFinal      = "grand_final"
+	BracketSectionGrandFinalReset = "grand_final_reset"
+)
+
+// Bracket statuses
+const (
+	BracketStatusActive    = "active"
+	BracketStatusCompleted = "completed"
+)
+
+// Bracket node statuses
+const (
+	BracketNodePending   = "pending"   // waiting for one or both players
+	BracketNodeReady     = "ready"     // both players known, waiting for a result
+	BracketNodeCompleted = "completed" // decided (played, or a walkover against a bye)
+	BracketNodeSkipped   = "skipped"   // never played (bye vs bye, or unused grand final reset)
+)
+
+// Bracket is the persisted elimination tree for one TournamentMatch
+type Bracket struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	TournamentID string     `json:"tournament_id" gorm:"not null;index"`
+	MatchID      string     `json:"match_id" gorm:"not null;uniqueIndex"`
+	PairingID    string     `json:"pairing_id" gorm:"index"`        // published MatchPairing the bracket was built from
+	Format       string     `json:"format"`                         // progression type: SINGLE_ELIMINATION, DOUBLE_ELIMINATION
+	Size         int        `json:"size"`                           // slots in the first round (power of two)
+	Rounds       int        `json:"rounds"`                         // winners bracket rounds
+	Status       string     `json:"status" gorm:"default:'active'"` // active, completed
+	ChampionID   string     `json:"champion_id,omitempty"`
+	ChampionName string     `json:"champion_name,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Nodes []BracketNode `json:"nodes,omitempty" gorm:"foreignKey:BracketID"`
+}
+
+// BracketNode is one head-to-head game in a bracket. A slot marked as a bye will never
+// receive a player, so the other player advances without playing.
+type BracketNode struct {
+	ID        string `json:"id" gorm:"primaryKey"`
+	BracketID string `json:"bracket_id" gorm:"not null;index"`
+	Section   string `json:"section" gorm:"type:varchar(24);not null"`
+	Round     int    `json:"round"`    // 1-based within the section
+	Position  int    `json:"position"` // 0-based within the round
+
+	Player1ID   string `json:"player1_id,omitempty"`
+	Player1Name string `json:"player1_name,omitempty"`
+	Player1Seed int    `json:"player1_seed,omitempty"`
+	Player1Bye  bool   `json:"player1_bye"`
+	Player2ID   string `json:"player2_id,omitempty"`
+	Player2Name string `json:"player2_name,omitempty"`
+	Player2Seed int    `json:"player2_seed,omitempty"`
+	Player2Bye  bool   `json:"player2_bye"`
+
+	Player1Score int64  `json:"player1_score"`
+	Player2Score int64  `json:"player2_score"`
+	WinnerID     string `json:"winner_id,omitempty"`
+	WinnerName   string `json:"winner_name,omitempty"`
+	LoserID      string `json:"loser_id,omitempty"`
+	LoserName    string `json:"loser_name,omitempty"`
+
+	// Edges: where the winner/loser goes next (slot 1 or 2)
+	NextWinnerNodeID string `json:"next_winner_node_id,omitempty"`
+	NextWinnerSlot   int    `json:"next_winner_slot,omitempty"`
+	NextLoserNodeID  string `json:"next_loser_node_id,omitempty"`
+	NextLoserSlot    int    `json:"next_loser_slot,omitempty"`
+
+	Status      string     `json:"status" gorm:"default:'pending'"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// CheckIn confirms an entrant is present before pairings are generated. MatchID is empty
+// for the tournament-wide check-in and set for a match that requires its own.
+type CheckIn struct {
+	ID             string    `json:"id" gorm:"primaryKey"`
+	TournamentID   string    `json:"tournament_id" gorm:"not null;uniqueIndex:idx_check_in"`
+	MatchID        string    `json:"match_id" gorm:"not null;default:'';uniqueIndex:idx_check_in"`
+	SubscriptionID string    `json:"subscription_id" gorm:"not null;uniqueIndex:idx_check_in"`
+	UserID         string    `json:"user_id" gorm:"index"` // entrant: the player, or the team for team entries
+	CheckedInBy    string    `json:"checked_in_by"`
+	CheckedInAt    time.Time `json:"checked_in_at" gorm:"autoCreateTime"`
+}
+
+// CheckInWindow returns when check-in opens and closes for something starting at start
+func (t *Tournament) CheckInWindow(start time.Time) (time.Time, time.Time) {
+	opens := start.Add(-time.Duration(t.CheckInOpensMinutes) * time.Minute)
+	closes := start.Add(-time.Duration(t.CheckInClosesMinutes) * time.Minute)
+	return opens, closes
+}
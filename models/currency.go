@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultCurrency is what amounts without an explicit currency are denominated in
+const DefaultCurrency = "USD"
+
+// Currency is a code amounts can be denominated in. Fiat codes are ISO 4217; token codes match
+// WalletMirror.Token on the chain they live on.
+type Currency struct {
+	Code     string `json:"code"`
+	Name     string `json:"name"`
+	Decimals int    `json:"decimals"`        // minor units per unit = 10^Decimals
+	Chain    string `json:"chain,omitempty"` // empty for fiat
+}
+
+// Currencies lists every code entry fees, prizes and waivers may use
+var Currencies = map[string]Currency{
+	"USD":  {Code: "USD", Name: "US Dollar", Decimals: 2},
+	"EUR":  {Code: "EUR", Name: "Euro", Decimals: 2},
+	"GBP":  {Code: "GBP", Name: "Pound Sterling", Decimals: 2},
+	"USDC": {Code: "USDC", Name: "USD Coin", Decimals: 6, Chain: "solana"},
+	"USDT": {Code: "USDT", Name: "Tether USD", Decimals: 6, Chain: "solana"},
+	"SOL":  {Code: "SOL", Name: "Solana", Decimals: 9, Chain: "solana"},
+}
+
+// LookupCurrency finds a supported currency by code, case-insensitively
+func LookupCurrency(code string) (Currency, bool) {
+	c, ok := Currencies[strings.ToUpper(strings.TrimSpace(code))]
+	return c, ok
+}
+
+// CurrencyDecimals is the precision amounts in currency are kept at. Unknown or empty codes (rows
+// written before currencies existed) fall back to the default currency's.
+func CurrencyDecimals(currency string) int {
+	if c, ok := LookupCurrency(currency); ok {
+		return c.Decimals
+	}
+	return Currencies[DefaultCurrency].Decimals
+}
+
+// MinorUnits converts an amount in currency to the integer minor units amounts are stored in
+func MinorUnits(amount float64, currency string) int64 {
+	return int64(math.Round(amount * math.Pow10(CurrencyDecimals(currency))))
+}
+
+// MajorUnits converts stored minor units back to an amount in currency, for API responses
+func MajorUnits(amount int64, currency string) float64 {
+	return float64(amount) / math.Pow10(CurrencyDecimals(currency))
+}
+
+// minorUnitColumns lists the amount columns that used to be stored as floats in currency units,
+// with the column now holding minor units and the column naming the currency
+var minorUnitColumns = []struct{ table, from, to, currency string }{
+	{"tournaments", "entry_fee", "entry_fee_minor", "currency"},
+	{"tournament_subscriptions", "payment_amount", "payment_amount_minor", "currency"},
+	{"tournament_subscriptions", "waiver_amount_used", "waiver_amount_used_minor", "currency"},
+	{"user_waivers", "amount", "amount_minor", "currency"},
+	{"user_waivers", "used_amount", "used_amount_minor", "currency"},
+	{"tournament_prizes", "amount", "amount_minor", "currency"},
+	{"subscription_refunds", "amount", "amount_minor", "currency"},
+	{"subscription_refunds", "waiver_restored", "waiver_restored_minor", "currency"},
+	{"reconciliation_reports", "paid_total", "paid_total_minor", "currency"},
+	{"reconciliation_reports", "refunded_total", "refunded_total_minor", "currency"},
+	{"reconciliation_reports", "failed_total", "failed_total_minor", "currency"},
+	{"reconciliation_reports", "expired_total", "expired_total_minor", "currency"},
+}
+
+// BackfillMinorUnits copies amounts left in the old float columns into their minor-unit columns
+// and drops the old columns. Run it after AutoMigrate; it does nothing once they are gone.
+func BackfillMinorUnits(db *gorm.DB) error {
+	codes := make([]string, 0, len(Currencies))
+	for code := range Currencies {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, col := range minorUnitColumns {
+			if !tx.Migrator().HasColumn(col.table, col.from) {
+				continue
+			}
+			decimals := "CASE UPPER(" + col.currency + ")"
+			for _, code := range codes {
+				decimals += fmt.Sprintf(" WHEN '%s' THEN %d", code, Currencies[code].Decimals)
+			}
+			decimals += fmt.Sprintf(" ELSE %d END", CurrencyDecimals(DefaultCurrency))
+			if err := tx.Exec(fmt.Sprintf("UPDATE %s SET %s = ROUND(COALESCE(%s, 0) * POWER(10, %s)) WHERE %s IS NOT NULL",
+				col.table, col.to, col.from, decimals, col.from)).Error; err != nil {
+				return fmt.Errorf("backfill %s.%s: %w", col.table, col.to, err)
+			}
+			if err := tx.Migrator().DropColumn(col.table, col.from); err != nil {
+				return fmt.Errorf("drop %s.%s: %w", col.table, col.from, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CurrencyRate converts amounts for display only: 1 Base is worth Rate Quote. Money is never
+// moved at these rates; payments must match the tournament's own currency.
+type CurrencyRate struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Base      string    `json:"base" gorm:"type:varchar(16);not null;uniqueIndex:idx_currency_rate_pair"`
+	Quote     string    `json:"quote" gorm:"type:varchar(16);not null;uniqueIndex:idx_currency_rate_pair"`
+	Rate      float64   `json:"rate" gorm:"not null"`
+	Source    string    `json:"source,omitempty"` // e.g. "manual", or the feed it was copied from
+	UpdatedBy string    `json:"updated_by,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// DisplayAmount is an amount converted to another currency at a CurrencyRate, for display only
+type DisplayAmount struct {
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+	Rate     float64 `json:"rate"`
+}
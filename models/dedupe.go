@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// subscriptionKeepOrder ranks duplicate subscriptions: a paid or waived entry first, otherwise the
+// newest
+const subscriptionKeepOrder = "CASE WHEN payment_status IN ('paid', 'waived') THEN 0 ELSE 1 END, joined_at DESC, id"
+
+// uniqueIndexDedupes lists unique indexes added to tables that may already hold duplicate keys.
+// Rows sharing a key (among those matching where) are ranked by keep and all but the first are
+// deleted, so AutoMigrate can create the index.
+var uniqueIndexDedupes = []struct {
+	table, index string
+	key          []string
+	where, keep  string
+}{
+	{"tournament_subscriptions", "idx_subscription_tournament_user", []string{"tournament_id", "external_user_id"}, "", subscriptionKeepOrder},
+	{"tournament_subscriptions", "idx_subscription_tournament_team", []string{"tournament_id", "team_id"}, "team_id <> ''", subscriptionKeepOrder},
+	// a live participation over a deleted one, then one whose prize was paid, then the newest
+	{"tournament_participations", "idx_participation_tournament_user", []string{"tournament_id", "external_user_id"}, "",
+		"deleted_at IS NOT NULL, prize_paid_at IS NULL, updated_at DESC, id"},
+	// a manual override over a generated seed, then the newest
+	{"player_seedings", "idx_player_seeding_tournament_user", []string{"tournament_id", "user_id"}, "",
+		"CASE WHEN seeding_method = 'MANUAL' THEN 0 ELSE 1 END, updated_at DESC, id"},
+	// configs are looked up by match type, so the most recently edited one wins
+	{"match_type_configs", "idx_match_type_config_match_type", []string{"match_type"}, "", "updated_at DESC, id"},
+}
+
+// DedupeUniqueIndexes removes the duplicate rows that would stop AutoMigrate from creating the
+// unique indexes in uniqueIndexDedupes, logging the IDs it deletes. Run it before AutoMigrate; an
+// index that already exists is skipped.
+func DedupeUniqueIndexes(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, d := range uniqueIndexDedupes {
+			if !tx.Migrator().HasTable(d.table) || tx.Migrator().HasIndex(d.table, d.index) {
+				continue
+			}
+			missing := false
+			for _, col := range d.key {
+				missing = missing || !tx.Migrator().HasColumn(d.table, col)
+			}
+			if missing {
+				continue // a key column added by this migration can't hold duplicates yet
+			}
+			where := ""
+			if d.where != "" {
+				where = " WHERE " + d.where
+			}
+			var deleted []string
+			if err := tx.Raw(fmt.Sprintf(`DELETE FROM %s WHERE id IN (
+				SELECT id FROM (
+					SELECT id, ROW_NUMBER() OVER (PARTITION BY %s ORDER BY %s) AS row_num FROM %s%s
+				) ranked WHERE row_num > 1
+			) RETURNING id`, d.table, strings.Join(d.key, ", "), d.keep, d.table, where)).
+				Scan(&deleted).Error; err != nil {
+				return fmt.Errorf("dedupe %s for %s: %w", d.table, d.index, err)
+			}
+			if len(deleted) > 0 {
+				log.Printf("🧹 Removed %d duplicate %s rows before creating %s: %s",
+					len(deleted), d.table, d.index, strings.Join(deleted, ", "))
+			}
+		}
+		return nil
+	})
+}
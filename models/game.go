@@ -53,6 +53,13 @@ type Game struct {
 	Status    string     `json:"status" gorm:"default:'draft'"` // draft | scheduled | published
 	PublishAt *time.Time `json:"publish_at"`                    // only used if scheduled
 
+	// 🔏 Score webhook signing (server-to-server); never serialized
+	WebhookSecret          string     `json:"-" gorm:"type:varchar(128)"`
+	WebhookSecretRotatedAt *time.Time `json:"webhook_secret_rotated_at,omitempty"`
+	// The secret replaced by the last rotation still verifies until it expires
+	PreviousWebhookSecret          string     `json:"-" gorm:"type:varchar(128)"`
+	PreviousWebhookSecretExpiresAt *time.Time `json:"-"`
+
 	CreatedAt time.Time      `json:"created_at"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"deleted_at,omitempty" gorm:"index"`
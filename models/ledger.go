@@ -0,0 +1,76 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLedgerAppendOnly is returned when code tries to rewrite posted ledger history; corrections
+// are posted as new, reversing transactions
+var ErrLedgerAppendOnly = errors.New("ledger entries are append-only")
+
+// Ledger account types. Assets and expenses carry debit balances; liabilities and revenue carry
+// credit balances.
+const (
+	LedgerAccountAsset     = "asset"
+	LedgerAccountLiability = "liability"
+	LedgerAccountRevenue   = "revenue"
+	LedgerAccountExpense   = "expense"
+)
+
+// Ledger transaction kinds
+const (
+	LedgerKindEntryFee       = "entry_fee"       // verified payment into a tournament pool
+	LedgerKindWaiverIssued   = "waiver_issued"   // promotional waiver credit granted
+	LedgerKindWaiverAdjusted = "waiver_adjusted" // waiver amount changed or waiver deleted
+	LedgerKindWaiverApplied  = "waiver_applied"  // waiver balance spent on an entry fee
+	LedgerKindWaiverRestored = "waiver_restored" // waiver balance given back (expiry, refund, cancel)
+	LedgerKindRefund         = "refund"          // entry fee returned to the user or as cashback
+	LedgerKindRefundPayout   = "refund_payout"   // refunded money actually sent back
+	LedgerKindPrizePayout    = "prize_payout"    // cash prize credited to a winner
+	LedgerKindPoolClose      = "pool_close"      // unpaid pool remainder taken as platform revenue
+)
+
+// LedgerAccount is one balance in the double-entry ledger: a user, a tournament pool, or a
+// platform account such as revenue or waiver liability
+type LedgerAccount struct {
+	ID        string    `json:"id" gorm:"primaryKey"`
+	Code      string    `json:"code" gorm:"type:varchar(128);not null;uniqueIndex"` // e.g. "user:<id>", "tournament:<id>:pool", "platform:revenue:USDC"
+	Name      string    `json:"name"`
+	Type      string    `json:"type" gorm:"type:varchar(16);not null"`
+	OwnerType string    `json:"owner_type" gorm:"type:varchar(16);not null;index:idx_ledger_account_owner"` // user, tournament, platform
+	OwnerID   string    `json:"owner_id,omitempty" gorm:"index:idx_ledger_account_owner"`
+	Currency  string    `json:"currency" gorm:"type:varchar(16);not null;default:'USD'"` // balances are in this currency's minor units
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LedgerTransaction groups postings that sum to zero. IdempotencyKey, when set, makes a retried
+// post a no-op.
+type LedgerTransaction struct {
+	ID             string          `json:"id" gorm:"primaryKey"`
+	Kind           string          `json:"kind" gorm:"type:varchar(32);not null;index"`
+	Currency       string          `json:"currency" gorm:"type:varchar(16);not null;default:'USD'"` // every posting moves this currency
+	IdempotencyKey string          `json:"idempotency_key,omitempty" gorm:"type:varchar(160);uniqueIndex:idx_ledger_idempotency,where:idempotency_key <> ''"`
+	TournamentID   string          `json:"tournament_id,omitempty" gorm:"index"`
+	SubscriptionID string          `json:"subscription_id,omitempty" gorm:"index"`
+	Description    string          `json:"description"`
+	Actor          string          `json:"actor,omitempty"`
+	PostedAt       time.Time       `json:"posted_at" gorm:"not null;index"`
+	Postings       []LedgerPosting `json:"postings,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+// LedgerPosting moves Amount minor units on one account: debits are positive, credits negative
+type LedgerPosting struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	TransactionID string    `json:"transaction_id" gorm:"not null;index"`
+	AccountID     string    `json:"account_id" gorm:"not null;index:idx_ledger_posting_account"`
+	Amount        int64     `json:"amount" gorm:"not null"`
+	PostedAt      time.Time `json:"posted_at" gorm:"not null;index:idx_ledger_posting_account"`
+}
+
+func (LedgerTransaction) BeforeUpdate(tx *gorm.DB) error { return ErrLedgerAppendOnly }
+func (LedgerTransaction) BeforeDelete(tx *gorm.DB) error { return ErrLedgerAppendOnly }
+func (LedgerPosting) BeforeUpdate(tx *gorm.DB) error     { return ErrLedgerAppendOnly }
+func (LedgerPosting) BeforeDelete(tx *gorm.DB) error     { return ErrLedgerAppendOnly }
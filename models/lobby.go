@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Lobby statuses
+const (
+	LobbyStatusPending   = "pending"
+	LobbyStatusCompleted = "completed"
+)
+
+// Lobby is one free-for-all game of a FREE_FOR_ALL match, built when its pairing is published
+type Lobby struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	TournamentID string     `json:"tournament_id" gorm:"not null;index"`
+	MatchID      string     `json:"match_id" gorm:"not null;uniqueIndex:idx_lobby_match_number"`
+	PairingID    string     `json:"pairing_id" gorm:"index"`
+	LobbyNumber  int        `json:"lobby_number" gorm:"uniqueIndex:idx_lobby_match_number"`
+	AdvanceCount int        `json:"advance_count"` // top K placements move on to the next match
+	Status       string     `json:"status" gorm:"type:varchar(16);default:'pending';index"`
+	RecordedBy   string     `json:"recorded_by,omitempty"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Players []LobbyPlayer `json:"players,omitempty" gorm:"foreignKey:LobbyID"`
+}
+
+// LobbyPlayer is a player's seat in a lobby and, once recorded, their placement
+type LobbyPlayer struct {
+	ID          string  `json:"id" gorm:"primaryKey"`
+	LobbyID     string  `json:"lobby_id" gorm:"not null;uniqueIndex:idx_lobby_player"`
+	UserID      string  `json:"user_id" gorm:"not null;uniqueIndex:idx_lobby_player"`
+	UserName    string  `json:"user_name"`
+	Seed        int     `json:"seed"`
+	SkillRating float64 `json:"skill_rating"`
+	Placement   int     `json:"placement" gorm:"default:0"` // 1 = winner, 0 = not recorded
+	Score       int64   `json:"score"`
+	Advanced    bool    `json:"advanced" gorm:"default:false"`
+}
+
+// MatchEntrant restricts who a match is paired from: players who qualified from an earlier
+// match (e.g. the top K of each lobby). A match without entrants takes every eligible subscriber.
+type MatchEntrant struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	MatchID       string    `json:"match_id" gorm:"not null;uniqueIndex:idx_match_entrant"`
+	UserID        string    `json:"user_id" gorm:"not null;uniqueIndex:idx_match_entrant"`
+	UserName      string    `json:"user_name"`
+	SourceMatchID string    `json:"source_match_id,omitempty" gorm:"index"`
+	SourceLobbyID string    `json:"source_lobby_id,omitempty"`
+	Placement     int       `json:"placement"`
+	CreatedAt     time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
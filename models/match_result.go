@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// MatchResult statuses
+const (
+	MatchResultPending   = "pending"   // one player has reported, waiting for the other (or the timeout)
+	MatchResultConfirmed = "confirmed" // both reports agree, or the timeout confirmed a single report
+	MatchResultDisputed  = "disputed"  // reports conflict, waiting for an admin
+	MatchResultResolved  = "resolved"  // an admin decided the outcome
+)
+
+// MatchResult is the agreed outcome of one head-to-head game: either a pair of a
+// published MatchPairing or a BracketNode
+type MatchResult struct {
+	ID            string `json:"id" gorm:"primaryKey"`
+	SubjectKey    string `json:"subject_key" gorm:"not null;uniqueIndex"` // "pair:<pairing_id>:<match_number>" or "node:<node_id>"
+	TournamentID  string `json:"tournament_id" gorm:"not null;index"`
+	MatchID       string `json:"match_id" gorm:"not null;index"`
+	PairingID     string `json:"pairing_id,omitempty" gorm:"index"`
+	MatchNumber   int    `json:"match_number,omitempty"`
+	BracketID     string `json:"bracket_id,omitempty"`
+	BracketNodeID string `json:"bracket_node_id,omitempty" gorm:"index"`
+	Player1ID     string `json:"player1_id"`
+	Player2ID     string `json:"player2_id"`
+
+	Status        string `json:"status" gorm:"type:varchar(16);default:'pending';index"`
+	Outcome       string `json:"outcome,omitempty"` // player1, player2, draw (set once confirmed or resolved)
+	WinnerID      string `json:"winner_id,omitempty"`
+	Player1Score  int64  `json:"player1_score"`
+	Player2Score  int64  `json:"player2_score"`
+	AutoConfirmed bool   `json:"auto_confirmed" gorm:"default:false"`
+
+	ResolvedBy     string `json:"resolved_by,omitempty"`
+	ResolutionNote string `json:"resolution_note,omitempty"`
+
+	ConfirmDeadline *time.Time `json:"confirm_deadline,omitempty" gorm:"index"`
+	ConfirmedAt     *time.Time `json:"confirmed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt       time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Reports []MatchResultReport `json:"reports,omitempty" gorm:"foreignKey:ResultID"`
+}
+
+// MatchResultReport is what one player says happened
+type MatchResultReport struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	ResultID     string    `json:"result_id" gorm:"not null;uniqueIndex:idx_match_result_reporter"`
+	ReporterID   string    `json:"reporter_id" gorm:"not null;uniqueIndex:idx_match_result_reporter"`
+	Outcome      string    `json:"outcome" gorm:"type:varchar(16);not null"` // player1, player2, draw
+	Player1Score int64     `json:"player1_score"`
+	Player2Score int64     `json:"player2_score"`
+	EvidenceURL  string    `json:"evidence_url,omitempty"` // optional screenshot on R2
+	Note         string    `json:"note,omitempty"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
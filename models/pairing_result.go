@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// PairingResult outcomes
+const (
+	PairingOutcomePlayer1 = "player1"
+	PairingOutcomePlayer2 = "player2"
+	PairingOutcomeDraw    = "draw"
+)
+
+// PairingResult is the confirmed outcome of one pair in a published MatchPairing
+type PairingResult struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	PairingID    string    `json:"pairing_id" gorm:"not null;uniqueIndex:idx_pairing_result_pair"`
+	MatchNumber  int       `json:"match_number" gorm:"not null;uniqueIndex:idx_pairing_result_pair"`
+	MatchID      string    `json:"match_id" gorm:"not null;index"`
+	TournamentID string    `json:"tournament_id" gorm:"not null;index"`
+	RoundNumber  int       `json:"round_number"`
+	Player1ID    string    `json:"player1_id"`
+	Player2ID    string    `json:"player2_id"`
+	Outcome      string    `json:"outcome" gorm:"type:varchar(16);not null"` // player1, player2, draw
+	WinnerID     string    `json:"winner_id,omitempty"`                      // empty on a draw
+	Player1Score int64     `json:"player1_score"`
+	Player2Score int64     `json:"player2_score"`
+	Status       string    `json:"status" gorm:"default:'confirmed'"`
+	RecordedBy   string    `json:"recorded_by"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Glicko-2 defaults for a player who has never played a rated game
+const (
+	DefaultRating           = 1500.0
+	DefaultRatingDeviation  = 350.0
+	DefaultRatingVolatility = 0.06
+)
+
+// PlayerRating is a player's Glicko-2 skill rating for one game. Team entrants are rated
+// under the team id.
+type PlayerRating struct {
+	ID           string     `json:"id" gorm:"primaryKey"`
+	UserID       string     `json:"user_id" gorm:"not null;uniqueIndex:idx_player_rating_user_game"`
+	GameID       string     `json:"game_id" gorm:"not null;uniqueIndex:idx_player_rating_user_game;index"`
+	Rating       float64    `json:"rating" gorm:"default:1500"`
+	Deviation    float64    `json:"deviation" gorm:"default:350"` // RD: lower = more certain
+	Volatility   float64    `json:"volatility" gorm:"default:0.06"`
+	GamesPlayed  int        `json:"games_played" gorm:"default:0"`
+	Wins         int        `json:"wins" gorm:"default:0"`
+	Losses       int        `json:"losses" gorm:"default:0"`
+	Draws        int        `json:"draws" gorm:"default:0"`
+	LastPlayedAt *time.Time `json:"last_played_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// RatingEvent marks a confirmed result as applied to ratings so it is never counted twice
+type RatingEvent struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	SourceKey    string    `json:"source_key" gorm:"not null;uniqueIndex"` // "pair:<pairing_id>:<n>", "node:<id>", "lobby:<id>"
+	GameID       string    `json:"game_id" gorm:"index"`
+	TournamentID string    `json:"tournament_id" gorm:"index"`
+	Players      int       `json:"players"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+}
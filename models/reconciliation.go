@@ -0,0 +1,46 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ReconciliationReport totals one tournament's payment outcomes for one UTC day and lists the
+// paid subscriptions its payment provider could not re-confirm
+type ReconciliationReport struct {
+	ID            string    `json:"id" gorm:"primaryKey"`
+	ReportDate    time.Time `json:"report_date" gorm:"type:date;not null;uniqueIndex:idx_reconciliation_day"`
+	TournamentID  string    `json:"tournament_id" gorm:"not null;uniqueIndex:idx_reconciliation_day;index"`
+	Currency      string    `json:"currency" gorm:"type:varchar(16)"` // all totals are in minor units of the tournament's currency
+	PaidCount     int       `json:"paid_count"`
+	PaidTotal     int64     `json:"-" gorm:"column:paid_total_minor;not null;default:0"`
+	RefundedCount int       `json:"refunded_count"`
+	RefundedTotal int64     `json:"-" gorm:"column:refunded_total_minor;not null;default:0"`
+	FailedCount   int       `json:"failed_count"`
+	FailedTotal   int64     `json:"-" gorm:"column:failed_total_minor;not null;default:0"`
+	ExpiredCount  int       `json:"expired_count"`
+	ExpiredTotal  int64     `json:"-" gorm:"column:expired_total_minor;not null;default:0"`
+	// Paid subscriptions re-confirmed by their provider vs. those that were not
+	VerifiedCount    int       `json:"verified_count"`
+	DiscrepancyCount int       `json:"discrepancy_count"`
+	Discrepancies    string    `json:"discrepancies" gorm:"type:jsonb"` // [{"subscription_id": ..., "reason": ...}]
+	GeneratedAt      time.Time `json:"generated_at"`
+}
+
+// MarshalJSON reports the totals in currency units
+func (r ReconciliationReport) MarshalJSON() ([]byte, error) {
+	type report ReconciliationReport
+	return json.Marshal(struct {
+		report
+		PaidTotal     float64 `json:"paid_total"`
+		RefundedTotal float64 `json:"refunded_total"`
+		FailedTotal   float64 `json:"failed_total"`
+		ExpiredTotal  float64 `json:"expired_total"`
+	}{
+		report(r),
+		MajorUnits(r.PaidTotal, r.Currency),
+		MajorUnits(r.RefundedTotal, r.Currency),
+		MajorUnits(r.FailedTotal, r.Currency),
+		MajorUnits(r.ExpiredTotal, r.Currency),
+	})
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Refund payout states: pending until money has moved (or a provider has no refund API and the
+// payout is made by hand), completed once it has, failed when the provider refused it
+const (
+	RefundStatusPending   = "pending"
+	RefundStatusCompleted = "completed"
+	RefundStatusFailed    = "failed"
+)
+
+// Refund methods
+const (
+	RefundMethodOriginal = "original" // back to the card / wallet that paid
+	RefundMethodCashback = "cashback" // a cashback waiver for the paid amount instead of cash
+	RefundMethodNone     = "none"     // nothing was paid; only waiver balance is restored
+)
+
+// SubscriptionRefund is the ledger entry for refunding one subscription
+type SubscriptionRefund struct {
+	ID               string     `json:"id" gorm:"primaryKey"`
+	SubscriptionID   string     `json:"subscription_id" gorm:"not null;uniqueIndex"` // a subscription is refunded at most once
+	TournamentID     string     `json:"tournament_id" gorm:"not null;index"`
+	ExternalUserID   string     `json:"external_user_id" gorm:"not null;index"`
+	Amount           int64      `json:"-" gorm:"column:amount_minor;not null;default:0"` // paid amount being returned, in minor units
+	Currency         string     `json:"currency" gorm:"type:varchar(16);not null;default:'USD'"`
+	WaiverRestored   int64      `json:"-" gorm:"column:waiver_restored_minor;not null;default:0"` // waiver balance given back, in minor units
+	WaiverID         string     `json:"waiver_id,omitempty"`                                      // waiver whose balance was restored
+	Method           string     `json:"method" gorm:"type:varchar(16);not null"`
+	CashbackWaiverID string     `json:"cashback_waiver_id,omitempty"`
+	Provider         string     `json:"provider,omitempty"`
+	PaymentReference string     `json:"payment_reference,omitempty"`  // the payment being refunded
+	ProviderRefundID string     `json:"provider_refund_id,omitempty"` // the provider's refund reference
+	Status           string     `json:"status" gorm:"type:varchar(16);not null;index"`
+	FailureReason    string     `json:"failure_reason,omitempty"`
+	Reason           string     `json:"reason"`
+	RefundedBy       string     `json:"refunded_by"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// MarshalJSON reports Amount and WaiverRestored in currency units
+func (r SubscriptionRefund) MarshalJSON() ([]byte, error) {
+	type refund SubscriptionRefund
+	return json.Marshal(struct {
+		refund
+		Amount         float64 `json:"amount"`
+		WaiverRestored float64 `json:"waiver_restored"`
+	}{refund(r), MajorUnits(r.Amount, r.Currency), MajorUnits(r.WaiverRestored, r.Currency)})
+}
@@ -44,6 +44,7 @@ type Reward struct {
 	Emoji       string         `gorm:"size:10" json:"emoji"`
 	Excerpt     string         `gorm:"type:text" json:"excerpt"`
 	Amount      float64        `json:"amount"`
+	Currency    string         `gorm:"type:varchar(16)" json:"currency,omitempty"` // cash rewards only
 	ItemDetails string         `json:"item_details"`
 	ExpiryDate  *time.Time     `json:"expiry_date,omitempty"`
 	Claimed     bool           `gorm:"default:false" json:"claimed"`
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// ScoreWebhookNonce records every accepted nonce per game so a signed payload can't be replayed.
+// Rows are pruned once their timestamp window has passed.
+type ScoreWebhookNonce struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	GameID     string    `json:"game_id" gorm:"not null;uniqueIndex:idx_score_webhook_game_nonce"`
+	Nonce      string    `json:"nonce" gorm:"not null;type:varchar(128);uniqueIndex:idx_score_webhook_game_nonce"`
+	EntryID    string    `json:"entry_id,omitempty"` // LeaderboardEntry created for this nonce (empty if rejected)
+	ReceivedAt time.Time `json:"received_at" gorm:"autoCreateTime;index"`
+}
+
+// ScoreWebhookDeadLetter keeps rejected, correctly signed webhook payloads for inspection and
+// manual replay
+type ScoreWebhookDeadLetter struct {
+	ID         string    `json:"id" gorm:"primaryKey"`
+	GameID     string    `json:"game_id" gorm:"index"`
+	Reason     string    `json:"reason"`
+	Details    string    `json:"details,omitempty"`
+	Payload    string    `json:"payload" gorm:"type:text"` // raw request body
+	Signature  string    `json:"signature"`
+	Timestamp  string    `json:"timestamp"`
+	Nonce      string    `json:"nonce"`
+	RemoteIP   string    `json:"remote_ip"`
+	ReceivedAt time.Time `json:"received_at" gorm:"autoCreateTime;index"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// TeamMember roles
+const (
+	TeamRoleCaptain = "captain"
+	TeamRoleMember  = "member"
+)
+
+// Team is a persistent roster that subscribes to team-based tournaments as one entrant.
+// The captain manages the roster and pays (or applies a waiver) for the team.
+type Team struct {
+	ID          string    `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null;uniqueIndex"`
+	Tag         string    `json:"tag,omitempty" gorm:"type:varchar(8)"` // short display tag, e.g. "MBX"
+	LogoURL     string    `json:"logo_url,omitempty"`                   // public R2 URL
+	CaptainID   string    `json:"captain_id" gorm:"not null;index"`     // external user id
+	CaptainName string    `json:"captain_name"`
+	CreatedAt   time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Members []TeamMember `json:"members,omitempty" gorm:"foreignKey:TeamID"`
+}
+
+// TeamMember is one player on a team's roster
+type TeamMember struct {
+	ID             string    `json:"id" gorm:"primaryKey"`
+	TeamID         string    `json:"team_id" gorm:"not null;uniqueIndex:idx_team_member"`
+	ExternalUserID string    `json:"external_user_id" gorm:"not null;uniqueIndex:idx_team_member;index"`
+	UserName       string    `json:"user_name"`
+	UserAvatarURL  string    `json:"user_avatar_url,omitempty"`
+	Role           string    `json:"role" gorm:"type:varchar(16);default:'member'"` // captain, member
+	JoinedAt       time.Time `json:"joined_at" gorm:"autoCreateTime"`
+}
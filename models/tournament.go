@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"errors"
 	"time"
 
@@ -19,7 +20,7 @@ type Tournament struct {
 	Genre           string         `json:"genre"`
 	GenreTags       string         `json:"genre_tags" gorm:"column:genre_tags"`
 	MaxSubscribers  int            `json:"max_subscribers" gorm:"default:0"`
-	EntryFee        float64        `json:"entry_fee" gorm:"default:0"`
+	EntryFee        int64          `json:"-" gorm:"column:entry_fee_minor;not null;default:0"` // minor units of Currency
 	MainPhotoURL    string         `json:"main_photo_url"`
 	Status          string         `json:"status" gorm:"default:'draft'"`
 	StartTime       time.Time      `json:"start_time" gorm:"not null"`
@@ -37,16 +38,50 @@ type Tournament struct {
 	PublishSchedule *time.Time     `json:"publish_schedule,omitempty"`
 	AcceptsWaivers  bool           `json:"accepts_waivers" gorm:"default:true"`
 
+	// Entry fee currency and prize funding
+	Currency     string `json:"currency" gorm:"type:varchar(16);not null;default:'USD'"`   // EntryFee and pool-funded prizes are in this
+	PrizeFunding string `json:"prize_funding" gorm:"type:varchar(16);default:'sponsored'"` // sponsored, pool
+
+	// Teams
+	IsTeamBased bool `json:"is_team_based" gorm:"default:false"` // entrants are teams, one subscription per team
+	TeamSizeMin int  `json:"team_size_min" gorm:"default:0"`
+	TeamSizeMax int  `json:"team_size_max" gorm:"default:0"` // 0 = no limit
+
+	// Check-in and waitlist
+	CheckInRequired      bool       `json:"check_in_required" gorm:"default:false"`   // only checked-in entrants are paired
+	CheckInOpensMinutes  int        `json:"check_in_opens_minutes" gorm:"default:30"` // window opens this long before the start...
+	CheckInClosesMinutes int        `json:"check_in_closes_minutes" gorm:"default:0"` // ...and closes this long before it
+	AutoForfeitNoShows   bool       `json:"auto_forfeit_no_shows" gorm:"default:false"`
+	CheckInProcessedAt   *time.Time `json:"check_in_processed_at,omitempty"`         // set once no-shows have been forfeited
+	WaitlistHoldMinutes  int        `json:"waitlist_hold_minutes" gorm:"default:30"` // how long a promoted waitlist entry holds its slot
+
+	// Results
+	WinnerID    string     `json:"winner_id,omitempty"`
+	WinnerName  string     `json:"winner_name,omitempty"`
+	FinalizedAt *time.Time `json:"finalized_at,omitempty"` // set once participations and XP have been written
+
 	// Relationships
 	Game          Game                     `json:"game,omitempty" gorm:"foreignKey:GameID"`
 	Photos        []TournamentPhoto        `json:"photos,omitempty" gorm:"foreignKey:TournamentID"`
 	Batches       []TournamentBatch        `json:"batches,omitempty" gorm:"foreignKey:TournamentID"`
 	Subscriptions []TournamentSubscription `json:"subscribers,omitempty" gorm:"foreignKey:TournamentID"`
+	Prizes        []TournamentPrize        `json:"prizes,omitempty" gorm:"foreignKey:TournamentID"`
 
 	// Calculated fields (not stored in DB)
 	SubscribersCount       int64 `json:"subscribers_count,omitempty" gorm:"-"`
 	ActiveSubscribersCount int64 `json:"active_subscribers_count,omitempty" gorm:"-"`
 	AvailableSlots         int64 `json:"available_slots,omitempty" gorm:"-"`
+	// EntryFee converted to ?display_currency= for display; nil when no rate is configured
+	EntryFeeDisplay *DisplayAmount `json:"entry_fee_display,omitempty" gorm:"-"`
+}
+
+// MarshalJSON reports EntryFee in currency units; it is stored in minor units
+func (t Tournament) MarshalJSON() ([]byte, error) {
+	type tournament Tournament
+	return json.Marshal(struct {
+		tournament
+		EntryFee float64 `json:"entry_fee"`
+	}{tournament(t), MajorUnits(t.EntryFee, t.Currency)})
 }
 
 // TournamentBatch contains Matches
@@ -66,10 +101,34 @@ type TournamentBatch struct {
 
 // Add to your models/tournament.go
 
+// MatchTypeConfig match formats
+const (
+	MatchFormatHeadToHead = "HEAD_TO_HEAD"
+	MatchFormatFreeForAll = "FREE_FOR_ALL"
+	MatchFormatTeamBased  = "TEAM_BASED"
+)
+
+// MatchTypeConfig progression types (how pairings are generated)
+const (
+	ProgressionSingleElimination = "SINGLE_ELIMINATION"
+	ProgressionDoubleElimination = "DOUBLE_ELIMINATION"
+	ProgressionRoundRobin        = "ROUND_ROBIN"
+	ProgressionSwiss             = "SWISS"
+	ProgressionLeaderboard       = "LEADERBOARD"
+	ProgressionLobbies           = "LOBBIES"
+)
+
+// MatchTypeConfig advancement rules
+const (
+	AdvancementWinnerAdvances = "WINNER_ADVANCES"
+	AdvancementTopNAdvance    = "TOP_N_ADVANCE"
+	AdvancementPointsBased    = "POINTS_BASED"
+)
+
 // MatchTypeConfig stores the configuration for different match types
 type MatchTypeConfig struct {
 	ID          string `json:"id" gorm:"primaryKey"`
-	MatchType   string `json:"match_type" gorm:"not null;index"` // e.g., "SINGLE_ELIMINATION_1V1", "LEADERBOARD_CHALLENGE"
+	MatchType   string `json:"match_type" gorm:"not null;uniqueIndex:idx_match_type_config_match_type"` // e.g., "SINGLE_ELIMINATION_1V1", "LEADERBOARD_CHALLENGE"
 	Name        string `json:"name" gorm:"not null"`
 	Description string `json:"description"`
 
@@ -91,10 +150,28 @@ type MatchTypeConfig struct {
 	// Seeding configuration
 	SeedingMethod string `json:"seeding_method"` // RANDOM, RANK_BASED, SKILL_BASED, CUSTOM
 
+	AdvanceCount int  `json:"advance_count" gorm:"default:1"` // TOP_N_ADVANCE: how many move on from each lobby/group
+	IsActive     bool `json:"is_active" gorm:"default:true"`  // inactive types can't be used for new matches
+
 	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
 	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
 }
 
+// IsElimination reports whether matches of this type are played on a bracket
+func (c *MatchTypeConfig) IsElimination() bool {
+	return c.ProgressionType == ProgressionSingleElimination || c.ProgressionType == ProgressionDoubleElimination
+}
+
+// IsLobbies reports whether matches of this type are split into free-for-all lobbies
+func (c *MatchTypeConfig) IsLobbies() bool {
+	return c.ProgressionType == ProgressionLobbies
+}
+
+// SupportsPairing reports whether matches of this type are paired (leaderboards are not)
+func (c *MatchTypeConfig) SupportsPairing() bool {
+	return c.ProgressionType != ProgressionLeaderboard
+}
+
 // MatchPairing stores proposed pairings before approval
 type MatchPairing struct {
 	ID           string `json:"id" gorm:"primaryKey"`
@@ -142,8 +219,8 @@ type MatchPairing struct {
 // PlayerSeeding stores seeding information for players in a tournament
 type PlayerSeeding struct {
 	ID           string `json:"id" gorm:"primaryKey"`
-	TournamentID string `json:"tournament_id" gorm:"not null;index"`
-	UserID       string `json:"user_id" gorm:"not null;index"` // ExternalUserID
+	TournamentID string `json:"tournament_id" gorm:"not null;index;uniqueIndex:idx_player_seeding_tournament_user"`
+	UserID       string `json:"user_id" gorm:"not null;index;uniqueIndex:idx_player_seeding_tournament_user"` // ExternalUserID, or TeamID for team entries
 	UserName     string `json:"user_name"`
 
 	// Seeding data
@@ -192,6 +269,10 @@ type TournamentMatch struct {
 	// NEW: Pairing reference
 	CurrentPairingID string `json:"current_pairing_id,omitempty"`
 
+	// Check-in before this match's StartDate, using the tournament's window settings
+	CheckInRequired    bool       `json:"check_in_required" gorm:"default:false"`
+	CheckInProcessedAt *time.Time `json:"check_in_processed_at,omitempty"`
+
 	// Relationship: One Match has many Rounds
 	Rounds []TournamentRound `json:"rounds,omitempty" gorm:"foreignKey:MatchID"`
 
@@ -228,28 +309,47 @@ type TournamentPhoto struct {
 // TournamentSubscription tracks user participation & payment metadata
 type TournamentSubscription struct {
 	ID           string `json:"id" gorm:"primaryKey"`
-	TournamentID string `json:"tournament_id" gorm:"not null;index"`
+	TournamentID string `json:"tournament_id" gorm:"not null;index;uniqueIndex:idx_subscription_tournament_user;uniqueIndex:idx_subscription_tournament_team,where:team_id <> ''"`
 	// Removed: TournamentUserID string
-	ExternalUserID string    `json:"external_user_id" gorm:"not null;index"` // ✅ Now the primary user identifier
-	UserName       string    `json:"user_name"`                              // Denormalized from profile service
-	UserAvatarURL  *string   `json:"user_avatar_url,omitempty"`              // Denormalized from profile service
+	ExternalUserID string    `json:"external_user_id" gorm:"not null;index;uniqueIndex:idx_subscription_tournament_user"` // ✅ Now the primary user identifier
+	UserName       string    `json:"user_name"`                                                                           // Denormalized from profile service
+	UserAvatarURL  *string   `json:"user_avatar_url,omitempty"`                                                           // Denormalized from profile service
+	TeamID         string    `json:"team_id,omitempty" gorm:"index;uniqueIndex:idx_subscription_tournament_team"`         // team entry: ExternalUserID is the captain, UserName the team name
+	Region         string    `json:"region,omitempty" gorm:"type:varchar(32)"`                                            // where the entrant plays from, for region-separated pairing
 	JoinedAt       time.Time `json:"joined_at" gorm:"autoCreateTime"`
 	// ✅ Payment Metadata (enhanced)
-	PaymentID        string  `json:"payment_id"`                               // Unique identifier for the *payment* (e.g., Stripe payment_intent ID, Solana tx hash)
-	PaymentAmount    float64 `json:"payment_amount"`                           // Actual amount *paid* (USD or token, same unit as EntryFee)
-	PaymentStatus    string  `json:"payment_status" gorm:"default:'pending'"`  // paid, pending, failed, refunded, waived
-	TransactionID    string  `json:"transaction_id,omitempty"`                 // Optional: raw blockchain tx hash (if applicable); may differ from PaymentID
-	WaiverCodeUsed   string  `json:"waiver_code_used" gorm:"type:varchar(64)"` // e.g., "WELCOME10"
-	WaiverAmountUsed float64 `json:"waiver_amount_used" gorm:"default:0"`      // e.g., 5.0
-	WaiverIDUsed     string  `json:"waiver_id_used" gorm:"type:uuid"`          // links to user_waivers.id (nullable)
+	PaymentID        string `json:"payment_id"`                                                               // Unique identifier for the *payment* (e.g., Stripe payment_intent ID, Solana tx hash)
+	PaymentAmount    int64  `json:"-" gorm:"column:payment_amount_minor;not null;default:0"`                  // Actual amount *paid*, in minor units of Currency
+	Currency         string `json:"currency" gorm:"type:varchar(16);not null;default:'USD'"`                  // the tournament's currency when subscribing
+	PaymentStatus    string `json:"payment_status" gorm:"default:'pending'"`                                  // paid, pending, failed, refunded, waived
+	TransactionID    string `json:"transaction_id,omitempty" gorm:"uniqueIndex:idx_subscription_payment_ref"` // Optional: raw blockchain tx hash (if applicable); may differ from PaymentID
+	WaiverCodeUsed   string `json:"waiver_code_used" gorm:"type:varchar(64)"`                                 // e.g., "WELCOME10"
+	WaiverAmountUsed int64  `json:"-" gorm:"column:waiver_amount_used_minor;not null;default:0"`              // minor units, e.g. 500 = $5
+	WaiverIDUsed     string `json:"waiver_id_used" gorm:"type:uuid"`                                          // links to user_waivers.id (nullable)
 	// Optional: for audit & reconciliation
 	PaymentMethod string     `json:"payment_method,omitempty"` // e.g., "solana", "stripe", "manual"
 	PaymentAt     *time.Time `json:"payment_at,omitempty"`     // When payment was confirmed
+	// Server-side verification: a provider reference settles at most one subscription
+	PaymentProvider   string     `json:"payment_provider,omitempty" gorm:"type:varchar(32);uniqueIndex:idx_subscription_payment_ref,where:payment_provider <> ''"`
+	PaymentRecipient  string     `json:"payment_recipient,omitempty"` // treasury address or merchant account that received it
+	PaymentVerifiedAt *time.Time `json:"payment_verified_at,omitempty"`
 	// Optional fields for status tracking (e.g., suspend, revoke)
 	SuspendedAt     *time.Time `json:"suspended_at,omitempty"`
 	SuspendedReason string     `json:"suspended_reason,omitempty"`
 	RevokedAt       *time.Time `json:"revoked_at,omitempty"`
 	RevokedReason   string     `json:"revoked_reason,omitempty"`
+	ForfeitedAt     *time.Time `json:"forfeited_at,omitempty"` // no-show: missed a required check-in
+	ForfeitReason   string     `json:"forfeit_reason,omitempty"`
+}
+
+// MarshalJSON reports PaymentAmount and WaiverAmountUsed in currency units
+func (s TournamentSubscription) MarshalJSON() ([]byte, error) {
+	type subscription TournamentSubscription
+	return json.Marshal(struct {
+		subscription
+		PaymentAmount    float64 `json:"payment_amount"`
+		WaiverAmountUsed float64 `json:"waiver_amount_used"`
+	}{subscription(s), MajorUnits(s.PaymentAmount, s.Currency), MajorUnits(s.WaiverAmountUsed, s.Currency)})
 }
 
 // LeaderboardEntry — populated by game server webhook or client submission
@@ -293,16 +393,17 @@ type UserWaiver struct {
 	ID            string     `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
 	UserID        string     `gorm:"index;not null" json:"user_id"` // ✅ Links to ExternalUserID directly
 	Code          string     `gorm:"not null;index" json:"code"`
-	Title         string     `gorm:"not null" json:"title"`                     // e.g., "Welcome Bonus", "Respawn Token"
-	Type          string     `gorm:"not null;default:'discount'" json:"type"`   // 'discount', 'cashback', 'entry_fee_reduction', 'respawn', 'custom'
-	Amount        float64    `gorm:"default:0.0" json:"amount"`                 // Max value (e.g., $10 discount)
-	UsedAmount    float64    `gorm:"not null;default:0.0" json:"used_amount"`   // Amount already applied (e.g., $5 of $10 used)
-	UsesRemaining *int       `gorm:"default:1" json:"uses_remaining,omitempty"` // Nullable: Num uses left. NULL = unlimited. 1 = single use.
-	MaxUses       *int       `gorm:"default:1" json:"max_uses,omitempty"`       // Nullable: Total allowed uses. NULL = unlimited.
-	ImageURL      string     `gorm:"type:text" json:"image_url"`                // Optional image URL for badge
-	Emoji         string     `gorm:"size:10" json:"emoji"`                      // Optional emoji for badge
-	Excerpt       string     `gorm:"type:text" json:"excerpt"`                  // Short description or note
-	Description   string     `json:"description"`                               // Longer description
+	Title         string     `gorm:"not null" json:"title"`                                   // e.g., "Welcome Bonus", "Respawn Token"
+	Type          string     `gorm:"not null;default:'discount'" json:"type"`                 // 'discount', 'cashback', 'entry_fee_reduction', 'respawn', 'custom'
+	Amount        int64      `gorm:"column:amount_minor;not null;default:0" json:"-"`         // Max value in minor units of Currency (e.g., 1000 = $10 discount)
+	Currency      string     `gorm:"type:varchar(16);not null;default:'USD'" json:"currency"` // only applies to entry fees in this currency
+	UsedAmount    int64      `gorm:"column:used_amount_minor;not null;default:0" json:"-"`    // Amount already applied, in minor units (e.g., 500 of 1000 used)
+	UsesRemaining *int       `gorm:"default:1" json:"uses_remaining,omitempty"`               // Nullable: Num uses left. NULL = unlimited. 1 = single use.
+	MaxUses       *int       `gorm:"default:1" json:"max_uses,omitempty"`                     // Nullable: Total allowed uses. NULL = unlimited.
+	ImageURL      string     `gorm:"type:text" json:"image_url"`                              // Optional image URL for badge
+	Emoji         string     `gorm:"size:10" json:"emoji"`                                    // Optional emoji for badge
+	Excerpt       string     `gorm:"type:text" json:"excerpt"`                                // Short description or note
+	Description   string     `json:"description"`                                             // Longer description
 	IsActive      bool       `gorm:"default:true" json:"is_active"`
 	IsViewed      bool       `gorm:"default:false" json:"is_viewed"`    // New flag
 	IsRedeemed    bool       `gorm:"default:false" json:"is_redeemed"`  // True if used at least once / fully consumed based on logic
@@ -314,11 +415,21 @@ type UserWaiver struct {
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
 
+// MarshalJSON reports Amount and UsedAmount in currency units
+func (w UserWaiver) MarshalJSON() ([]byte, error) {
+	type waiver UserWaiver
+	return json.Marshal(struct {
+		waiver
+		Amount     float64 `json:"amount"`
+		UsedAmount float64 `json:"used_amount"`
+	}{waiver(w), MajorUnits(w.Amount, w.Currency), MajorUnits(w.UsedAmount, w.Currency)})
+}
+
 // TournamentParticipation = subscription + activity summary
 type TournamentParticipation struct {
 	ID             string `gorm:"primaryKey;type:uuid;default:gen_random_uuid()" json:"id"`
-	ExternalUserID string `gorm:"index;not null" json:"external_user_id"`
-	TournamentID   string `gorm:"index;not null" json:"tournament_id"`
+	ExternalUserID string `gorm:"index;uniqueIndex:idx_participation_tournament_user;not null" json:"external_user_id"`
+	TournamentID   string `gorm:"index;uniqueIndex:idx_participation_tournament_user;not null" json:"tournament_id"`
 	SubscriptionID string `gorm:"index;not null" json:"subscription_id"` // links to TournamentSubscription.ID
 
 	// Engagement
@@ -327,9 +438,10 @@ type TournamentParticipation struct {
 	FinalRank          int   `json:"final_rank" gorm:"default:0"` // 0 = not ranked
 
 	// XP & rewards
-	XPEarned     int64  `json:"xp_earned" gorm:"default:0"`
-	PrizeEarned  string `json:"prize_earned,omitempty"` // e.g., "100 USDC"
-	BadgeAwarded string `json:"badge_awarded,omitempty"`
+	XPEarned     int64      `json:"xp_earned" gorm:"default:0"`
+	PrizeEarned  string     `json:"prize_earned,omitempty"` // e.g., "100 USDC"
+	BadgeAwarded string     `json:"badge_awarded,omitempty"`
+	PrizePaidAt  *time.Time `json:"prize_paid_at,omitempty"` // set once prize rewards have been issued
 
 	// Status
 	Status string `json:"status" gorm:"type:varchar(16);default:'joined'"` // joined → active → completed → disqualified
@@ -353,7 +465,7 @@ func (w *UserWaiver) BeforeCreate(tx *gorm.DB) error {
 
 	// For 'respawn' type, Amount is likely irrelevant. Ensure it's zero.
 	if w.Type == "respawn" {
-		w.Amount = 0.0
+		w.Amount = 0
 		// UsesRemaining and MaxUses become crucial for respawn.
 		// Default of 1 use makes sense for a single-respawn token.
 		// If UsesRemaining is explicitly set to 0 or negative, it might indicate an error during creation.
@@ -373,7 +485,7 @@ func (w *UserWaiver) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-func (w *UserWaiver) ConsumeUsage(tx *gorm.DB, amountToConsume float64) error {
+func (w *UserWaiver) ConsumeUsage(tx *gorm.DB, amountToConsume int64) error {
 	// 1. Check general validity
 	now := time.Now()
 	if !w.IsActive {
@@ -447,10 +559,10 @@ func (w *UserWaiver) ConsumeUsage(tx *gorm.DB, amountToConsume float64) error {
 
 	// 3. Update the waiver record in the provided transaction
 	updates := map[string]interface{}{
-		"used_amount":    w.UsedAmount,
-		"uses_remaining": w.UsesRemaining,
-		"is_redeemed":    w.IsRedeemed,
-		"updated_at":     time.Now(),
+		"used_amount_minor": w.UsedAmount,
+		"uses_remaining":    w.UsesRemaining,
+		"is_redeemed":       w.IsRedeemed,
+		"updated_at":        time.Now(),
 	}
 	// If this is the first use, potentially set ExpiresAt based on DurationHours
 	if (w.Type == "discount" || w.Type == "cashback" || w.Type == "entry_fee_reduction") && w.UsedAmount > 0 && w.ExpiresAt == nil && w.DurationHours > 0 {
@@ -0,0 +1,67 @@
+package models
+
+// Tournament lifecycle statuses: draft → scheduled → published → active → completed/cancelled
+const (
+	TournamentStatusDraft     = "draft"
+	TournamentStatusScheduled = "scheduled"
+	TournamentStatusPublished = "published"
+	TournamentStatusActive    = "active"
+	TournamentStatusCompleted = "completed"
+	TournamentStatusCancelled = "cancelled"
+)
+
+// Statuses shared by TournamentMatch and TournamentRound
+const (
+	StageStatusPending   = "pending"
+	StageStatusActive    = "active"
+	StageStatusCompleted = "completed"
+	StageStatusCancelled = "cancelled"
+)
+
+// tournamentTransitions lists the allowed next statuses for each tournament status.
+// completed and cancelled are terminal.
+var tournamentTransitions = map[string][]string{
+	TournamentStatusDraft:     {TournamentStatusScheduled, TournamentStatusPublished, TournamentStatusCancelled},
+	TournamentStatusScheduled: {TournamentStatusDraft, TournamentStatusPublished, TournamentStatusCancelled},
+	TournamentStatusPublished: {TournamentStatusDraft, TournamentStatusActive, TournamentStatusCancelled},
+	TournamentStatusActive:    {TournamentStatusCompleted, TournamentStatusCancelled},
+}
+
+// stageTransitions lists the allowed next statuses for matches and rounds
+var stageTransitions = map[string][]string{
+	StageStatusPending: {StageStatusActive, StageStatusCompleted, StageStatusCancelled},
+	StageStatusActive:  {StageStatusCompleted, StageStatusCancelled},
+}
+
+// CanTransitionTournament reports whether a tournament may move from one status to another
+func CanTransitionTournament(from, to string) bool {
+	if from == "" {
+		from = TournamentStatusDraft
+	}
+	return containsStatus(tournamentTransitions[from], to)
+}
+
+// CanTransitionStage reports whether a match or round may move from one status to another
+func CanTransitionStage(from, to string) bool {
+	if from == "" {
+		from = StageStatusPending
+	}
+	if from == to {
+		return true
+	}
+	return containsStatus(stageTransitions[from], to)
+}
+
+// IsTerminalTournamentStatus reports whether no further transitions are possible
+func IsTerminalTournamentStatus(status string) bool {
+	return status == TournamentStatusCompleted || status == TournamentStatusCancelled
+}
+
+func containsStatus(list []string, status string) bool {
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
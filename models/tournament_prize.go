@@ -0,0 +1,51 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Prize types for a TournamentPrize row
+const (
+	PrizeTypeCash  = "cash"
+	PrizeTypeItem  = "item"
+	PrizeTypeBadge = "badge"
+)
+
+// Prize funding for Tournament.PrizeFunding
+const (
+	PrizeFundingSponsored = "sponsored" // paid by the sponsor, not bounded by entry fees
+	PrizeFundingPool      = "pool"      // paid out of collected entry fees
+)
+
+// TournamentPrize awards every final rank in [RankFrom, RankTo] the same prize
+type TournamentPrize struct {
+	ID           string    `json:"id" gorm:"primaryKey"`
+	TournamentID string    `json:"tournament_id" gorm:"not null;index"`
+	RankFrom     int       `json:"rank_from" gorm:"not null"`
+	RankTo       int       `json:"rank_to" gorm:"not null"`
+	PrizeType    string    `json:"prize_type" gorm:"type:varchar(16);not null"` // cash, item, badge
+	Title        string    `json:"title"`
+	Amount       int64     `json:"-" gorm:"column:amount_minor;not null;default:0"` // per winner in minor units of Currency, cash only
+	Currency     string    `json:"currency,omitempty" gorm:"type:varchar(16)"`      // cash only; defaults to the tournament's currency
+	ItemDetails  string    `json:"item_details,omitempty"`
+	BadgeCode    string    `json:"badge_code,omitempty"` // BadgeType.Code
+	ImageURL     string    `json:"image_url,omitempty"`
+	Emoji        string    `json:"emoji,omitempty" gorm:"size:10"`
+	CreatedAt    time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+}
+
+// Winners returns how many ranks the row covers
+func (p TournamentPrize) Winners() int {
+	return p.RankTo - p.RankFrom + 1
+}
+
+// MarshalJSON reports Amount in currency units
+func (p TournamentPrize) MarshalJSON() ([]byte, error) {
+	type prize TournamentPrize
+	return json.Marshal(struct {
+		prize
+		Amount float64 `json:"amount"`
+	}{prize(p), MajorUnits(p.Amount, p.Currency)})
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Waitlist entry statuses: waiting → offered → enrolled, or expired/cancelled
+const (
+	WaitlistStatusWaiting   = "waiting"
+	WaitlistStatusOffered   = "offered" // promoted: a slot is held until HoldExpiresAt
+	WaitlistStatusEnrolled  = "enrolled"
+	WaitlistStatusExpired   = "expired"
+	WaitlistStatusCancelled = "cancelled"
+)
+
+// WaitlistEntry queues a user (or a captain's team) for a full tournament, first come first served
+type WaitlistEntry struct {
+	ID             string     `json:"id" gorm:"primaryKey"`
+	TournamentID   string     `json:"tournament_id" gorm:"not null;uniqueIndex:idx_waitlist_user;index:idx_waitlist_queue"`
+	ExternalUserID string     `json:"external_user_id" gorm:"not null;uniqueIndex:idx_waitlist_user"`
+	UserName       string     `json:"user_name"`
+	UserAvatarURL  *string    `json:"user_avatar_url,omitempty"`
+	TeamID         string     `json:"team_id,omitempty"`
+	Status         string     `json:"status" gorm:"type:varchar(16);default:'waiting';index:idx_waitlist_queue"`
+	QueuedAt       time.Time  `json:"queued_at" gorm:"index:idx_waitlist_queue"` // FIFO order; reset when re-joining
+	OfferedAt      *time.Time `json:"offered_at,omitempty" gorm:"index"`
+	HoldExpiresAt  *time.Time `json:"hold_expires_at,omitempty"`
+	EnrolledAt     *time.Time `json:"enrolled_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"autoUpdateTime"`
+}
@@ -0,0 +1,569 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Built-in elimination match types (seeded as MatchTypeConfigs)
+const (
+	MatchTypeSingleElimination = "SINGLE_ELIMINATION_1V1"
+	MatchTypeDoubleElimination = "DOUBLE_ELIMINATION_1V1"
+)
+
+var (
+	ErrBracketNotFound      = errors.New("bracket not found")
+	ErrBracketCompleted     = errors.New("bracket is already completed")
+	ErrBracketNodeNotReady  = errors.New("bracket node is not waiting for a result")
+	ErrInvalidBracketWinner = errors.New("winner must be one of the node's players")
+	ErrBracketHasResults    = errors.New("bracket already has results and cannot be rebuilt")
+)
+
+// nextPowerOfTwo returns the smallest power of two >= n (minimum 2)
+func nextPowerOfTwo(n int) int {
+	size := 2
+	for size < n {
+		size *= 2
+	}
+	return size
+}
+
+// bracketSeedOrder returns the standard seed layout for a bracket of the given size,
+// e.g. 8 → [1 8 4 5 2 7 3 6], so the top seeds can only meet in the latest rounds
+func bracketSeedOrder(size int) []int {
+	order := []int{1}
+	for len(order) < size {
+		n := len(order)*2 + 1
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, n-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// losersRoundSize is the number of nodes in losers bracket round L (1-based)
+func losersRoundSize(size, round int) int {
+	return size >> ((round+1)/2 + 1)
+}
+
+// bracketState is an in-memory bracket used while building or advancing it
+type bracketState struct {
+	bracket *models.Bracket
+	nodes   map[string]*models.BracketNode
+	dirty   map[string]bool
+	resetID string
+}
+
+func newBracketState(bracket *models.Bracket, nodes []models.BracketNode) *bracketState {
+	st := &bracketState{
+		bracket: bracket,
+		nodes:   make(map[string]*models.BracketNode, len(nodes)),
+		dirty:   make(map[string]bool),
+	}
+	for i := range nodes {
+		n := &nodes[i]
+		st.nodes[n.ID] = n
+		if n.Section == models.BracketSectionGrandFinalReset {
+			st.resetID = n.ID
+		}
+	}
+	return st
+}
+
+// buildBracket lays out every node for a bracket whose first round is given by pairs
+// (a pair with an empty Player2ID is a bye) and resolves the byes
+func buildBracket(bracket *models.Bracket, pairs []Pair) *bracketState {
+	size := nextPowerOfTwo(len(pairs) * 2)
+	rounds := 0
+	for s := size; s > 1; s /= 2 {
+		rounds++
+	}
+	bracket.Size = size
+	bracket.Rounds = rounds
+	double := bracket.Format == models.ProgressionDoubleElimination
+
+	var nodes []models.BracketNode
+	grid := make(map[string][][]int) // section → round → node indexes
+	add := func(section string, round, count int) {
+		idx := make([]int, count)
+		for p := 0; p < count; p++ {
+			idx[p] = len(nodes)
+			nodes = append(nodes, models.BracketNode{
+				ID:        uuid.NewString(),
+				BracketID: bracket.ID,
+				Section:   section,
+				Round:     round,
+				Position:  p,
+				Status:    models.BracketNodePending,
+			})
+		}
+		grid[section] = append(grid[section], idx)
+	}
+
+	for r := 1; r <= rounds; r++ {
+		add(models.BracketSectionWinners, r, size>>r)
+	}
+	losersRounds := 0
+	if double {
+		losersRounds = 2 * (rounds - 1)
+		for l := 1; l <= losersRounds; l++ {
+			add(models.BracketSectionLosers, l, losersRoundSize(size, l))
+		}
+		add(models.BracketSectionGrandFinal, 1, 1)
+		add(models.BracketSectionGrandFinalReset, 1, 1)
+	}
+
+	at := func(section string, round, pos int) *models.BracketNode {
+		return &nodes[grid[section][round-1][pos]]
+	}
+	winnerTo := func(n, next *models.BracketNode, slot int) {
+		n.NextWinnerNodeID, n.NextWinnerSlot = next.ID, slot
+	}
+	loserTo := func(n, next *models.BracketNode, slot int) {
+		n.NextLoserNodeID, n.NextLoserSlot = next.ID, slot
+	}
+
+	// Winners bracket
+	for r := 1; r < rounds; r++ {
+		for p := 0; p < size>>r; p++ {
+			winnerTo(at(models.BracketSectionWinners, r, p), at(models.BracketSectionWinners, r+1, p/2), p%2+1)
+		}
+	}
+
+	if double {
+		grandFinal := at(models.BracketSectionGrandFinal, 1, 0)
+		winnerTo(at(models.BracketSectionWinners, rounds, 0), grandFinal, 1)
+
+		if rounds == 1 {
+			loserTo(at(models.BracketSectionWinners, 1, 0), grandFinal, 2)
+		} else {
+			// Round 1 losers meet each other; later winners-bracket losers drop in
+			// against the losers-bracket survivors (mirrored to delay rematches)
+			for p := 0; p < size/2; p++ {
+				loserTo(at(models.BracketSectionWinners, 1, p), at(models.BracketSectionLosers, 1, p/2), p%2+1)
+			}
+			for r := 2; r <= rounds; r++ {
+				count := size >> r
+				for p := 0; p < count; p++ {
+					loserTo(at(models.BracketSectionWinners, r, p), at(models.BracketSectionLosers, 2*(r-1), count-1-p), 2)
+				}
+			}
+			for l := 1; l <= losersRounds; l++ {
+				for p := 0; p < losersRoundSize(size, l); p++ {
+					n := at(models.BracketSectionLosers, l, p)
+					switch {
+					case l == losersRounds:
+						winnerTo(n, grandFinal, 2)
+					case l%2 == 1:
+						winnerTo(n, at(models.BracketSectionLosers, l+1, p), 1)
+					default:
+						winnerTo(n, at(models.BracketSectionLosers, l+1, p/2), p%2+1)
+					}
+				}
+			}
+		}
+	}
+
+	st := newBracketState(bracket, nodes)
+	for id := range st.nodes {
+		st.dirty[id] = true
+	}
+
+	// Seat the first round; missing pairs are bye vs bye
+	for p := 0; p < size/2; p++ {
+		n := at(models.BracketSectionWinners, 1, p)
+		if p < len(pairs) {
+			pair := pairs[p]
+			st.place(n.ID, 1, pair.Player1ID, pair.Player1Name, pair.Player1Seed)
+			st.place(n.ID, 2, pair.Player2ID, pair.Player2Name, pair.Player2Seed)
+		} else {
+			st.place(n.ID, 1, "", "", 0)
+			st.place(n.ID, 2, "", "", 0)
+		}
+	}
+
+	return st
+}
+
+// sortedNodes returns nodes ordered for storage and display
+func (st *bracketState) sortedNodes() []models.BracketNode {
+	sectionOrder := map[string]int{
+		models.BracketSectionWinners:         0,
+		models.BracketSectionLosers:          1,
+		models.BracketSectionGrandFinal:      2,
+		models.BracketSectionGrandFinalReset: 3,
+	}
+	out := make([]models.BracketNode, 0, len(st.nodes))
+	for _, n := range st.nodes {
+		out = append(out, *n)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Section != b.Section {
+			return sectionOrder[a.Section] < sectionOrder[b.Section]
+		}
+		if a.Round != b.Round {
+			return a.Round < b.Round
+		}
+		return a.Position < b.Position
+	})
+	return out
+}
+
+// place puts a player (or a bye when playerID is empty) into a node slot and resolves the node
+func (st *bracketState) place(nodeID string, slot int, playerID, name string, seed int) {
+	n, ok := st.nodes[nodeID]
+	if !ok {
+		return
+	}
+	if slot == 1 {
+		n.Player1ID, n.Player1Name, n.Player1Seed, n.Player1Bye = playerID, name, seed, playerID == ""
+	} else {
+		n.Player2ID, n.Player2Name, n.Player2Seed, n.Player2Bye = playerID, name, seed, playerID == ""
+	}
+	st.dirty[n.ID] = true
+	st.resolve(n)
+}
+
+// resolve moves a pending node on once both slots are known: two players make it ready,
+// one player walks over the bye, and two byes skip the node entirely
+func (st *bracketState) resolve(n *models.BracketNode) {
+	if n.Status != models.BracketNodePending {
+		return
+	}
+	slot1Known := n.Player1ID != "" || n.Player1Bye
+	slot2Known := n.Player2ID != "" || n.Player2Bye
+	if !slot1Known || !slot2Known {
+		return
+	}
+
+	switch {
+	case n.Player1ID != "" && n.Player2ID != "":
+		n.Status = models.BracketNodeReady
+		st.dirty[n.ID] = true
+	case n.Player1ID != "":
+		st.complete(n, 1, 0, 0)
+	case n.Player2ID != "":
+		st.complete(n, 2, 0, 0)
+	default:
+		n.Status = models.BracketNodeSkipped
+		st.dirty[n.ID] = true
+		st.advance(n, "", "", 0, "", "", 0)
+	}
+}
+
+// complete records a node result and pushes winner and loser along their edges
+func (st *bracketState) complete(n *models.BracketNode, winnerSlot int, score1, score2 int64) {
+	now := time.Now()
+	n.Status = models.BracketNodeCompleted
+	n.CompletedAt = &now
+	n.Player1Score, n.Player2Score = score1, score2
+
+	winnerID, winnerName, winnerSeed := n.Player1ID, n.Player1Name, n.Player1Seed
+	loserID, loserName, loserSeed := n.Player2ID, n.Player2Name, n.Player2Seed
+	if winnerSlot == 2 {
+		winnerID, winnerName, winnerSeed, loserID, loserName, loserSeed = loserID, loserName, loserSeed, winnerID, winnerName, winnerSeed
+	}
+	n.WinnerID, n.WinnerName = winnerID, winnerName
+	n.LoserID, n.LoserName = loserID, loserName
+	st.dirty[n.ID] = true
+
+	st.advance(n, winnerID, winnerName, winnerSeed, loserID, loserName, loserSeed)
+}
+
+// advance follows a decided node's edges; empty IDs propagate as byes
+func (st *bracketState) advance(n *models.BracketNode, winnerID, winnerName string, winnerSeed int, loserID, loserName string, loserSeed int) {
+	switch n.Section {
+	case models.BracketSectionGrandFinal:
+		// The winners-bracket champion (slot 1) only has to win once; otherwise play the reset
+		if reset, ok := st.nodes[st.resetID]; ok {
+			if loserID == "" || winnerID == n.Player1ID {
+				reset.Status = models.BracketNodeSkipped
+				st.dirty[reset.ID] = true
+				st.crown(winnerID, winnerName)
+			} else {
+				st.place(reset.ID, 1, winnerID, winnerName, winnerSeed)
+				st.place(reset.ID, 2, loserID, loserName, loserSeed)
+			}
+			return
+		}
+		st.crown(winnerID, winnerName)
+		return
+	case models.BracketSectionGrandFinalReset:
+		st.crown(winnerID, winnerName)
+		return
+	}
+
+	if n.NextWinnerNodeID == "" {
+		// Single elimination final
+		st.crown(winnerID, winnerName)
+	} else {
+		st.place(n.NextWinnerNodeID, n.NextWinnerSlot, winnerID, winnerName, winnerSeed)
+	}
+	if n.NextLoserNodeID != "" {
+		st.place(n.NextLoserNodeID, n.NextLoserSlot, loserID, loserName, loserSeed)
+	}
+}
+
+// crown marks the bracket as won
+func (st *bracketState) crown(playerID, name string) {
+	if playerID == "" || st.bracket.Status == models.BracketStatusCompleted {
+		return
+	}
+	now := time.Now()
+	st.bracket.ChampionID = playerID
+	st.bracket.ChampionName = name
+	st.bracket.Status = models.BracketStatusCompleted
+	st.bracket.CompletedAt = &now
+}
+
+// saveDirty writes every node changed since the state was loaded
+func (st *bracketState) saveDirty(tx *gorm.DB) error {
+	for id := range st.dirty {
+		if err := tx.Save(st.nodes[id]).Error; err != nil {
+			return err
+		}
+	}
+	st.dirty = make(map[string]bool)
+	return nil
+}
+
+// recordMatchWinner copies the bracket champion onto the TournamentMatch
+func recordMatchWinner(tx *gorm.DB, matchID, winnerID, winnerName string) error {
+	var match models.TournamentMatch
+	if err := tx.Select("id", "status").First(&match, "id = ?", matchID).Error; err != nil {
+		return err
+	}
+	updates := map[string]interface{}{
+		"winner_id":   winnerID,
+		"winner_name": winnerName,
+	}
+	if models.CanTransitionStage(match.Status, models.StageStatusCompleted) {
+		updates["status"] = models.StageStatusCompleted
+	}
+	return tx.Model(&models.TournamentMatch{}).Where("id = ?", matchID).Updates(updates).Error
+}
+
+// publishBracket (re)builds the bracket for a match from its published first-round pairs.
+// An existing bracket is replaced only while no game in it has actually been played.
+func publishBracket(tx *gorm.DB, pairing *models.MatchPairing, format string, pairs []Pair) (*models.Bracket, error) {
+	var existing models.Bracket
+	err := tx.Where("match_id = ?", pairing.MatchID).First(&existing).Error
+	if err == nil {
+		var played int64
+		if err := tx.Model(&models.BracketNode{}).
+			Where("bracket_id = ? AND status = ? AND player1_id <> '' AND player2_id <> ''",
+				existing.ID, models.BracketNodeCompleted).
+			Count(&played).Error; err != nil {
+			return nil, err
+		}
+		if played > 0 {
+			return nil, ErrBracketHasResults
+		}
+		if err := tx.Where("bracket_id = ?", existing.ID).Delete(&models.BracketNode{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Delete(&existing).Error; err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	bracket := &models.Bracket{
+		ID:           uuid.NewString(),
+		TournamentID: pairing.TournamentID,
+		MatchID:      pairing.MatchID,
+		PairingID:    pairing.ID,
+		Format:       format,
+		Status:       models.BracketStatusActive,
+	}
+	st := buildBracket(bracket, pairs)
+
+	if err := tx.Create(bracket).Error; err != nil {
+		return nil, err
+	}
+	nodes := st.sortedNodes()
+	if err := tx.Create(&nodes).Error; err != nil {
+		return nil, err
+	}
+	if bracket.ChampionID != "" {
+		if err := recordMatchWinner(tx, bracket.MatchID, bracket.ChampionID, bracket.ChampionName); err != nil {
+			return nil, err
+		}
+	}
+
+	bracket.Nodes = nodes
+	log.Printf("🏟️ Built %s bracket %s for match %s (%d slots, %d nodes)",
+		format, bracket.ID, bracket.MatchID, bracket.Size, len(nodes))
+	return bracket, nil
+}
+
+// ReportBracketResult records the winner of a ready node and advances the bracket.
+// When the final (or grand final reset) is decided the champion becomes the match winner.
+func (ps *PairingService) ReportBracketResult(bracketID, nodeID, winnerID string, score1, score2 int64) (*models.Bracket, error) {
+	var bracket *models.Bracket
+	err := ps.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		bracket, err = reportBracketResultTx(tx, bracketID, nodeID, winnerID, score1, score2)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return bracket, nil
+}
+
+// reportBracketResultTx is ReportBracketResult inside the caller's transaction
+func reportBracketResultTx(tx *gorm.DB, bracketID, nodeID, winnerID string, score1, score2 int64) (*models.Bracket, error) {
+	var bracket models.Bracket
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		First(&bracket, "id = ?", bracketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBracketNotFound
+		}
+		return nil, err
+	}
+	if bracket.Status == models.BracketStatusCompleted {
+		return nil, ErrBracketCompleted
+	}
+
+	var nodes []models.BracketNode
+	if err := tx.Where("bracket_id = ?", bracket.ID).Find(&nodes).Error; err != nil {
+		return nil, err
+	}
+	st := newBracketState(&bracket, nodes)
+
+	n, ok := st.nodes[nodeID]
+	if !ok {
+		return nil, ErrBracketNotFound
+	}
+	if n.Status != models.BracketNodeReady {
+		return nil, ErrBracketNodeNotReady
+	}
+
+	switch winnerID {
+	case n.Player1ID:
+		st.complete(n, 1, score1, score2)
+	case n.Player2ID:
+		st.complete(n, 2, score1, score2)
+	default:
+		return nil, ErrInvalidBracketWinner
+	}
+	if err := applyRatedGames(tx, "node:"+n.ID, bracket.TournamentID, []ratedGame{{a: n.WinnerID, b: n.LoserID, scoreA: 1}}); err != nil {
+		return nil, err
+	}
+
+	if err := st.saveDirty(tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Save(&bracket).Error; err != nil {
+		return nil, err
+	}
+	if bracket.ChampionID != "" {
+		if err := recordMatchWinner(tx, bracket.MatchID, bracket.ChampionID, bracket.ChampionName); err != nil {
+			return nil, err
+		}
+		log.Printf("🏆 Bracket %s won by %s", bracket.ID, bracket.ChampionID)
+	}
+
+	bracket.Nodes = st.sortedNodes()
+	return &bracket, nil
+}
+
+// bracketErrorResponse maps bracket errors to HTTP responses
+func bracketErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrBracketNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrInvalidBracketWinner):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrBracketCompleted), errors.Is(err, ErrBracketNodeNotReady), errors.Is(err, ErrBracketHasResults):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "bracket update failed", "details": err.Error()})
+	}
+}
+
+// ReportBracketNodeResult is the admin endpoint for entering a node result
+func (ps *PairingService) ReportBracketNodeResult(c *fiber.Ctx) error {
+	var req struct {
+		WinnerID     string `json:"winner_id"`
+		Player1Score int64  `json:"player1_score"`
+		Player2Score int64  `json:"player2_score"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+	if req.WinnerID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "winner_id is required"})
+	}
+
+	bracket, err := ps.ReportBracketResult(c.Params("bracket_id"), c.Params("node_id"), req.WinnerID, req.Player1Score, req.Player2Score)
+	if err != nil {
+		return bracketErrorResponse(c, err)
+	}
+	return c.JSON(bracket)
+}
+
+// GetMatchBracket returns the bracket of a match with all of its nodes
+func (ps *PairingService) GetMatchBracket(c *fiber.Ctx) error {
+	return ps.bracketResponse(c, ps.DB.Where("match_id = ?", c.Params("match_id")))
+}
+
+// GetPublishedMatchBracket returns a match bracket of a published tournament
+func (ps *PairingService) GetPublishedMatchBracket(c *fiber.Ctx) error {
+	return ps.bracketResponse(c, ps.DB.
+		Joins("JOIN tournaments ON tournaments.id = brackets.tournament_id").
+		Where("brackets.match_id = ? AND brackets.tournament_id = ?", c.Params("match_id"), c.Params("id")).
+		Where("tournaments.status IN ('published', 'active', 'scheduled', 'completed')"))
+}
+
+func (ps *PairingService) bracketResponse(c *fiber.Ctx, query *gorm.DB) error {
+	var bracket models.Bracket
+	if err := query.Preload("Nodes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("section ASC, round ASC, position ASC")
+	}).First(&bracket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "bracket not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+	return c.JSON(bracket)
+}
+
+// describeBracketPairs summarises an elimination first round for pairing metadata
+func describeBracketPairs(pairs []Pair, players int, progression string) map[string]interface{} {
+	size := nextPowerOfTwo(len(pairs) * 2)
+	var byes []map[string]interface{}
+	for _, p := range pairs {
+		if p.Player2ID == "" && p.Player1ID != "" {
+			byes = append(byes, map[string]interface{}{"id": p.Player1ID, "name": p.Player1Name})
+		}
+	}
+	rounds := 0
+	for s := size; s > 1; s /= 2 {
+		rounds++
+	}
+	return map[string]interface{}{
+		"bracket_type":  progression,
+		"total_players": players,
+		"bracket_size":  size,
+		"rounds":        rounds,
+		"has_bye":       len(byes) > 0,
+		"bye_players":   byes,
+		"double":        progression == models.ProgressionDoubleElimination,
+		"summary":       fmt.Sprintf("%d players in a %d-slot bracket", players, size),
+	}
+}
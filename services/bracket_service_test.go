@@ -0,0 +1,198 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+
+	"game-publish-system/models"
+)
+
+// testEntrants returns n subscriptions p1..pn, best seed first
+func testEntrants(n int) []models.TournamentSubscription {
+	players := make([]models.TournamentSubscription, n)
+	for i := range players {
+		players[i] = models.TournamentSubscription{ExternalUserID: fmt.Sprintf("p%d", i+1), UserName: fmt.Sprintf("Player %d", i+1)}
+	}
+	return players
+}
+
+func TestBuildBracket(t *testing.T) {
+	tests := []struct {
+		players     int
+		format      string
+		size        int
+		nodes       int // winners, losers, grand final + reset
+		ready       int // games playable straight away
+		skipped     int // bye vs bye
+		wantChampID string
+	}{
+		{1, models.ProgressionSingleElimination, 2, 1, 0, 0, "p1"},
+		{2, models.ProgressionSingleElimination, 2, 1, 1, 0, ""},
+		{3, models.ProgressionSingleElimination, 4, 3, 1, 0, ""},
+		{5, models.ProgressionSingleElimination, 8, 7, 2, 0, ""},
+		{8, models.ProgressionSingleElimination, 8, 7, 4, 0, ""},
+		{2, models.ProgressionDoubleElimination, 2, 3, 1, 0, ""},
+		{3, models.ProgressionDoubleElimination, 4, 7, 1, 0, ""},
+		{5, models.ProgressionDoubleElimination, 8, 15, 2, 1, ""},
+		{8, models.ProgressionDoubleElimination, 8, 15, 4, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s %d players", tt.format, tt.players), func(t *testing.T) {
+			pairs, _ := (&PairingService{}).generateEliminationPairs(testEntrants(tt.players), tt.format)
+			bracket := &models.Bracket{ID: "b", Format: tt.format, Status: models.BracketStatusActive}
+			st := buildBracket(bracket, pairs)
+			nodes := st.sortedNodes()
+
+			if bracket.Size != tt.size {
+				t.Errorf("size = %d, want %d", bracket.Size, tt.size)
+			}
+			if len(nodes) != tt.nodes {
+				t.Fatalf("%d nodes, want %d", len(nodes), tt.nodes)
+			}
+			if bracket.ChampionID != tt.wantChampID {
+				t.Errorf("champion = %q, want %q", bracket.ChampionID, tt.wantChampID)
+			}
+			checkBracketEdges(t, st, bracket)
+
+			ready, skipped := 0, 0
+			for _, n := range nodes {
+				bye1, bye2 := n.Player1Bye, n.Player2Bye
+				switch n.Status {
+				case models.BracketNodeReady:
+					ready++
+					if bye1 || bye2 {
+						t.Errorf("%s r%d #%d is ready against a bye", n.Section, n.Round, n.Position)
+					}
+				case models.BracketNodeSkipped:
+					if n.Section == models.BracketSectionGrandFinalReset {
+						continue
+					}
+					skipped++
+					if !bye1 || !bye2 {
+						t.Errorf("%s r%d #%d skipped with a player in it", n.Section, n.Round, n.Position)
+					}
+				case models.BracketNodeCompleted:
+					if n.WinnerID == "" {
+						t.Errorf("%s r%d #%d completed without a winner", n.Section, n.Round, n.Position)
+					}
+					if next, ok := st.nodes[n.NextWinnerNodeID]; ok {
+						got := next.Player1ID
+						if n.NextWinnerSlot == 2 {
+							got = next.Player2ID
+						}
+						if got != n.WinnerID {
+							t.Errorf("%s r%d #%d walkover winner %s not advanced", n.Section, n.Round, n.Position, n.WinnerID)
+						}
+					}
+				default:
+					if bye1 && bye2 {
+						t.Errorf("%s r%d #%d is bye vs bye but %s", n.Section, n.Round, n.Position, n.Status)
+					}
+				}
+			}
+			if ready != tt.ready {
+				t.Errorf("%d ready nodes, want %d", ready, tt.ready)
+			}
+			if skipped != tt.skipped {
+				t.Errorf("%d skipped nodes, want %d", skipped, tt.skipped)
+			}
+		})
+	}
+}
+
+// TestBuildBracketDoubleBye seats a first round with an empty pair: the bye vs bye node is
+// skipped and its slot in the next round becomes a walkover
+func TestBuildBracketDoubleBye(t *testing.T) {
+	for _, format := range []string{models.ProgressionSingleElimination, models.ProgressionDoubleElimination} {
+		t.Run(format, func(t *testing.T) {
+			bracket := &models.Bracket{ID: "b", Format: format, Status: models.BracketStatusActive}
+			st := buildBracket(bracket, []Pair{{Player1ID: "p1", Player2ID: "p2"}, {}})
+			checkBracketEdges(t, st, bracket)
+
+			var first, empty, final *models.BracketNode
+			for _, n := range st.nodes {
+				if n.Section != models.BracketSectionWinners {
+					continue
+				}
+				switch {
+				case n.Round == 1 && n.Position == 0:
+					first = n
+				case n.Round == 1:
+					empty = n
+				default:
+					final = n
+				}
+			}
+			if first.Status != models.BracketNodeReady {
+				t.Errorf("p1 vs p2 is %s, want ready", first.Status)
+			}
+			if empty.Status != models.BracketNodeSkipped {
+				t.Errorf("bye vs bye is %s, want skipped", empty.Status)
+			}
+			if !final.Player2Bye || final.Status != models.BracketNodePending {
+				t.Errorf("final = %s with slot 2 bye %v, want pending on a bye", final.Status, final.Player2Bye)
+			}
+		})
+	}
+}
+
+// checkBracketEdges checks that winners move up one round, every slot after the first round is
+// fed by exactly one edge, and losers only drop out of a double elimination bracket
+func checkBracketEdges(t *testing.T, st *bracketState, bracket *models.Bracket) {
+	t.Helper()
+	double := bracket.Format == models.ProgressionDoubleElimination
+	fed := map[string]int{} // node ID + slot → incoming edges
+	for _, n := range st.nodes {
+		for _, edge := range []struct {
+			to   string
+			slot int
+		}{{n.NextWinnerNodeID, n.NextWinnerSlot}, {n.NextLoserNodeID, n.NextLoserSlot}} {
+			if edge.to == "" {
+				continue
+			}
+			if _, ok := st.nodes[edge.to]; !ok || (edge.slot != 1 && edge.slot != 2) {
+				t.Errorf("%s r%d #%d has an edge to a missing node or slot %d", n.Section, n.Round, n.Position, edge.slot)
+				continue
+			}
+			fed[fmt.Sprintf("%s/%d", edge.to, edge.slot)]++
+		}
+
+		if n.Section == models.BracketSectionWinners {
+			next := st.nodes[n.NextWinnerNodeID]
+			switch {
+			case n.Round < bracket.Rounds:
+				if next == nil || next.Section != models.BracketSectionWinners || next.Round != n.Round+1 ||
+					next.Position != n.Position/2 || n.NextWinnerSlot != n.Position%2+1 {
+					t.Errorf("winners r%d #%d does not feed r%d #%d slot %d", n.Round, n.Position, n.Round+1, n.Position/2, n.Position%2+1)
+				}
+			case double:
+				if next == nil || next.Section != models.BracketSectionGrandFinal || n.NextWinnerSlot != 1 {
+					t.Errorf("winners final does not feed grand final slot 1")
+				}
+			default:
+				if next != nil {
+					t.Errorf("single elimination final has a winner edge")
+				}
+			}
+			if double != (n.NextLoserNodeID != "") {
+				t.Errorf("winners r%d #%d loser edge = %q in %s", n.Round, n.Position, n.NextLoserNodeID, bracket.Format)
+			}
+		}
+		if n.Section == models.BracketSectionLosers && n.NextWinnerNodeID == "" {
+			t.Errorf("losers r%d #%d leads nowhere", n.Round, n.Position)
+		}
+	}
+
+	for _, n := range st.nodes {
+		seated := n.Section == models.BracketSectionWinners && n.Round == 1
+		for slot := 1; slot <= 2; slot++ {
+			want := 1
+			if seated || n.Section == models.BracketSectionGrandFinalReset {
+				want = 0
+			}
+			if got := fed[fmt.Sprintf("%s/%d", n.ID, slot)]; got != want {
+				t.Errorf("%s r%d #%d slot %d is fed by %d edges, want %d", n.Section, n.Round, n.Position, slot, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrCheckInNotRequired = errors.New("check-in is not required here")
+	ErrCheckInNotOpen     = errors.New("check-in has not opened yet")
+	ErrCheckInClosed      = errors.New("check-in has closed")
+	ErrNotCheckedIn       = errors.New("not checked in")
+	ErrForfeited          = errors.New("this entry was forfeited for missing check-in")
+)
+
+// checkInSettingKeys are the tournament form fields parseCheckInSettings reads
+var checkInSettingKeys = []string{"check_in_required", "check_in_opens_minutes", "check_in_closes_minutes", "auto_forfeit_no_shows"}
+
+// parseCheckInSettings reads check_in_required, check_in_opens_minutes,
+// check_in_closes_minutes and auto_forfeit_no_shows from a tournament form. Fields not in the
+// form keep their value in current.
+func parseCheckInSettings(c *fiber.Ctx, current models.Tournament) (bool, int, int, bool, error) {
+	required, autoForfeit := current.CheckInRequired, current.AutoForfeitNoShows
+	if v := c.FormValue("check_in_required"); v != "" {
+		required = strings.ToLower(v) == "true"
+	}
+	if v := c.FormValue("auto_forfeit_no_shows"); v != "" {
+		autoForfeit = strings.ToLower(v) == "true"
+	}
+	minutes := [2]int{current.CheckInOpensMinutes, current.CheckInClosesMinutes}
+	for i, key := range []string{"check_in_opens_minutes", "check_in_closes_minutes"} {
+		if v := c.FormValue(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return false, 0, 0, false, fmt.Errorf("%s must be a non-negative integer", key)
+			}
+			minutes[i] = n
+		}
+	}
+	if minutes[0] <= minutes[1] {
+		return false, 0, 0, false, errors.New("check_in_opens_minutes must be greater than check_in_closes_minutes")
+	}
+	return required, minutes[0], minutes[1], autoForfeit, nil
+}
+
+// checkInTarget resolves what is being checked into: the match when matchID is set (it must
+// require its own check-in), otherwise the tournament. Returns the window for it.
+func checkInTarget(db *gorm.DB, tournamentID, matchID string) (*models.Tournament, time.Time, time.Time, error) {
+	var tournament models.Tournament
+	if err := db.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		return nil, time.Time{}, time.Time{}, err
+	}
+
+	start := tournament.StartTime
+	if matchID != "" {
+		var match models.TournamentMatch
+		if err := db.Joins("JOIN tournament_batches ON tournament_batches.id = tournament_matches.batch_id").
+			Where("tournament_matches.id = ? AND tournament_batches.tournament_id = ?", matchID, tournamentID).
+			First(&match).Error; err != nil {
+			return nil, time.Time{}, time.Time{}, err
+		}
+		if !match.CheckInRequired {
+			return nil, time.Time{}, time.Time{}, ErrCheckInNotRequired
+		}
+		if !match.StartDate.IsZero() {
+			start = match.StartDate
+		}
+	} else if !tournament.CheckInRequired {
+		return nil, time.Time{}, time.Time{}, ErrCheckInNotRequired
+	}
+
+	opens, closes := tournament.CheckInWindow(start)
+	return &tournament, opens, closes, nil
+}
+
+// checkInWindowError reports why check-in can't change right now, or nil while it is open
+func checkInWindowError(now, opens, closes time.Time) error {
+	if now.Before(opens) {
+		return ErrCheckInNotOpen
+	}
+	if !now.Before(closes) {
+		return ErrCheckInClosed
+	}
+	return nil
+}
+
+// userSubscription finds the caller's pairable subscription (team entries are held by the captain)
+func userSubscription(db *gorm.DB, tournamentID, userID string) (*models.TournamentSubscription, error) {
+	var sub models.TournamentSubscription
+	err := db.Where("tournament_id = ? AND external_user_id = ? AND payment_status IN ? AND revoked_at IS NULL",
+		tournamentID, userID, activeSubscriptionStatuses).First(&sub).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotSubscribed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if sub.ForfeitedAt != nil {
+		return nil, ErrForfeited
+	}
+	return &sub, nil
+}
+
+// CheckIn marks the caller present for the tournament, or for a match with its own check-in
+func (s *TournamentService) CheckIn(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		MatchID string `json:"match_id"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+		}
+	}
+
+	_, opens, closes, err := checkInTarget(s.DB, tournamentID, req.MatchID)
+	if err != nil {
+		return checkInErrorResponse(c, err)
+	}
+	if err := checkInWindowError(time.Now(), opens, closes); err != nil {
+		return checkInErrorResponse(c, err)
+	}
+	sub, err := userSubscription(s.DB, tournamentID, userID)
+	if err != nil {
+		return checkInErrorResponse(c, err)
+	}
+
+	checkIn := models.CheckIn{
+		ID:             uuid.NewString(),
+		TournamentID:   tournamentID,
+		MatchID:        req.MatchID,
+		SubscriptionID: sub.ID,
+		UserID:         entrantID(*sub),
+		CheckedInBy:    userID,
+	}
+	res := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&checkIn)
+	if res.Error != nil {
+		return checkInErrorResponse(c, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		// Already checked in: return the original check-in
+		if err := s.DB.Where("tournament_id = ? AND match_id = ? AND subscription_id = ?", tournamentID, req.MatchID, sub.ID).
+			First(&checkIn).Error; err != nil {
+			return checkInErrorResponse(c, err)
+		}
+		return c.JSON(fiber.Map{"message": "already checked in", "check_in": checkIn})
+	}
+
+	log.Printf("✅ %s checked in to tournament %s (match %q)", checkIn.UserID, tournamentID, req.MatchID)
+	return c.Status(201).JSON(fiber.Map{
+		"message":       "checked in",
+		"check_in":      checkIn,
+		"window_closes": closes,
+	})
+}
+
+// UndoCheckIn withdraws the caller's check-in while the window is still open
+func (s *TournamentService) UndoCheckIn(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+	matchID := c.Query("match_id")
+
+	_, opens, closes, err := checkInTarget(s.DB, tournamentID, matchID)
+	if err != nil {
+		return checkInErrorResponse(c, err)
+	}
+	if err := checkInWindowError(time.Now(), opens, closes); err != nil {
+		return checkInErrorResponse(c, err)
+	}
+	sub, err := userSubscription(s.DB, tournamentID, userID)
+	if err != nil {
+		return checkInErrorResponse(c, err)
+	}
+
+	res := s.DB.Where("tournament_id = ? AND match_id = ? AND subscription_id = ?", tournamentID, matchID, sub.ID).
+		Delete(&models.CheckIn{})
+	if res.Error != nil {
+		return checkInErrorResponse(c, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return checkInErrorResponse(c, ErrNotCheckedIn)
+	}
+	return c.JSON(fiber.Map{"message": "check-in withdrawn"})
+}
+
+// GetCheckIns shows the check-in window and who has and hasn't checked in (?match_id= for a match)
+func (s *TournamentService) GetCheckIns(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	matchID := c.Query("match_id")
+
+	tournament, opens, closes, err := checkInTarget(s.DB, tournamentID, matchID)
+	if err != nil {
+		return checkInErrorResponse(c, err)
+	}
+
+	var subs []models.TournamentSubscription
+	if err := s.DB.Where("tournament_id = ? AND payment_status IN ? AND revoked_at IS NULL", tournamentID, activeSubscriptionStatuses).
+		Order("joined_at ASC").Find(&subs).Error; err != nil {
+		return checkInErrorResponse(c, err)
+	}
+	var checkIns []models.CheckIn
+	if err := s.DB.Where("tournament_id = ? AND match_id = ?", tournamentID, matchID).
+		Order("checked_in_at ASC").Find(&checkIns).Error; err != nil {
+		return checkInErrorResponse(c, err)
+	}
+	checked := make(map[string]bool, len(checkIns))
+	for _, ci := range checkIns {
+		checked[ci.SubscriptionID] = true
+	}
+
+	var missing, forfeited []fiber.Map
+	for _, sub := range subs {
+		if checked[sub.ID] {
+			continue
+		}
+		entry := fiber.Map{"user_id": entrantID(sub), "user_name": sub.UserName, "subscription_id": sub.ID}
+		if sub.ForfeitedAt != nil {
+			entry["forfeited_at"] = sub.ForfeitedAt
+			forfeited = append(forfeited, entry)
+			continue
+		}
+		missing = append(missing, entry)
+	}
+
+	now := time.Now()
+	return c.JSON(fiber.Map{
+		"tournament_id":         tournamentID,
+		"match_id":              matchID,
+		"opens_at":              opens,
+		"closes_at":             closes,
+		"is_open":               checkInWindowError(now, opens, closes) == nil,
+		"auto_forfeit_no_shows": tournament.AutoForfeitNoShows,
+		"checked_in":            checkIns,
+		"not_checked_in":        missing,
+		"forfeited":             forfeited,
+		"checked_in_count":      len(checkIns),
+		"not_checked_in_count":  len(missing),
+	})
+}
+
+// filterCheckedIn drops players who haven't checked in when the match (or else the tournament)
+// requires check-in. Players are matched by subscription, so team entries work unchanged.
+func (ps *PairingService) filterCheckedIn(tournament models.Tournament, match models.TournamentMatch,
+	players []models.TournamentSubscription) ([]models.TournamentSubscription, error) {
+	matchID := ""
+	switch {
+	case match.CheckInRequired:
+		matchID = match.ID
+	case tournament.CheckInRequired:
+	default:
+		return players, nil
+	}
+
+	var subIDs []string
+	if err := ps.DB.Model(&models.CheckIn{}).
+		Where("tournament_id = ? AND match_id = ?", tournament.ID, matchID).
+		Pluck("subscription_id", &subIDs).Error; err != nil {
+		return nil, err
+	}
+	checked := make(map[string]bool, len(subIDs))
+	for _, id := range subIDs {
+		checked[id] = true
+	}
+	filtered := make([]models.TournamentSubscription, 0, len(players))
+	for _, p := range players {
+		if checked[p.ID] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// forfeitNoShows forfeits entrants who missed a closed check-in window, for tournaments and
+// matches with auto_forfeit_no_shows. Each window is processed once.
+func (s *TournamentService) forfeitNoShows(now time.Time) {
+	var tournaments []models.Tournament
+	if err := s.DB.Where("check_in_required = ? AND auto_forfeit_no_shows = ? AND check_in_processed_at IS NULL AND status IN ?",
+		true, true, []string{models.TournamentStatusPublished, models.TournamentStatusActive}).
+		Find(&tournaments).Error; err != nil {
+		log.Printf("[CheckIn] DB error: %v", err)
+		return
+	}
+	for i := range tournaments {
+		t := &tournaments[i]
+		if _, closes := t.CheckInWindow(t.StartTime); now.Before(closes) {
+			continue
+		}
+		if err := s.forfeitWindow(t, "", now); err != nil {
+			log.Printf("❌ Failed to forfeit no-shows for tournament %s: %v", t.ID, err)
+		}
+	}
+
+	var matches []models.TournamentMatch
+	if err := s.DB.Joins("JOIN tournament_batches ON tournament_batches.id = tournament_matches.batch_id").
+		Joins("JOIN tournaments ON tournaments.id = tournament_batches.tournament_id").
+		Where("tournament_matches.check_in_required = ? AND tournament_matches.check_in_processed_at IS NULL", true).
+		Where("tournament_matches.status IN ?", []string{models.StageStatusPending, models.StageStatusActive}).
+		Where("tournaments.auto_forfeit_no_shows = ? AND tournaments.status IN ? AND tournaments.deleted_at IS NULL",
+			true, []string{models.TournamentStatusPublished, models.TournamentStatusActive}).
+		Find(&matches).Error; err != nil {
+		log.Printf("[CheckIn] DB error: %v", err)
+		return
+	}
+	for _, m := range matches {
+		var batch models.TournamentBatch
+		var t models.Tournament
+		if err := s.DB.First(&batch, "id = ?", m.BatchID).Error; err != nil {
+			continue
+		}
+		if err := s.DB.First(&t, "id = ?", batch.TournamentID).Error; err != nil {
+			continue
+		}
+		start := m.StartDate
+		if start.IsZero() {
+			start = t.StartTime
+		}
+		if _, closes := t.CheckInWindow(start); now.Before(closes) {
+			continue
+		}
+		if err := s.forfeitWindow(&t, m.ID, now); err != nil {
+			log.Printf("❌ Failed to forfeit no-shows for match %s: %v", m.ID, err)
+		}
+	}
+}
+
+// forfeitWindow forfeits every pairable subscription without a check-in for the window and
+// marks the window processed
+func (s *TournamentService) forfeitWindow(t *models.Tournament, matchID string, now time.Time) error {
+	var forfeited []models.TournamentSubscription
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		checkedIn := tx.Model(&models.CheckIn{}).Select("subscription_id").
+			Where("tournament_id = ? AND match_id = ?", t.ID, matchID)
+		if err := tx.Where("tournament_id = ? AND payment_status IN ? AND revoked_at IS NULL AND forfeited_at IS NULL", t.ID, activeSubscriptionStatuses).
+			Where("id NOT IN (?)", checkedIn).
+			Find(&forfeited).Error; err != nil {
+			return err
+		}
+
+		reason := "missed tournament check-in"
+		if matchID != "" {
+			reason = "missed match check-in"
+		}
+		if len(forfeited) > 0 {
+			ids := make([]string, len(forfeited))
+			for i, sub := range forfeited {
+				ids[i] = sub.ID
+			}
+			if err := tx.Model(&models.TournamentSubscription{}).Where("id IN ?", ids).
+				Updates(map[string]interface{}{"forfeited_at": &now, "forfeit_reason": reason}).Error; err != nil {
+				return err
+			}
+		}
+
+		if matchID != "" {
+			return tx.Model(&models.TournamentMatch{}).Where("id = ?", matchID).Update("check_in_processed_at", &now).Error
+		}
+		return tx.Model(&models.Tournament{}).Where("id = ?", t.ID).Update("check_in_processed_at", &now).Error
+	})
+	if err != nil {
+		return err
+	}
+	if len(forfeited) > 0 {
+		log.Printf("🚫 Forfeited %d no-shows in tournament %s (match %q)", len(forfeited), t.ID, matchID)
+		s.releaseSlot(t.ID)
+	}
+	return nil
+}
+
+func checkInErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "tournament or match not found"})
+	case errors.Is(err, ErrNotSubscribed), errors.Is(err, ErrNotCheckedIn):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrCheckInNotRequired):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrCheckInNotOpen), errors.Is(err, ErrCheckInClosed), errors.Is(err, ErrForfeited):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "check-in failed", "details": err.Error()})
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"game-publish-system/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestParseCheckInSettings(t *testing.T) {
+	current := models.Tournament{
+		CheckInRequired:      true,
+		CheckInOpensMinutes:  30,
+		CheckInClosesMinutes: 5,
+		AutoForfeitNoShows:   false,
+	}
+	type settings struct {
+		Required    bool `json:"required"`
+		Opens       int  `json:"opens"`
+		Closes      int  `json:"closes"`
+		AutoForfeit bool `json:"auto_forfeit"`
+	}
+	tests := []struct {
+		name    string
+		form    url.Values
+		want    settings
+		wantErr bool
+	}{
+		{"empty form keeps current", url.Values{}, settings{true, 30, 5, false}, false},
+		{"overrides only what is sent", url.Values{"auto_forfeit_no_shows": {"TRUE"}, "check_in_opens_minutes": {"60"}},
+			settings{true, 60, 5, true}, false},
+		{"turns check-in off", url.Values{"check_in_required": {"false"}}, settings{false, 30, 5, false}, false},
+		{"closes at the start", url.Values{"check_in_closes_minutes": {"0"}}, settings{true, 30, 0, false}, false},
+		{"negative minutes", url.Values{"check_in_opens_minutes": {"-5"}}, settings{}, true},
+		{"non-numeric minutes", url.Values{"check_in_closes_minutes": {"soon"}}, settings{}, true},
+		{"window closes before it opens", url.Values{"check_in_closes_minutes": {"45"}}, settings{}, true},
+		{"empty window", url.Values{"check_in_opens_minutes": {"10"}, "check_in_closes_minutes": {"10"}}, settings{}, true},
+	}
+
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		required, opens, closes, autoForfeit, err := parseCheckInSettings(c, current)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(settings{required, opens, closes, autoForfeit})
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+			if tt.wantErr {
+				if resp.StatusCode != 400 {
+					t.Errorf("status = %d, want 400", resp.StatusCode)
+				}
+				return
+			}
+			var got settings
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("decode (status %d): %v", resp.StatusCode, err)
+			}
+			if got != tt.want {
+				t.Errorf("settings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckInWindowError(t *testing.T) {
+	start := time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC)
+	tournament := models.Tournament{CheckInOpensMinutes: 30, CheckInClosesMinutes: 5}
+	opens, closes := tournament.CheckInWindow(start)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want error
+	}{
+		{"an hour early", start.Add(-time.Hour), ErrCheckInNotOpen},
+		{"just before it opens", opens.Add(-time.Second), ErrCheckInNotOpen},
+		{"as it opens", opens, nil},
+		{"mid window", start.Add(-10 * time.Minute), nil},
+		{"just before it closes", closes.Add(-time.Second), nil},
+		{"as it closes", closes, ErrCheckInClosed},
+		{"after the start", start.Add(time.Minute), ErrCheckInClosed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkInWindowError(tt.now, opens, closes); !errors.Is(err, tt.want) {
+				t.Errorf("checkInWindowError = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// TestForfeitWindow checks no-shows are forfeited once, checked-in and unpaid entries are left
+// alone, and the freed slot goes to the waitlist
+func TestForfeitWindow(t *testing.T) {
+	db := openTestDB(t, &models.Game{}, &models.Tournament{}, &models.TournamentSubscription{},
+		&models.CheckIn{}, &models.WaitlistEntry{})
+	tournament := createTestTournament(t, db, models.Tournament{
+		MaxSubscribers:      3,
+		CheckInRequired:     true,
+		AutoForfeitNoShows:  true,
+		CheckInOpensMinutes: 30,
+		WaitlistHoldMinutes: 15,
+	})
+	t.Cleanup(func() { db.Where("tournament_id = ?", tournament.ID).Delete(&models.CheckIn{}) })
+
+	subs := map[string]*models.TournamentSubscription{}
+	for _, s := range []struct{ name, status string }{
+		{"present", "paid"},
+		{"no-show", "waived"},
+		{"unpaid", "pending"},
+	} {
+		sub := models.TournamentSubscription{
+			ID:             uuid.NewString(),
+			TournamentID:   tournament.ID,
+			ExternalUserID: uuid.NewString(),
+			UserName:       s.name,
+			PaymentStatus:  s.status,
+		}
+		if err := db.Create(&sub).Error; err != nil {
+			t.Fatalf("create %s subscription: %v", s.name, err)
+		}
+		subs[s.name] = &sub
+	}
+	if err := db.Create(&models.CheckIn{
+		ID:             uuid.NewString(),
+		TournamentID:   tournament.ID,
+		SubscriptionID: subs["present"].ID,
+		UserID:         subs["present"].ExternalUserID,
+	}).Error; err != nil {
+		t.Fatalf("create check-in: %v", err)
+	}
+	waiting := models.WaitlistEntry{
+		ID:             uuid.NewString(),
+		TournamentID:   tournament.ID,
+		ExternalUserID: uuid.NewString(),
+		Status:         models.WaitlistStatusWaiting,
+		QueuedAt:       time.Now(),
+	}
+	if err := db.Create(&waiting).Error; err != nil {
+		t.Fatalf("create waitlist entry: %v", err)
+	}
+
+	service := &TournamentService{DB: db}
+	now := time.Now()
+	if err := service.forfeitWindow(&tournament, "", now); err != nil {
+		t.Fatalf("forfeitWindow: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		wantForfeit bool
+	}{
+		{"present", false},
+		{"no-show", true},
+		{"unpaid", false},
+	}
+	for _, tt := range tests {
+		var sub models.TournamentSubscription
+		if err := db.First(&sub, "id = ?", subs[tt.name].ID).Error; err != nil {
+			t.Fatalf("load %s: %v", tt.name, err)
+		}
+		if got := sub.ForfeitedAt != nil; got != tt.wantForfeit {
+			t.Errorf("%s forfeited = %v, want %v", tt.name, got, tt.wantForfeit)
+		}
+		if tt.wantForfeit && sub.ForfeitReason != "missed tournament check-in" {
+			t.Errorf("%s forfeit reason = %q", tt.name, sub.ForfeitReason)
+		}
+	}
+
+	var reloaded models.Tournament
+	if err := db.First(&reloaded, "id = ?", tournament.ID).Error; err != nil {
+		t.Fatalf("load tournament: %v", err)
+	}
+	if reloaded.CheckInProcessedAt == nil {
+		t.Error("check_in_processed_at not set")
+	}
+	if err := db.First(&waiting, "id = ?", waiting.ID).Error; err != nil {
+		t.Fatalf("load waitlist entry: %v", err)
+	}
+	if waiting.Status != models.WaitlistStatusOffered || waiting.HoldExpiresAt == nil {
+		t.Errorf("waitlist entry = %s (hold %v), want offered the forfeited slot", waiting.Status, waiting.HoldExpiresAt)
+	}
+}
@@ -0,0 +1,220 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInvalidAmount       = errors.New("invalid amount")
+)
+
+// parseCurrency normalizes a currency code; empty means models.DefaultCurrency
+func parseCurrency(code string) (string, error) {
+	if strings.TrimSpace(code) == "" {
+		return models.DefaultCurrency, nil
+	}
+	currency, ok := models.LookupCurrency(code)
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedCurrency, code)
+	}
+	return currency.Code, nil
+}
+
+// currencyDecimals is the precision amounts in currency are kept at
+func currencyDecimals(currency string) int {
+	return models.CurrencyDecimals(currency)
+}
+
+// minorUnits converts an amount in currency to the integer minor units amounts are stored in
+func minorUnits(amount float64, currency string) int64 {
+	return models.MinorUnits(amount, currency)
+}
+
+// majorUnits is the inverse of minorUnits, for API responses
+func majorUnits(amount int64, currency string) float64 {
+	return models.MajorUnits(amount, currency)
+}
+
+// formatAmount renders minor units of currency at its precision, e.g. 1250 USD as "12.50 USD"
+func formatAmount(amount int64, currency string) string {
+	return strconv.FormatFloat(majorUnits(amount, currency), 'f', currencyDecimals(currency), 64) + " " + currency
+}
+
+// majorTotals converts per-currency totals in minor units to currency units, for API responses
+func majorTotals(totals map[string]int64) map[string]float64 {
+	out := make(map[string]float64, len(totals))
+	for currency, total := range totals {
+		out[currency] = majorUnits(total, currency)
+	}
+	return out
+}
+
+// parseMinorUnits reads a non-negative decimal string such as "12.5" as minor units of currency
+// without going through float64. More fractional digits than the currency has is an error.
+func parseMinorUnits(s, currency string) (int64, error) {
+	decimals := currencyDecimals(currency)
+	s = strings.TrimSpace(s)
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	if len(frac) > decimals {
+		return 0, fmt.Errorf("%w: %s allows at most %d decimal places", ErrInvalidAmount, currency, decimals)
+	}
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+		}
+	}
+	n, err := strconv.ParseInt(whole+frac+strings.Repeat("0", decimals-len(frac)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+	return n, nil
+}
+
+// checkAmountPrecision rejects amounts finer than currency's minor unit (e.g. 1.005 USD)
+func checkAmountPrecision(amount float64, currency string) error {
+	decimals := currencyDecimals(currency)
+	if math.Abs(amount*math.Pow10(decimals)-float64(minorUnits(amount, currency))) > 1e-6 {
+		return fmt.Errorf("%w: %s allows at most %d decimal places", ErrInvalidAmount, currency, decimals)
+	}
+	return nil
+}
+
+// displayRate finds the rate converting from into to, using the inverse pair when only that one
+// is configured
+func displayRate(db *gorm.DB, from, to string) (float64, bool, error) {
+	if from == to {
+		return 1, true, nil
+	}
+	var rates []models.CurrencyRate
+	if err := db.Where("(base = ? AND quote = ?) OR (base = ? AND quote = ?)", from, to, to, from).
+		Find(&rates).Error; err != nil {
+		return 0, false, err
+	}
+	inverse := 0.0
+	for _, r := range rates {
+		if r.Rate <= 0 {
+			continue
+		}
+		if r.Base == from {
+			return r.Rate, true, nil
+		}
+		inverse = 1 / r.Rate
+	}
+	return inverse, inverse > 0, nil
+}
+
+// convertForDisplay converts amount in from to the to currency, rounded to to's precision; nil
+// means no rate is configured for the pair
+func convertForDisplay(db *gorm.DB, amount float64, from, to string) (*models.DisplayAmount, error) {
+	rate, ok, err := displayRate(db, from, to)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &models.DisplayAmount{
+		Amount:   majorUnits(minorUnits(amount*rate, to), to),
+		Currency: to,
+		Rate:     rate,
+	}, nil
+}
+
+// displayCurrency reads ?display_currency=; empty means no conversion was asked for
+func displayCurrency(c *fiber.Ctx) (string, error) {
+	if c.Query("display_currency") == "" {
+		return "", nil
+	}
+	return parseCurrency(c.Query("display_currency"))
+}
+
+// GetCurrencies lists supported currencies and the configured display rates
+func (s *TournamentService) GetCurrencies(c *fiber.Ctx) error {
+	currencies := make([]models.Currency, 0, len(models.Currencies))
+	for _, currency := range models.Currencies {
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool { return currencies[i].Code < currencies[j].Code })
+
+	var rates []models.CurrencyRate
+	if err := s.DB.Order("base, quote").Find(&rates).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch currency rates", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"default": models.DefaultCurrency, "currencies": currencies, "rates": rates})
+}
+
+// SetCurrencyRate creates or replaces the display rate for a currency pair (admin)
+func (s *TournamentService) SetCurrencyRate(c *fiber.Ctx) error {
+	var req struct {
+		Base   string  `json:"base"`
+		Quote  string  `json:"quote"`
+		Rate   float64 `json:"rate"`
+		Source string  `json:"source"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+	base, err := parseCurrency(req.Base)
+	if err != nil || req.Base == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "base must be a supported currency"})
+	}
+	quote, err := parseCurrency(req.Quote)
+	if err != nil || req.Quote == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "quote must be a supported currency"})
+	}
+	if base == quote {
+		return c.Status(400).JSON(fiber.Map{"error": "base and quote must differ"})
+	}
+	if req.Rate <= 0 || math.IsInf(req.Rate, 0) || math.IsNaN(req.Rate) {
+		return c.Status(400).JSON(fiber.Map{"error": "rate must be a positive number"})
+	}
+	if req.Source == "" {
+		req.Source = "manual"
+	}
+
+	rate := models.CurrencyRate{
+		ID:        uuid.NewString(),
+		Base:      base,
+		Quote:     quote,
+		Rate:      req.Rate,
+		Source:    req.Source,
+		UpdatedBy: c.Locals("user_id").(string),
+		UpdatedAt: time.Now(),
+	}
+	if err := s.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source", "updated_by", "updated_at"}),
+	}).Create(&rate).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to save currency rate", "details": err.Error()})
+	}
+	s.DB.Where("base = ? AND quote = ?", base, quote).First(&rate)
+	return c.JSON(rate)
+}
+
+// DeleteCurrencyRate removes a display rate (admin)
+func (s *TournamentService) DeleteCurrencyRate(c *fiber.Ctx) error {
+	res := s.DB.Where("id = ?", c.Params("rate_id")).Delete(&models.CurrencyRate{})
+	if res.Error != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "DB error", "details": res.Error.Error()})
+	}
+	if res.RowsAffected == 0 {
+		return c.Status(404).JSON(fiber.Map{"error": "currency rate not found"})
+	}
+	return c.JSON(fiber.Map{"message": "currency rate deleted"})
+}
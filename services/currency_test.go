@@ -0,0 +1,172 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"game-publish-system/models"
+)
+
+func TestParseMinorUnits(t *testing.T) {
+	tests := []struct {
+		in, currency string
+		want         int64
+		wantErr      bool
+	}{
+		{"12.5", "USD", 1250, false},
+		{"12.50", "USD", 1250, false},
+		{"12", "USD", 1200, false},
+		{".5", "USD", 50, false},
+		{"5.", "USD", 500, false},
+		{" 3.25 ", "EUR", 325, false},
+		{"0", "USD", 0, false},
+		{"0.1", "USDC", 100000, false},
+		{"1.000001", "USDC", 1000001, false},
+		{"0.000000001", "SOL", 1, false},
+		{"2", "", 200, false},
+		{"12.505", "USD", 0, true},
+		{"1.0000001", "USDC", 0, true},
+		{"", "USD", 0, true},
+		{".", "USD", 0, true},
+		{"-1", "USD", 0, true},
+		{"+1", "USD", 0, true},
+		{"abc", "USD", 0, true},
+		{"1e3", "USD", 0, true},
+		{"1.2.3", "USD", 0, true},
+		{"99999999999999999999", "USD", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in+" "+tt.currency, func(t *testing.T) {
+			got, err := parseMinorUnits(tt.in, tt.currency)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidAmount) {
+					t.Errorf("err = %v, want ErrInvalidAmount", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("parseMinorUnits = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMinorUnitsRounding(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     int64
+	}{
+		{0.1 + 0.2, "USD", 30}, // 0.30000000000000004
+		{19.99, "USD", 1999},   // 1998.9999999999998 before rounding
+		{1.005, "USD", 100},    // 1.00499999999999989...
+		{2.675, "EUR", 268},
+		{0.1, "USDC", 100000},
+		{1.5, "SOL", 1500000000},
+		{7, "unknown", 700},
+	}
+	for _, tt := range tests {
+		got := minorUnits(tt.amount, tt.currency)
+		if got != tt.want {
+			t.Errorf("minorUnits(%v, %s) = %d, want %d", tt.amount, tt.currency, got, tt.want)
+		}
+		if back := minorUnits(majorUnits(got, tt.currency), tt.currency); back != got {
+			t.Errorf("minorUnits(majorUnits(%d, %s)) = %d, want a round trip", got, tt.currency, back)
+		}
+	}
+}
+
+func TestCheckAmountPrecision(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		wantErr  bool
+	}{
+		{12.5, "USD", false},
+		{19.99, "USD", false},
+		{0.1 + 0.2, "USD", false},
+		{1.005, "USD", true},
+		{0.000001, "USDC", false},
+		{0.0000001, "USDC", true},
+		{0.000000001, "SOL", false},
+	}
+	for _, tt := range tests {
+		err := checkAmountPrecision(tt.amount, tt.currency)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("checkAmountPrecision(%v, %s) = %v, wantErr %v", tt.amount, tt.currency, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency string
+		want     string
+	}{
+		{1250, "USD", "12.50 USD"},
+		{5, "EUR", "0.05 EUR"},
+		{1500000, "USDC", "1.500000 USDC"},
+		{1, "SOL", "0.000000001 SOL"},
+		{0, "GBP", "0.00 GBP"},
+	}
+	for _, tt := range tests {
+		if got := formatAmount(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("formatAmount(%d, %s) = %q, want %q", tt.amount, tt.currency, got, tt.want)
+		}
+	}
+}
+
+func TestRescaleMinorUnits(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		from, to int
+		want     int64
+	}{
+		{1500000, 6, 6, 1500000},
+		{1500000, 6, 9, 1500000000},
+		{1500000000, 9, 6, 1500000},
+		{1500000999, 9, 6, 1500000}, // truncates below the target precision
+		{125, 2, 6, 1250000},
+	}
+	for _, tt := range tests {
+		if got := rescaleMinorUnits(tt.amount, tt.from, tt.to); got != tt.want {
+			t.Errorf("rescaleMinorUnits(%d, %d, %d) = %d, want %d", tt.amount, tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+// TestMinorUnitJSON checks stored minor units come back out under the old decimal fields
+func TestMinorUnitJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		field string
+		want  float64
+	}{
+		{"tournament entry fee", models.Tournament{EntryFee: 1250, Currency: "USD"}, "entry_fee", 12.5},
+		{"token entry fee", models.Tournament{EntryFee: 1500000, Currency: "USDC"}, "entry_fee", 1.5},
+		{"subscription payment", models.TournamentSubscription{PaymentAmount: 250000000, Currency: "SOL"}, "payment_amount", 0.25},
+		{"waiver amount", models.UserWaiver{Amount: 1000, UsedAmount: 250, Currency: "EUR"}, "used_amount", 2.5},
+		{"refund", models.SubscriptionRefund{Amount: 999, Currency: "USD"}, "amount", 9.99},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.value)
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			var out map[string]interface{}
+			if err := json.Unmarshal(data, &out); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got, _ := out[tt.field].(float64); got != tt.want {
+				t.Errorf("%s = %v, want %v in %s", tt.field, out[tt.field], tt.want, data)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package services
 
 import (
 	"archive/zip"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -988,3 +990,52 @@ func (s *GameService) GetFeaturedGamesMinimal(c *fiber.Ctx) error {
 
 	return c.JSON(minimalGames)
 }
+
+// ===== Score Webhook Secret =====
+
+// RotateWebhookSecret generates a new HMAC secret for the game's score webhook.
+// The secret is only returned once; store it on the game server. The replaced secret keeps
+// verifying for scoreWebhookSecretGrace so the game server can switch over.
+func (s *GameService) RotateWebhookSecret(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	var game models.Game
+	if err := s.DB.First(&game, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "game not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "DB error"})
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to generate secret"})
+	}
+	secret := hex.EncodeToString(buf)
+	now := time.Now()
+
+	// Only the secret being replaced gets a grace window; one rotated out earlier ends now
+	var previousExpiresAt *time.Time
+	if game.WebhookSecret != "" {
+		expires := now.Add(scoreWebhookSecretGrace())
+		previousExpiresAt = &expires
+	}
+	if err := s.DB.Model(&game).Updates(map[string]interface{}{
+		"webhook_secret":                     secret,
+		"webhook_secret_rotated_at":          now,
+		"previous_webhook_secret":            game.WebhookSecret,
+		"previous_webhook_secret_expires_at": previousExpiresAt,
+	}).Error; err != nil {
+		log.Printf("DB Error rotating webhook secret for game %s: %v", id, err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to rotate webhook secret"})
+	}
+
+	log.Printf("🔏 Rotated score webhook secret for game %s (by %v)", game.Name, c.Locals("user_id"))
+	return c.JSON(fiber.Map{
+		"game_id":                    game.ID,
+		"webhook_secret":             secret,
+		"rotated_at":                 now,
+		"previous_secret_expires_at": previousExpiresAt,
+		"webhook_url":                "/webhooks/games/" + game.ID + "/scores",
+	})
+}
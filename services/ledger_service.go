@@ -0,0 +1,420 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Platform ledger accounts. Every account holds one currency: ledgerAccountCode suffixes the
+// code for anything but models.DefaultCurrency.
+const (
+	ledgerCash            = "platform:cash"             // money held from card and wallet payments
+	ledgerRevenue         = "platform:revenue"          // pool remainders the platform keeps
+	ledgerWaiverLiability = "platform:waiver_liability" // unspent waiver balance owed to users
+	ledgerPromotions      = "platform:promotions"       // cost of waivers handed out
+	ledgerPrizeExpense    = "platform:prize_expense"    // sponsored prizes, not paid from a pool
+)
+
+var ErrLedgerUnbalanced = errors.New("ledger transaction does not balance")
+
+// userLedgerAccount is what the platform owes a user (prizes, refunds not yet paid out)
+func userLedgerAccount(userID string) string { return "user:" + userID }
+
+// poolLedgerAccount holds a tournament's entry fees until prizes, refunds or close
+func poolLedgerAccount(tournamentID string) string { return "tournament:" + tournamentID + ":pool" }
+
+// ledgerAccountCode is the code of account's balance in currency
+func ledgerAccountCode(account, currency string) string {
+	if currency == "" || currency == models.DefaultCurrency {
+		return account
+	}
+	return account + ":" + currency
+}
+
+type ledgerLeg struct {
+	Account string
+	Amount  int64 // debit > 0, credit < 0
+}
+
+func debit(account string, amount int64) ledgerLeg  { return ledgerLeg{account, amount} }
+func credit(account string, amount int64) ledgerLeg { return ledgerLeg{account, -amount} }
+
+// ledgerEntry is one balanced transaction to post; leg amounts are minor units of Currency
+type ledgerEntry struct {
+	Kind           string
+	Currency       string // empty means models.DefaultCurrency
+	IdempotencyKey string
+	TournamentID   string
+	SubscriptionID string
+	Description    string
+	Actor          string
+	Legs           []ledgerLeg
+}
+
+// ledgerAccountFor derives an account's type and owner from its code
+func ledgerAccountFor(code, currency string) models.LedgerAccount {
+	account := models.LedgerAccount{
+		ID:       uuid.NewString(),
+		Code:     ledgerAccountCode(code, currency),
+		Name:     ledgerAccountCode(code, currency),
+		Currency: currency,
+	}
+	parts := strings.Split(code, ":")
+	switch parts[0] {
+	case "user":
+		account.Type, account.OwnerType, account.OwnerID = models.LedgerAccountLiability, "user", parts[1]
+	case "tournament":
+		account.Type, account.OwnerType, account.OwnerID = models.LedgerAccountLiability, "tournament", parts[1]
+	default:
+		account.OwnerType = "platform"
+		switch code {
+		case ledgerCash:
+			account.Type = models.LedgerAccountAsset
+		case ledgerRevenue:
+			account.Type = models.LedgerAccountRevenue
+		case ledgerPromotions, ledgerPrizeExpense:
+			account.Type = models.LedgerAccountExpense
+		default:
+			account.Type = models.LedgerAccountLiability
+		}
+	}
+	return account
+}
+
+// ledgerAccountID returns the account for code in currency, opening it on first use
+func ledgerAccountID(tx *gorm.DB, code, currency string) (string, error) {
+	account := ledgerAccountFor(code, currency)
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).
+		Create(&account).Error; err != nil {
+		return "", err
+	}
+	if err := tx.Where("code = ?", account.Code).First(&account).Error; err != nil {
+		return "", err
+	}
+	return account.ID, nil
+}
+
+// postLedger appends a balanced transaction. Zero legs are dropped; a transaction whose
+// IdempotencyKey was already posted is skipped.
+func postLedger(tx *gorm.DB, entry ledgerEntry) error {
+	var legs []ledgerLeg
+	var sum int64
+	for _, leg := range entry.Legs {
+		if leg.Amount != 0 {
+			legs = append(legs, leg)
+			sum += leg.Amount
+		}
+	}
+	if len(legs) == 0 {
+		return nil
+	}
+	if sum != 0 {
+		return fmt.Errorf("%w: %s legs sum to %d", ErrLedgerUnbalanced, entry.Kind, sum)
+	}
+	if entry.Currency == "" {
+		entry.Currency = models.DefaultCurrency
+	}
+
+	now := time.Now()
+	txn := models.LedgerTransaction{
+		ID:             uuid.NewString(),
+		Kind:           entry.Kind,
+		Currency:       entry.Currency,
+		IdempotencyKey: entry.IdempotencyKey,
+		TournamentID:   entry.TournamentID,
+		SubscriptionID: entry.SubscriptionID,
+		Description:    entry.Description,
+		Actor:          entry.Actor,
+		PostedAt:       now,
+	}
+	if entry.IdempotencyKey != "" {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&txn)
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+	} else if err := tx.Create(&txn).Error; err != nil {
+		return err
+	}
+
+	postings := make([]models.LedgerPosting, len(legs))
+	for i, leg := range legs {
+		accountID, err := ledgerAccountID(tx, leg.Account, entry.Currency)
+		if err != nil {
+			return err
+		}
+		postings[i] = models.LedgerPosting{
+			ID:            uuid.NewString(),
+			TransactionID: txn.ID,
+			AccountID:     accountID,
+			Amount:        leg.Amount,
+			PostedAt:      now,
+		}
+	}
+	return tx.Create(&postings).Error
+}
+
+// ledgerBalance is an account's debit-positive balance over postings before until (zero = all)
+func ledgerBalance(db *gorm.DB, accountID string, until time.Time) (int64, error) {
+	var balance int64
+	query := db.Model(&models.LedgerPosting{}).Select("COALESCE(SUM(amount), 0)").Where("account_id = ?", accountID)
+	if !until.IsZero() {
+		query = query.Where("posted_at < ?", until)
+	}
+	err := query.Scan(&balance).Error
+	return balance, err
+}
+
+// normalBalance flips credit-normal accounts so a positive balance reads as "held" or "owed"
+func normalBalance(accountType string, balance int64) int64 {
+	if accountType == models.LedgerAccountLiability || accountType == models.LedgerAccountRevenue {
+		return -balance
+	}
+	return balance
+}
+
+func ledgerAccountJSON(a models.LedgerAccount, balance int64) fiber.Map {
+	normal := normalBalance(a.Type, balance)
+	return fiber.Map{
+		"account":       a,
+		"balance_minor": normal,
+		"balance":       majorUnits(normal, a.Currency),
+	}
+}
+
+// GetLedgerAccounts lists accounts with their balances, optionally filtered by owner_type
+func (s *TournamentService) GetLedgerAccounts(c *fiber.Ctx) error {
+	query := s.DB.Order("code")
+	if ownerType := c.Query("owner_type"); ownerType != "" {
+		query = query.Where("owner_type = ?", ownerType)
+	}
+	var accounts []models.LedgerAccount
+	if err := query.Find(&accounts).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch ledger accounts", "details": err.Error()})
+	}
+
+	var sums []struct {
+		AccountID string
+		Currency  string
+		Balance   int64
+	}
+	if err := s.DB.Table("ledger_postings").
+		Select("ledger_postings.account_id, ledger_accounts.currency, SUM(ledger_postings.amount) AS balance").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = ledger_postings.account_id").
+		Group("ledger_postings.account_id, ledger_accounts.currency").
+		Scan(&sums).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to sum ledger balances", "details": err.Error()})
+	}
+	balances := make(map[string]int64, len(sums))
+	trial := map[string]int64{}
+	for _, b := range sums {
+		balances[b.AccountID] = b.Balance
+		trial[b.Currency] += b.Balance
+	}
+
+	list := make([]fiber.Map, len(accounts))
+	for i, a := range accounts {
+		list[i] = ledgerAccountJSON(a, balances[a.ID])
+	}
+	// Every transaction balances in one currency, so each currency must sum to zero
+	return c.JSON(fiber.Map{"accounts": list, "total": len(list), "trial_balance_minor": trial})
+}
+
+// ledgerStatement writes an account's postings between from and to with a running balance
+func (s *TournamentService) ledgerStatement(c *fiber.Ctx, account models.LedgerAccount) error {
+	var from, to time.Time
+	var err error
+	if v := c.Query("from"); v != "" {
+		if from, err = time.Parse("2006-01-02", v); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "from must be YYYY-MM-DD"})
+		}
+	}
+	if v := c.Query("to"); v != "" {
+		if to, err = time.Parse("2006-01-02", v); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "to must be YYYY-MM-DD"})
+		}
+		to = to.Add(24 * time.Hour)
+	}
+
+	opening := int64(0)
+	if !from.IsZero() {
+		if opening, err = ledgerBalance(s.DB, account.ID, from); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "DB error", "details": err.Error()})
+		}
+	}
+
+	var lines []struct {
+		models.LedgerPosting
+		Kind         string `json:"kind"`
+		Description  string `json:"description"`
+		TournamentID string `json:"tournament_id"`
+	}
+	query := s.DB.Table("ledger_postings").
+		Select("ledger_postings.*, ledger_transactions.kind, ledger_transactions.description, ledger_transactions.tournament_id").
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_postings.transaction_id").
+		Where("ledger_postings.account_id = ?", account.ID)
+	if !from.IsZero() {
+		query = query.Where("ledger_postings.posted_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("ledger_postings.posted_at < ?", to)
+	}
+	if err := query.Order("ledger_postings.posted_at, ledger_postings.id").Scan(&lines).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch statement", "details": err.Error()})
+	}
+
+	running := opening
+	entries := make([]fiber.Map, len(lines))
+	for i, l := range lines {
+		running += l.Amount
+		entries[i] = fiber.Map{
+			"posting_id":     l.ID,
+			"transaction_id": l.TransactionID,
+			"kind":           l.Kind,
+			"description":    l.Description,
+			"tournament_id":  l.TournamentID,
+			"posted_at":      l.PostedAt,
+			"amount_minor":   normalBalance(account.Type, l.Amount),
+			"balance_minor":  normalBalance(account.Type, running),
+		}
+	}
+	return c.JSON(fiber.Map{
+		"account":               account,
+		"opening_balance_minor": normalBalance(account.Type, opening),
+		"closing_balance_minor": normalBalance(account.Type, running),
+		"closing_balance":       majorUnits(normalBalance(account.Type, running), account.Currency),
+		"entries":               entries,
+	})
+}
+
+// GetLedgerStatement returns one account's statement (?from=&to= as YYYY-MM-DD)
+func (s *TournamentService) GetLedgerStatement(c *fiber.Ctx) error {
+	var account models.LedgerAccount
+	if err := s.DB.First(&account, "id = ?", c.Params("account_id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "ledger account not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	return s.ledgerStatement(c, account)
+}
+
+// GetMyLedger returns the caller's account statement in ?currency= (default USD): prizes and
+// refunds owed to them
+func (s *TournamentService) GetMyLedger(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	currency, err := parseCurrency(c.Query("currency"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	var account models.LedgerAccount
+	if err := s.DB.Where("code = ?", ledgerAccountCode(userLedgerAccount(userID), currency)).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			account = ledgerAccountFor(userLedgerAccount(userID), currency)
+			return c.JSON(fiber.Map{"account": account, "closing_balance_minor": 0, "closing_balance": 0, "entries": []fiber.Map{}})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	return s.ledgerStatement(c, account)
+}
+
+// GetTournamentLedger answers "what did this tournament take in and pay out": its pool account
+// broken down by transaction kind
+func (s *TournamentService) GetTournamentLedger(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	var tournament models.Tournament
+	if err := s.DB.Select("id", "currency").First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+
+	var account models.LedgerAccount
+	if err := s.DB.Where("code = ?", ledgerAccountCode(poolLedgerAccount(tournamentID), tournament.Currency)).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(fiber.Map{"tournament_id": tournamentID, "currency": tournament.Currency, "taken_in_minor": 0, "paid_out_minor": 0, "balance_minor": 0, "by_kind": fiber.Map{}})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+
+	var rows []struct {
+		Kind    string
+		Credits int64
+		Debits  int64
+	}
+	if err := s.DB.Table("ledger_postings").
+		Select(`ledger_transactions.kind,
+			COALESCE(SUM(CASE WHEN ledger_postings.amount < 0 THEN -ledger_postings.amount ELSE 0 END), 0) AS credits,
+			COALESCE(SUM(CASE WHEN ledger_postings.amount > 0 THEN ledger_postings.amount ELSE 0 END), 0) AS debits`).
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_postings.transaction_id").
+		Where("ledger_postings.account_id = ?", account.ID).
+		Group("ledger_transactions.kind").
+		Scan(&rows).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to summarize tournament ledger", "details": err.Error()})
+	}
+
+	byKind := fiber.Map{}
+	var takenIn, paidOut int64
+	for _, r := range rows {
+		byKind[r.Kind] = fiber.Map{"in_minor": r.Credits, "out_minor": r.Debits}
+		takenIn += r.Credits
+		paidOut += r.Debits
+	}
+	return c.JSON(fiber.Map{
+		"tournament_id":  tournamentID,
+		"currency":       account.Currency,
+		"account":        account,
+		"taken_in_minor": takenIn,
+		"paid_out_minor": paidOut,
+		"balance_minor":  takenIn - paidOut,
+		"taken_in":       majorUnits(takenIn, account.Currency),
+		"paid_out":       majorUnits(paidOut, account.Currency),
+		"balance":        majorUnits(takenIn-paidOut, account.Currency),
+		"by_kind":        byKind,
+	})
+}
+
+// closeTournamentPool moves whatever is left in a finalized tournament's pool accounts (one per
+// currency) to platform revenue
+func (s *TournamentService) closeTournamentPool(tournamentID string) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var accounts []models.LedgerAccount
+		if err := tx.Where("owner_type = ? AND owner_id = ?", "tournament", tournamentID).Find(&accounts).Error; err != nil {
+			return err
+		}
+		for _, account := range accounts {
+			balance, err := ledgerBalance(tx, account.ID, time.Time{})
+			if err != nil {
+				return err
+			}
+			remainder := normalBalance(account.Type, balance)
+			if remainder <= 0 {
+				continue
+			}
+			if err := postLedger(tx, ledgerEntry{
+				Kind:           models.LedgerKindPoolClose,
+				Currency:       account.Currency,
+				IdempotencyKey: ledgerAccountCode("pool_close:"+tournamentID, account.Currency),
+				TournamentID:   tournamentID,
+				Description:    "unpaid pool remainder taken as revenue",
+				Actor:          "system",
+				Legs: []ledgerLeg{
+					debit(poolLedgerAccount(tournamentID), remainder),
+					credit(ledgerRevenue, remainder),
+				},
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
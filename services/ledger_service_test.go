@@ -0,0 +1,162 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"game-publish-system/models"
+
+	"github.com/google/uuid"
+)
+
+func TestLedgerAccountFor(t *testing.T) {
+	tests := []struct {
+		code, currency string
+		wantCode       string
+		wantType       string
+		wantOwner      string
+		wantOwnerID    string
+	}{
+		{"user:u1", "USD", "user:u1", models.LedgerAccountLiability, "user", "u1"},
+		{"user:u1", "", "user:u1", models.LedgerAccountLiability, "user", "u1"},
+		{"tournament:t1:pool", "USDC", "tournament:t1:pool:USDC", models.LedgerAccountLiability, "tournament", "t1"},
+		{ledgerCash, "SOL", ledgerCash + ":SOL", models.LedgerAccountAsset, "platform", ""},
+		{ledgerRevenue, "USD", ledgerRevenue, models.LedgerAccountRevenue, "platform", ""},
+		{ledgerPromotions, "USD", ledgerPromotions, models.LedgerAccountExpense, "platform", ""},
+		{ledgerPrizeExpense, "USD", ledgerPrizeExpense, models.LedgerAccountExpense, "platform", ""},
+		{ledgerWaiverLiability, "EUR", ledgerWaiverLiability + ":EUR", models.LedgerAccountLiability, "platform", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.wantCode, func(t *testing.T) {
+			got := ledgerAccountFor(tt.code, tt.currency)
+			if got.Code != tt.wantCode {
+				t.Errorf("code = %q, want %q", got.Code, tt.wantCode)
+			}
+			if got.Type != tt.wantType {
+				t.Errorf("type = %q, want %q", got.Type, tt.wantType)
+			}
+			if got.OwnerType != tt.wantOwner || got.OwnerID != tt.wantOwnerID {
+				t.Errorf("owner = %s/%s, want %s/%s", got.OwnerType, got.OwnerID, tt.wantOwner, tt.wantOwnerID)
+			}
+		})
+	}
+}
+
+func TestNormalBalance(t *testing.T) {
+	tests := []struct {
+		accountType string
+		balance     int64
+		want        int64
+	}{
+		{models.LedgerAccountAsset, 500, 500},
+		{models.LedgerAccountExpense, 500, 500},
+		{models.LedgerAccountLiability, -500, 500},
+		{models.LedgerAccountRevenue, -500, 500},
+		{models.LedgerAccountLiability, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := normalBalance(tt.accountType, tt.balance); got != tt.want {
+			t.Errorf("normalBalance(%s, %d) = %d, want %d", tt.accountType, tt.balance, got, tt.want)
+		}
+	}
+}
+
+// TestPostLedgerBalance covers the checks postLedger makes before it writes anything
+func TestPostLedgerBalance(t *testing.T) {
+	tests := []struct {
+		name    string
+		legs    []ledgerLeg
+		wantErr error
+	}{
+		{"no legs", nil, nil},
+		{"only zero legs", []ledgerLeg{debit(ledgerCash, 0), credit("user:u1", 0)}, nil},
+		{"debit without credit", []ledgerLeg{debit(ledgerCash, 1000)}, ErrLedgerUnbalanced},
+		{"legs off by one", []ledgerLeg{debit(ledgerCash, 1000), credit("user:u1", 999)}, ErrLedgerUnbalanced},
+		{"split credit short", []ledgerLeg{
+			debit(ledgerCash, 1000), credit("user:u1", 600), credit(ledgerRevenue, 300),
+		}, ErrLedgerUnbalanced},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// nil DB: none of these cases may reach it
+			err := postLedger(nil, ledgerEntry{Kind: models.LedgerKindEntryFee, Legs: tt.legs})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPostLedgerIdempotency(t *testing.T) {
+	db := openTestDB(t, &models.LedgerAccount{}, &models.LedgerTransaction{}, &models.LedgerPosting{})
+
+	userID := uuid.NewString()
+	pool := poolLedgerAccount(uuid.NewString())
+	tests := []struct {
+		name      string
+		key       string
+		posts     int
+		wantTxns  int64
+		wantPosts int64
+	}{
+		{"same key posts once", "entry_fee:" + uuid.NewString(), 3, 1, 2},
+		{"no key posts every time", "", 2, 2, 4},
+	}
+	var wantPool int64
+	for _, tt := range tests {
+		wantPool += 1500 * tt.wantTxns
+		t.Run(tt.name, func(t *testing.T) {
+			description := "test " + uuid.NewString()
+			for i := 0; i < tt.posts; i++ {
+				if err := postLedger(db, ledgerEntry{
+					Kind:           models.LedgerKindEntryFee,
+					Currency:       "USDC",
+					IdempotencyKey: tt.key,
+					Description:    description,
+					Actor:          userID,
+					Legs:           []ledgerLeg{debit(ledgerCash, 1500), credit(pool, 1500)},
+				}); err != nil {
+					t.Fatalf("post %d: %v", i, err)
+				}
+			}
+
+			var txnIDs []string
+			if err := db.Model(&models.LedgerTransaction{}).Where("description = ?", description).
+				Pluck("id", &txnIDs).Error; err != nil {
+				t.Fatalf("load transactions: %v", err)
+			}
+			if int64(len(txnIDs)) != tt.wantTxns {
+				t.Errorf("transactions = %d, want %d", len(txnIDs), tt.wantTxns)
+			}
+			var postings int64
+			if err := db.Model(&models.LedgerPosting{}).Where("transaction_id IN ?", txnIDs).
+				Count(&postings).Error; err != nil {
+				t.Fatalf("count postings: %v", err)
+			}
+			if postings != tt.wantPosts {
+				t.Errorf("postings = %d, want %d", postings, tt.wantPosts)
+			}
+			var sum int64
+			if err := db.Model(&models.LedgerPosting{}).Select("COALESCE(SUM(amount), 0)").
+				Where("transaction_id IN ?", txnIDs).Scan(&sum).Error; err != nil {
+				t.Fatalf("sum postings: %v", err)
+			}
+			if sum != 0 {
+				t.Errorf("postings sum to %d, want 0", sum)
+			}
+		})
+	}
+
+	var account models.LedgerAccount
+	if err := db.Where("code = ?", ledgerAccountCode(pool, "USDC")).First(&account).Error; err != nil {
+		t.Fatalf("load pool account: %v", err)
+	}
+	balance, err := ledgerBalance(db, account.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("pool balance: %v", err)
+	}
+	if got := normalBalance(account.Type, balance); got != wantPool {
+		t.Errorf("pool balance = %d, want %d", got, wantPool)
+	}
+}
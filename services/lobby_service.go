@@ -0,0 +1,462 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MatchTypeFreeForAll splits the field into N-player lobbies (battle royale style)
+const MatchTypeFreeForAll = "FREE_FOR_ALL_LOBBY"
+
+const (
+	defaultLobbySize    = 8
+	defaultLobbyAdvance = 1
+)
+
+var (
+	ErrInvalidLobbySettings = errors.New("advance_count must be smaller than the lobby size")
+	ErrLobbyNotFound        = errors.New("lobby not found")
+	ErrLobbyHasResults      = errors.New("lobbies already have recorded placements")
+	ErrInvalidPlacements    = errors.New("placements must cover every lobby player once, numbered 1..n")
+	ErrLobbyLocked          = errors.New("the next match has already been paired from this lobby")
+)
+
+// LobbySeat is one player placed in a free-for-all lobby
+type LobbySeat struct {
+	UserID      string  `json:"user_id"`
+	UserName    string  `json:"user_name"`
+	Seed        int     `json:"seed"`
+	SkillRating float64 `json:"skill_rating"`
+}
+
+// LobbyPlacement is one player's finishing position in a lobby
+type LobbyPlacement struct {
+	UserID    string `json:"user_id"`
+	Placement int    `json:"placement"`
+	Score     int64  `json:"score"`
+}
+
+// lobbySettings resolves the lobby size and how many advance from each lobby: the request
+// wins, then the match type config (DefaultPlayerCountMax, AdvancementRule/AdvanceCount)
+func lobbySettings(cfg *models.MatchTypeConfig, req PairingRequest) (int, int) {
+	size := req.LobbySize
+	if size <= 0 {
+		size = cfg.DefaultPlayerCountMax
+		if size < 2 {
+			size = defaultLobbySize
+		}
+	}
+	advance := req.AdvanceCount
+	if advance <= 0 {
+		switch {
+		case cfg.AdvancementRule == models.AdvancementWinnerAdvances:
+			advance = 1
+		case cfg.AdvanceCount > 0:
+			advance = cfg.AdvanceCount
+		default:
+			advance = defaultLobbyAdvance
+		}
+	}
+	return size, advance
+}
+
+// snakeLobbyIndex returns the lobby for the i-th strongest player when dealing players
+// 1..L, L..1, 1..L across L lobbies, which keeps the lobbies' total skill close
+func snakeLobbyIndex(i, lobbies int) int {
+	pos := i % lobbies
+	if (i/lobbies)%2 == 1 {
+		return lobbies - 1 - pos
+	}
+	return pos
+}
+
+// generateLobbyPairs splits players into lobbies of at most lobbySize balanced by
+// PlayerSeeding.SkillRating. Each Pair is one lobby, its players in Players.
+func (ps *PairingService) generateLobbyPairs(players []models.TournamentSubscription, seedings map[string]models.PlayerSeeding, lobbySize, advance int) ([]Pair, map[string]interface{}, error) {
+	if lobbySize < 2 || advance >= lobbySize {
+		return nil, nil, ErrInvalidLobbySettings
+	}
+
+	ranked := append([]models.TournamentSubscription(nil), players...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return seedings[ranked[i].ExternalUserID].SkillRating > seedings[ranked[j].ExternalUserID].SkillRating
+	})
+
+	n := len(ranked)
+	lobbyCount := (n + lobbySize - 1) / lobbySize
+	pairs := make([]Pair, lobbyCount)
+	ratings := make([]float64, lobbyCount)
+	for l := range pairs {
+		pairs[l] = Pair{MatchNumber: l + 1, RoundNumber: 1}
+	}
+	for i, p := range ranked {
+		l := snakeLobbyIndex(i, lobbyCount)
+		rating := seedings[p.ExternalUserID].SkillRating
+		pairs[l].Players = append(pairs[l].Players, LobbySeat{
+			UserID:      p.ExternalUserID,
+			UserName:    p.UserName,
+			Seed:        i + 1,
+			SkillRating: rating,
+		})
+		ratings[l] += rating
+	}
+
+	smallest := n
+	for l := range pairs {
+		if len(pairs[l].Players) < smallest {
+			smallest = len(pairs[l].Players)
+		}
+		ratings[l] /= float64(len(pairs[l].Players))
+	}
+	if advance >= smallest && lobbyCount > 1 {
+		return nil, nil, fmt.Errorf("%w (smallest lobby has %d players)", ErrInvalidLobbySettings, smallest)
+	}
+
+	metadata := map[string]interface{}{
+		"format":                "free_for_all",
+		"lobby_size":            lobbySize,
+		"lobby_count":           lobbyCount,
+		"advance_count":         advance,
+		"total_players":         n,
+		"average_skill_ratings": ratings,
+	}
+	return pairs, metadata, nil
+}
+
+// filterMatchEntrants keeps only the players who qualified into a match. Matches without
+// entrants are open to every eligible subscriber.
+func (ps *PairingService) filterMatchEntrants(matchID string, players []models.TournamentSubscription) ([]models.TournamentSubscription, error) {
+	var entrants []models.MatchEntrant
+	if err := ps.DB.Where("match_id = ?", matchID).Find(&entrants).Error; err != nil {
+		return nil, err
+	}
+	if len(entrants) == 0 {
+		return players, nil
+	}
+
+	qualified := make(map[string]bool, len(entrants))
+	for _, e := range entrants {
+		qualified[e.UserID] = true
+	}
+	filtered := make([]models.TournamentSubscription, 0, len(entrants))
+	for _, p := range players {
+		if qualified[p.ExternalUserID] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, nil
+}
+
+// nextMatch returns the match that follows in the tournament structure: the next match of the
+// same batch, else the first match of the next batch (nil if this is the last match)
+func nextMatch(tx *gorm.DB, match *models.TournamentMatch) (*models.TournamentMatch, error) {
+	var next models.TournamentMatch
+	err := tx.Where("batch_id = ? AND sort_order > ?", match.BatchID, match.SortOrder).
+		Order("sort_order ASC").First(&next).Error
+	if err == nil {
+		return &next, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var batch models.TournamentBatch
+	if err := tx.First(&batch, "id = ?", match.BatchID).Error; err != nil {
+		return nil, err
+	}
+	err = tx.Joins("JOIN tournament_batches ON tournament_batches.id = tournament_matches.batch_id").
+		Where("tournament_batches.tournament_id = ? AND tournament_batches.sort_order > ?", batch.TournamentID, batch.SortOrder).
+		Order("tournament_batches.sort_order ASC, tournament_matches.sort_order ASC").
+		First(&next).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// publishLobbies (re)creates the lobbies of a FREE_FOR_ALL match from its published pairing.
+// Existing lobbies are replaced only while none has recorded placements.
+func publishLobbies(tx *gorm.DB, pairing *models.MatchPairing, pairs []Pair) ([]models.Lobby, error) {
+	var existing []models.Lobby
+	if err := tx.Where("match_id = ?", pairing.MatchID).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	if len(existing) > 0 {
+		ids := make([]string, len(existing))
+		for i, l := range existing {
+			if l.Status == models.LobbyStatusCompleted {
+				return nil, ErrLobbyHasResults
+			}
+			ids[i] = l.ID
+		}
+		if err := tx.Where("lobby_id IN ?", ids).Delete(&models.LobbyPlayer{}).Error; err != nil {
+			return nil, err
+		}
+		if err := tx.Where("id IN ?", ids).Delete(&models.Lobby{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	advance := defaultLobbyAdvance
+	var metadata map[string]interface{}
+	if err := json.Unmarshal([]byte(pairing.MetadataJSON), &metadata); err == nil {
+		if v, ok := metadata["advance_count"].(float64); ok && v > 0 {
+			advance = int(v)
+		}
+	}
+
+	lobbies := make([]models.Lobby, 0, len(pairs))
+	for _, pair := range pairs {
+		lobby := models.Lobby{
+			ID:           uuid.NewString(),
+			TournamentID: pairing.TournamentID,
+			MatchID:      pairing.MatchID,
+			PairingID:    pairing.ID,
+			LobbyNumber:  pair.MatchNumber,
+			AdvanceCount: advance,
+			Status:       models.LobbyStatusPending,
+		}
+		for _, seat := range pair.Players {
+			lobby.Players = append(lobby.Players, models.LobbyPlayer{
+				ID:          uuid.NewString(),
+				LobbyID:     lobby.ID,
+				UserID:      seat.UserID,
+				UserName:    seat.UserName,
+				Seed:        seat.Seed,
+				SkillRating: seat.SkillRating,
+			})
+		}
+		if err := tx.Create(&lobby).Error; err != nil {
+			return nil, err
+		}
+		lobbies = append(lobbies, lobby)
+	}
+	return lobbies, nil
+}
+
+// validatePlacements checks that placements name every lobby player once with 1..n
+func validatePlacements(players []models.LobbyPlayer, placements []LobbyPlacement) (map[string]LobbyPlacement, error) {
+	if len(placements) != len(players) {
+		return nil, ErrInvalidPlacements
+	}
+	seated := make(map[string]bool, len(players))
+	for _, p := range players {
+		seated[p.UserID] = true
+	}
+	byUser := make(map[string]LobbyPlacement, len(placements))
+	taken := make(map[int]bool, len(placements))
+	for _, pl := range placements {
+		if !seated[pl.UserID] || byUser[pl.UserID].UserID != "" {
+			return nil, ErrInvalidPlacements
+		}
+		if pl.Placement < 1 || pl.Placement > len(players) || taken[pl.Placement] {
+			return nil, ErrInvalidPlacements
+		}
+		taken[pl.Placement] = true
+		byUser[pl.UserID] = pl
+	}
+	return byUser, nil
+}
+
+// recordLobbyPlacements stores a lobby's placements and advances its top K into the next
+// match. Re-recording is allowed until the next match has been paired.
+func recordLobbyPlacements(tx *gorm.DB, lobbyID string, placements []LobbyPlacement, recordedBy string) (*models.Lobby, error) {
+	var lobby models.Lobby
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lobby, "id = ?", lobbyID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLobbyNotFound
+		}
+		return nil, err
+	}
+	if err := tx.Where("lobby_id = ?", lobby.ID).Find(&lobby.Players).Error; err != nil {
+		return nil, err
+	}
+	byUser, err := validatePlacements(lobby.Players, placements)
+	if err != nil {
+		return nil, err
+	}
+
+	var match models.TournamentMatch
+	if err := tx.First(&match, "id = ?", lobby.MatchID).Error; err != nil {
+		return nil, err
+	}
+	next, err := nextMatch(tx, &match)
+	if err != nil {
+		return nil, err
+	}
+	if next != nil && lobby.Status == models.LobbyStatusCompleted {
+		var paired int64
+		if err := tx.Model(&models.MatchPairing{}).
+			Where("match_id = ? AND status IN ?", next.ID, []string{"approved", "published"}).
+			Count(&paired).Error; err != nil {
+			return nil, err
+		}
+		if paired > 0 {
+			return nil, ErrLobbyLocked
+		}
+	}
+
+	for i := range lobby.Players {
+		p := &lobby.Players[i]
+		pl := byUser[p.UserID]
+		p.Placement = pl.Placement
+		p.Score = pl.Score
+		p.Advanced = next != nil && pl.Placement <= lobby.AdvanceCount
+		if err := tx.Model(p).Updates(map[string]interface{}{
+			"placement": p.Placement,
+			"score":     p.Score,
+			"advanced":  p.Advanced,
+		}).Error; err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(lobby.Players, func(i, j int) bool { return lobby.Players[i].Placement < lobby.Players[j].Placement })
+	if err := applyRatedGames(tx, "lobby:"+lobby.ID, lobby.TournamentID, placementGames(lobby.Players)); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	lobby.Status = models.LobbyStatusCompleted
+	lobby.RecordedBy = recordedBy
+	lobby.CompletedAt = &now
+	if err := tx.Model(&lobby).Updates(map[string]interface{}{
+		"status":       lobby.Status,
+		"recorded_by":  recordedBy,
+		"completed_at": &now,
+	}).Error; err != nil {
+		return nil, err
+	}
+
+	if next != nil {
+		if err := tx.Where("source_lobby_id = ?", lobby.ID).Delete(&models.MatchEntrant{}).Error; err != nil {
+			return nil, err
+		}
+		for _, p := range lobby.Players {
+			if !p.Advanced {
+				continue
+			}
+			entrant := models.MatchEntrant{
+				ID:            uuid.NewString(),
+				MatchID:       next.ID,
+				UserID:        p.UserID,
+				UserName:      p.UserName,
+				SourceMatchID: match.ID,
+				SourceLobbyID: lobby.ID,
+				Placement:     p.Placement,
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entrant).Error; err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Once every lobby is in, the match is over; a single lobby also has a winner
+	var lobbies []models.Lobby
+	if err := tx.Where("match_id = ?", match.ID).Find(&lobbies).Error; err != nil {
+		return nil, err
+	}
+	for _, l := range lobbies {
+		if l.ID != lobby.ID && l.Status != models.LobbyStatusCompleted {
+			return &lobby, nil
+		}
+	}
+	if len(lobbies) == 1 {
+		return &lobby, recordMatchWinner(tx, match.ID, lobby.Players[0].UserID, lobby.Players[0].UserName)
+	}
+	if models.CanTransitionStage(match.Status, models.StageStatusCompleted) {
+		if err := tx.Model(&match).Update("status", models.StageStatusCompleted).Error; err != nil {
+			return nil, err
+		}
+	}
+	return &lobby, nil
+}
+
+// RecordLobbyResult records the placements of a free-for-all lobby
+func (ps *PairingService) RecordLobbyResult(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		Placements []LobbyPlacement `json:"placements"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+
+	var lobby *models.Lobby
+	err := ps.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		lobby, err = recordLobbyPlacements(tx, c.Params("lobby_id"), req.Placements, userID)
+		return err
+	})
+	if err != nil {
+		return lobbyErrorResponse(c, err)
+	}
+
+	log.Printf("✅ Placements recorded for lobby %d of match %s", lobby.LobbyNumber, lobby.MatchID)
+	return c.JSON(lobby)
+}
+
+// GetMatchLobbies lists a match's lobbies with their players and placements
+func (ps *PairingService) GetMatchLobbies(c *fiber.Ctx) error {
+	matchID := c.Params("match_id")
+
+	var lobbies []models.Lobby
+	if err := ps.DB.Preload("Players", func(db *gorm.DB) *gorm.DB {
+		return db.Order("placement = 0, placement ASC, seed ASC")
+	}).Where("match_id = ?", matchID).
+		Order("lobby_number ASC").
+		Find(&lobbies).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+
+	completed := 0
+	for _, l := range lobbies {
+		if l.Status == models.LobbyStatusCompleted {
+			completed++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"match_id":  matchID,
+		"lobbies":   lobbies,
+		"count":     len(lobbies),
+		"completed": completed,
+	})
+}
+
+// GetMatchEntrants lists the players who qualified into a match
+func (ps *PairingService) GetMatchEntrants(c *fiber.Ctx) error {
+	var entrants []models.MatchEntrant
+	if err := ps.DB.Where("match_id = ?", c.Params("match_id")).
+		Order("placement ASC, created_at ASC").
+		Find(&entrants).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+	return c.JSON(fiber.Map{"match_id": c.Params("match_id"), "entrants": entrants, "count": len(entrants)})
+}
+
+// lobbyErrorResponse maps lobby errors to HTTP responses
+func lobbyErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrLobbyNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrInvalidPlacements), errors.Is(err, ErrInvalidLobbySettings):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrLobbyLocked), errors.Is(err, ErrLobbyHasResults):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "failed to record lobby result", "details": err.Error()})
+	}
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"game-publish-system/utils"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultResultConfirmTimeout = 24 * time.Hour
+
+var (
+	ErrResultFinal       = errors.New("result has already been confirmed")
+	ErrNotMatchPlayer    = errors.New("only the two players (or team captains) can report this result")
+	ErrBracketDraw       = errors.New("bracket games cannot end in a draw")
+	ErrResultNotFound    = errors.New("result not found")
+	ErrResultSubjectMiss = errors.New("pairing_id and match_number, or bracket_node_id, are required")
+)
+
+// resultConfirmTimeout reads RESULT_CONFIRM_TIMEOUT_HOURS, defaulting to 24 hours
+func resultConfirmTimeout() time.Duration {
+	if v := os.Getenv("RESULT_CONFIRM_TIMEOUT_HOURS"); v != "" {
+		if hours, err := strconv.Atoi(v); err == nil && hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return defaultResultConfirmTimeout
+}
+
+// ResultReportRequest is accepted as JSON or multipart (with an optional "evidence" file)
+type ResultReportRequest struct {
+	PairingID     string `json:"pairing_id" form:"pairing_id"`
+	MatchNumber   int    `json:"match_number" form:"match_number"`
+	BracketNodeID string `json:"bracket_node_id" form:"bracket_node_id"`
+	WinnerID      string `json:"winner_id" form:"winner_id"`
+	Draw          bool   `json:"draw" form:"draw"`
+	Player1Score  int64  `json:"player1_score" form:"player1_score"`
+	Player2Score  int64  `json:"player2_score" form:"player2_score"`
+	Note          string `json:"note" form:"note"`
+}
+
+// resultSubject is the game a report refers to
+type resultSubject struct {
+	key       string
+	result    models.MatchResult // template for a new MatchResult
+	allowDraw bool
+}
+
+// loadResultSubject resolves a report to a published pair or a ready bracket node of the match
+func (ps *PairingService) loadResultSubject(matchID string, req *ResultReportRequest) (*resultSubject, error) {
+	switch {
+	case req.BracketNodeID != "":
+		var node models.BracketNode
+		if err := ps.DB.First(&node, "id = ?", req.BracketNodeID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrBracketNotFound
+			}
+			return nil, err
+		}
+		var bracket models.Bracket
+		if err := ps.DB.First(&bracket, "id = ? AND match_id = ?", node.BracketID, matchID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrBracketNotFound
+			}
+			return nil, err
+		}
+		if node.Status != models.BracketNodeReady {
+			return nil, ErrBracketNodeNotReady
+		}
+		return &resultSubject{
+			key: "node:" + node.ID,
+			result: models.MatchResult{
+				TournamentID:  bracket.TournamentID,
+				MatchID:       bracket.MatchID,
+				BracketID:     bracket.ID,
+				BracketNodeID: node.ID,
+				Player1ID:     node.Player1ID,
+				Player2ID:     node.Player2ID,
+			},
+		}, nil
+
+	case req.PairingID != "" && req.MatchNumber > 0:
+		var pairing models.MatchPairing
+		if err := ps.DB.First(&pairing, "id = ? AND match_id = ?", req.PairingID, matchID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrPairNotFound
+			}
+			return nil, err
+		}
+		if pairing.Status != "published" {
+			return nil, ErrPairingNotPublished
+		}
+		pair, err := findPair(&pairing, req.MatchNumber)
+		if err != nil {
+			return nil, err
+		}
+		if pair.Player2ID == "" {
+			return nil, ErrPairIsBye
+		}
+		return &resultSubject{
+			key: fmt.Sprintf("pair:%s:%d", pairing.ID, pair.MatchNumber),
+			result: models.MatchResult{
+				TournamentID: pairing.TournamentID,
+				MatchID:      pairing.MatchID,
+				PairingID:    pairing.ID,
+				MatchNumber:  pair.MatchNumber,
+				Player1ID:    pair.Player1ID,
+				Player2ID:    pair.Player2ID,
+			},
+			allowDraw: true,
+		}, nil
+
+	default:
+		return nil, ErrResultSubjectMiss
+	}
+}
+
+// resultOutcome converts a winner (or draw) into an outcome for a result's players
+func resultOutcome(result *models.MatchResult, winnerID string, draw, allowDraw bool) (string, error) {
+	switch {
+	case draw && !allowDraw:
+		return "", ErrBracketDraw
+	case draw:
+		return models.PairingOutcomeDraw, nil
+	case winnerID == result.Player1ID:
+		return models.PairingOutcomePlayer1, nil
+	case winnerID == result.Player2ID:
+		return models.PairingOutcomePlayer2, nil
+	default:
+		return "", ErrInvalidPairWinner
+	}
+}
+
+// ReportMatchResult lets either player (or team captain) of a head-to-head game report its outcome.
+// Matching reports confirm the result; conflicting ones open a dispute; a lone report
+// is confirmed automatically once RESULT_CONFIRM_TIMEOUT_HOURS pass.
+func (ps *PairingService) ReportMatchResult(c *fiber.Ctx) error {
+	matchID := c.Params("match_id")
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(401).JSON(fiber.Map{"error": "user context required"})
+	}
+
+	var req ResultReportRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid request body", "details": err.Error()})
+	}
+
+	subject, err := ps.loadResultSubject(matchID, &req)
+	if err != nil {
+		return matchResultErrorResponse(c, err)
+	}
+	reporterID := resultEntrant(ps.DB, userID, &subject.result)
+	if reporterID == "" {
+		return matchResultErrorResponse(c, ErrNotMatchPlayer)
+	}
+	outcome, err := resultOutcome(&subject.result, req.WinnerID, req.Draw, subject.allowDraw)
+	if err != nil {
+		return matchResultErrorResponse(c, err)
+	}
+
+	// A settled result takes no more reports; checked again under the lock below
+	var existing models.MatchResult
+	if err := ps.DB.Select("status").Where("subject_key = ?", subject.key).Limit(1).Find(&existing).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	if existing.Status == models.MatchResultConfirmed || existing.Status == models.MatchResultResolved {
+		return matchResultErrorResponse(c, ErrResultFinal)
+	}
+
+	// --- Optional screenshot evidence → R2 ---
+	var evidenceURL, evidenceKey string
+	if evidence, err := c.FormFile("evidence"); err == nil && evidence.Size > 0 {
+		ext := filepath.Ext(evidence.Filename)
+		if ext == "" {
+			ext = ".jpg"
+		}
+		key := "results/evidence/" + uuid.NewString() + ext
+		url, err := utils.UploadFileToR2(evidence, key)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to upload evidence"})
+		}
+		evidenceURL, evidenceKey = url, key
+	}
+
+	var result models.MatchResult
+	err = ps.DB.Transaction(func(tx *gorm.DB) error {
+		fresh := subject.result
+		fresh.ID = uuid.NewString()
+		fresh.SubjectKey = subject.key
+		fresh.Status = models.MatchResultPending
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&fresh).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&result, "subject_key = ?", subject.key).Error; err != nil {
+			return err
+		}
+		if result.Status == models.MatchResultConfirmed || result.Status == models.MatchResultResolved {
+			return ErrResultFinal
+		}
+
+		report := models.MatchResultReport{
+			ID:           uuid.NewString(),
+			ResultID:     result.ID,
+			ReporterID:   reporterID,
+			Outcome:      outcome,
+			Player1Score: req.Player1Score,
+			Player2Score: req.Player2Score,
+			EvidenceURL:  evidenceURL,
+			Note:         req.Note,
+		}
+		updateColumns := []string{"outcome", "player1_score", "player2_score", "note", "updated_at"}
+		if evidenceURL != "" {
+			updateColumns = append(updateColumns, "evidence_url")
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "result_id"}, {Name: "reporter_id"}},
+			DoUpdates: clause.AssignmentColumns(updateColumns),
+		}).Create(&report).Error; err != nil {
+			return err
+		}
+
+		var reports []models.MatchResultReport
+		if err := tx.Where("result_id = ?", result.ID).Find(&reports).Error; err != nil {
+			return err
+		}
+
+		if len(reports) < 2 {
+			if result.ConfirmDeadline == nil {
+				deadline := time.Now().Add(resultConfirmTimeout())
+				result.ConfirmDeadline = &deadline
+			}
+			result.Status = models.MatchResultPending
+		} else if reports[0].Outcome == reports[1].Outcome {
+			scores := reports[0]
+			if reports[1].ReporterID == result.Player1ID {
+				scores = reports[1]
+			}
+			if err := confirmMatchResult(tx, &result, scores.Outcome, scores.Player1Score, scores.Player2Score, "players"); err != nil {
+				return err
+			}
+		} else {
+			result.Status = models.MatchResultDisputed
+			result.ConfirmDeadline = nil
+			log.Printf("⚠️ Result dispute opened for %s", result.SubjectKey)
+		}
+
+		if err := tx.Save(&result).Error; err != nil {
+			return err
+		}
+		result.Reports = reports
+		return nil
+	})
+	if err != nil {
+		// The report was not saved, so nothing references the evidence
+		if evidenceKey != "" {
+			if delErr := utils.DeleteFileFromR2(evidenceKey); delErr != nil {
+				log.Printf("⚠️ Failed to delete unused result evidence %s: %v", evidenceKey, delErr)
+			}
+		}
+		return matchResultErrorResponse(c, err)
+	}
+
+	return c.JSON(result)
+}
+
+// confirmMatchResult stamps the agreed outcome and feeds it to the pairing or bracket.
+// This is the single place confirmed head-to-head results flow out of.
+func confirmMatchResult(tx *gorm.DB, result *models.MatchResult, outcome string, score1, score2 int64, confirmedBy string) error {
+	now := time.Now()
+	result.Outcome = outcome
+	result.Player1Score = score1
+	result.Player2Score = score2
+	result.ConfirmedAt = &now
+	result.ConfirmDeadline = nil
+	if result.Status != models.MatchResultResolved {
+		result.Status = models.MatchResultConfirmed
+	}
+	switch outcome {
+	case models.PairingOutcomePlayer1:
+		result.WinnerID = result.Player1ID
+	case models.PairingOutcomePlayer2:
+		result.WinnerID = result.Player2ID
+	default:
+		result.WinnerID = ""
+	}
+
+	if result.BracketNodeID != "" {
+		if _, err := reportBracketResultTx(tx, result.BracketID, result.BracketNodeID, result.WinnerID, score1, score2); err != nil {
+			return err
+		}
+	} else {
+		var pairing models.MatchPairing
+		if err := tx.First(&pairing, "id = ?", result.PairingID).Error; err != nil {
+			return err
+		}
+		pair, err := findPair(&pairing, result.MatchNumber)
+		if err != nil {
+			return err
+		}
+		if _, err := recordPairingResult(tx, &pairing, pair, outcome, score1, score2, confirmedBy); err != nil {
+			return err
+		}
+	}
+
+	log.Printf("✅ Result confirmed for %s: %s (by %s)", result.SubjectKey, outcome, confirmedBy)
+	return nil
+}
+
+// autoConfirmExpiredResults confirms single-reporter results whose deadline has passed
+func (ps *PairingService) autoConfirmExpiredResults(now time.Time) {
+	var ids []string
+	if err := ps.DB.Model(&models.MatchResult{}).
+		Where("status = ? AND confirm_deadline <= ?", models.MatchResultPending, now).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("[Scheduler] DB error loading expired results: %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		err := ps.DB.Transaction(func(tx *gorm.DB) error {
+			var result models.MatchResult
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&result, "id = ?", id).Error; err != nil {
+				return err
+			}
+			if result.Status != models.MatchResultPending {
+				return nil
+			}
+			var report models.MatchResultReport
+			if err := tx.Where("result_id = ?", result.ID).First(&report).Error; err != nil {
+				return err
+			}
+			result.AutoConfirmed = true
+			if err := confirmMatchResult(tx, &result, report.Outcome, report.Player1Score, report.Player2Score, "timeout"); err != nil {
+				return err
+			}
+			return tx.Save(&result).Error
+		})
+		if err != nil {
+			log.Printf("[Scheduler] Failed to auto-confirm result %s: %v", id, err)
+		}
+	}
+}
+
+// GetMatchResults lists reported results for a match, with the individual reports
+func (ps *PairingService) GetMatchResults(c *fiber.Ctx) error {
+	var results []models.MatchResult
+	if err := ps.DB.Preload("Reports").
+		Where("match_id = ?", c.Params("match_id")).
+		Order("created_at ASC").
+		Find(&results).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+	return c.JSON(fiber.Map{"match_id": c.Params("match_id"), "results": results, "count": len(results)})
+}
+
+// GetResultDisputes lists results waiting for an admin (admin)
+func (ps *PairingService) GetResultDisputes(c *fiber.Ctx) error {
+	status := c.Query("status", models.MatchResultDisputed)
+
+	query := ps.DB.Preload("Reports").Where("status = ?", status)
+	if tournamentID := c.Query("tournament_id"); tournamentID != "" {
+		query = query.Where("tournament_id = ?", tournamentID)
+	}
+
+	var results []models.MatchResult
+	if err := query.Order("updated_at ASC").Find(&results).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+	return c.JSON(fiber.Map{"data": results, "count": len(results)})
+}
+
+// ResolveMatchResult lets an admin decide a disputed (or still pending) result
+func (ps *PairingService) ResolveMatchResult(c *fiber.Ctx) error {
+	resultID := c.Params("result_id")
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		WinnerID     string `json:"winner_id"`
+		Draw         bool   `json:"draw"`
+		Player1Score int64  `json:"player1_score"`
+		Player2Score int64  `json:"player2_score"`
+		Note         string `json:"note"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+
+	var result models.MatchResult
+	err := ps.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&result, "id = ?", resultID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrResultNotFound
+			}
+			return err
+		}
+		if result.Status == models.MatchResultConfirmed || result.Status == models.MatchResultResolved {
+			return ErrResultFinal
+		}
+
+		outcome, err := resultOutcome(&result, req.WinnerID, req.Draw, result.BracketNodeID == "")
+		if err != nil {
+			return err
+		}
+
+		result.Status = models.MatchResultResolved
+		result.ResolvedBy = userID
+		result.ResolutionNote = req.Note
+		if err := confirmMatchResult(tx, &result, outcome, req.Player1Score, req.Player2Score, userID); err != nil {
+			return err
+		}
+		return tx.Save(&result).Error
+	})
+	if err != nil {
+		return matchResultErrorResponse(c, err)
+	}
+
+	return c.JSON(result)
+}
+
+// matchResultErrorResponse maps result reporting errors to HTTP responses
+func matchResultErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrResultNotFound), errors.Is(err, ErrPairNotFound), errors.Is(err, ErrBracketNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrNotMatchPlayer):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrResultSubjectMiss), errors.Is(err, ErrInvalidPairWinner),
+		errors.Is(err, ErrPairIsBye), errors.Is(err, ErrBracketDraw), errors.Is(err, ErrInvalidBracketWinner):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrResultFinal), errors.Is(err, ErrPairingNotPublished),
+		errors.Is(err, ErrBracketNodeNotReady), errors.Is(err, ErrBracketCompleted):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "failed to record result", "details": err.Error()})
+	}
+}
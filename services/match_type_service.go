@@ -0,0 +1,339 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownMatchType       = errors.New("unknown match type")
+	ErrInactiveMatchType      = errors.New("match type is inactive")
+	ErrMatchTypeExists        = errors.New("match type already exists")
+	ErrMatchTypeInUse         = errors.New("match type is used by existing matches")
+	ErrInvalidMatchTypeConfig = errors.New("invalid match type config")
+)
+
+var knownProgressionTypes = []string{
+	models.ProgressionSingleElimination,
+	models.ProgressionDoubleElimination,
+	models.ProgressionRoundRobin,
+	models.ProgressionSwiss,
+	models.ProgressionLeaderboard,
+	models.ProgressionLobbies,
+}
+
+// defaultMatchTypeConfigs are seeded on startup so existing matches keep working
+func defaultMatchTypeConfigs() []models.MatchTypeConfig {
+	return []models.MatchTypeConfig{
+		{
+			MatchType:             MatchTypeSingleElimination,
+			Name:                  "Single Elimination 1v1",
+			Description:           "Head-to-head knockout tournament",
+			MatchFormat:           models.MatchFormatHeadToHead,
+			ProgressionType:       models.ProgressionSingleElimination,
+			AdvancementRule:       models.AdvancementWinnerAdvances,
+			EliminationRule:       "IMMEDIATE_ELIMINATION",
+			DefaultPlayerCountMin: 2,
+			DefaultPlayerCountMax: 128,
+			PlayersPerTeam:        1,
+			NumberOfTeams:         2,
+			DefaultRoundsPerMatch: 1,
+			SeedingMethod:         "RANK_BASED",
+			AdvanceCount:          1,
+		},
+		{
+			MatchType:             MatchTypeDoubleElimination,
+			Name:                  "Double Elimination 1v1",
+			Description:           "Double elimination bracket",
+			MatchFormat:           models.MatchFormatHeadToHead,
+			ProgressionType:       models.ProgressionDoubleElimination,
+			AdvancementRule:       models.AdvancementWinnerAdvances,
+			EliminationRule:       "N_LOSSES_ELIMINATION",
+			DefaultPlayerCountMin: 2,
+			DefaultPlayerCountMax: 128,
+			PlayersPerTeam:        1,
+			NumberOfTeams:         2,
+			DefaultRoundsPerMatch: 1,
+			SeedingMethod:         "RANK_BASED",
+			AdvanceCount:          1,
+		},
+		{
+			MatchType:             "ROUND_ROBIN_1V1",
+			Name:                  "Round Robin 1v1",
+			Description:           "Every player plays every other player",
+			MatchFormat:           models.MatchFormatHeadToHead,
+			ProgressionType:       models.ProgressionRoundRobin,
+			AdvancementRule:       models.AdvancementPointsBased,
+			DefaultPlayerCountMin: 2,
+			DefaultPlayerCountMax: 16,
+			PlayersPerTeam:        1,
+			NumberOfTeams:         2,
+			DefaultRoundsPerMatch: 1,
+			SeedingMethod:         "RANK_BASED",
+			AdvanceCount:          1,
+		},
+		{
+			MatchType:             "LEADERBOARD_CHALLENGE",
+			Name:                  "Leaderboard Challenge",
+			Description:           "Players compete for highest score",
+			MatchFormat:           models.MatchFormatFreeForAll,
+			ProgressionType:       models.ProgressionLeaderboard,
+			AdvancementRule:       models.AdvancementPointsBased,
+			DefaultPlayerCountMin: 1,
+			DefaultPlayerCountMax: 1000,
+			PlayersPerTeam:        1,
+			NumberOfTeams:         1,
+			DefaultRoundsPerMatch: 1,
+			SeedingMethod:         "RANK_BASED",
+			AdvanceCount:          1,
+		},
+		{
+			MatchType:             "SWISS_SYSTEM",
+			Name:                  "Swiss System",
+			Description:           "Players with similar records face each other",
+			MatchFormat:           models.MatchFormatHeadToHead,
+			ProgressionType:       models.ProgressionSwiss,
+			AdvancementRule:       models.AdvancementPointsBased,
+			DefaultPlayerCountMin: 4,
+			DefaultPlayerCountMax: 128,
+			PlayersPerTeam:        1,
+			NumberOfTeams:         2,
+			DefaultRoundsPerMatch: 1,
+			SeedingMethod:         "RANK_BASED",
+			AdvanceCount:          1,
+		},
+		{
+			MatchType:             MatchTypeFreeForAll,
+			Name:                  "Free-for-All Lobbies",
+			Description:           "Skill-balanced N-player lobbies; the top finishers of each lobby advance",
+			MatchFormat:           models.MatchFormatFreeForAll,
+			ProgressionType:       models.ProgressionLobbies,
+			AdvancementRule:       models.AdvancementTopNAdvance,
+			DefaultPlayerCountMin: 2,
+			DefaultPlayerCountMax: defaultLobbySize,
+			PlayersPerTeam:        1,
+			NumberOfTeams:         defaultLobbySize,
+			DefaultRoundsPerMatch: 1,
+			SeedingMethod:         "SKILL_BASED",
+			AdvanceCount:          defaultLobbyAdvance,
+		},
+	}
+}
+
+// SeedMatchTypeConfigs inserts the built-in match types that are missing. Admin edits to
+// existing rows are left alone.
+func (s *TournamentService) SeedMatchTypeConfigs() error {
+	for _, cfg := range defaultMatchTypeConfigs() {
+		var count int64
+		if err := s.DB.Model(&models.MatchTypeConfig{}).Where("match_type = ?", cfg.MatchType).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+		cfg.ID = uuid.NewString()
+		cfg.IsActive = true
+		if err := s.DB.Create(&cfg).Error; err != nil {
+			return err
+		}
+		log.Printf("🧩 Seeded match type %s", cfg.MatchType)
+	}
+	return nil
+}
+
+// loadMatchTypeConfig fetches the config for a match type
+func loadMatchTypeConfig(db *gorm.DB, matchType string) (*models.MatchTypeConfig, error) {
+	var cfg models.MatchTypeConfig
+	if err := db.Where("match_type = ?", matchType).First(&cfg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownMatchType, matchType)
+		}
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// validateMatchType checks that new or updated matches use a configured, active type.
+// An empty type is allowed and falls back to the column default.
+func validateMatchType(db *gorm.DB, matchType string) error {
+	if matchType == "" {
+		return nil
+	}
+	cfg, err := loadMatchTypeConfig(db, matchType)
+	if err != nil {
+		return err
+	}
+	if !cfg.IsActive {
+		return fmt.Errorf("%w: %s", ErrInactiveMatchType, matchType)
+	}
+	return nil
+}
+
+// validateMatchTypeConfig checks the fields pairing relies on
+func validateMatchTypeConfig(cfg *models.MatchTypeConfig) error {
+	if cfg.MatchType == "" || cfg.Name == "" {
+		return fmt.Errorf("%w: match_type and name are required", ErrInvalidMatchTypeConfig)
+	}
+	known := false
+	for _, p := range knownProgressionTypes {
+		if cfg.ProgressionType == p {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w: progression_type must be one of %s", ErrInvalidMatchTypeConfig, strings.Join(knownProgressionTypes, ", "))
+	}
+	if cfg.DefaultPlayerCountMin < 1 || cfg.DefaultPlayerCountMax < cfg.DefaultPlayerCountMin {
+		return fmt.Errorf("%w: player counts must satisfy 1 <= min <= max", ErrInvalidMatchTypeConfig)
+	}
+	if cfg.AdvanceCount < 0 {
+		return fmt.Errorf("%w: advance_count cannot be negative", ErrInvalidMatchTypeConfig)
+	}
+	if cfg.IsLobbies() && cfg.AdvanceCount >= cfg.DefaultPlayerCountMax {
+		return fmt.Errorf("%w: advance_count must be smaller than default_player_count_max", ErrInvalidMatchTypeConfig)
+	}
+	return nil
+}
+
+// matchTypeSummary is the public shape of a match type
+func matchTypeSummary(cfg models.MatchTypeConfig) fiber.Map {
+	return fiber.Map{
+		"id":                       cfg.MatchType,
+		"config_id":                cfg.ID,
+		"name":                     cfg.Name,
+		"description":              cfg.Description,
+		"match_format":             cfg.MatchFormat,
+		"progression_type":         cfg.ProgressionType,
+		"advancement_rule":         cfg.AdvancementRule,
+		"default_player_count_min": cfg.DefaultPlayerCountMin,
+		"default_player_count_max": cfg.DefaultPlayerCountMax,
+		"players_per_team":         cfg.PlayersPerTeam,
+		"supports_pairing":         cfg.SupportsPairing(),
+	}
+}
+
+// GetSupportedMatchTypes returns all active match types
+func (s *TournamentService) GetSupportedMatchTypes(c *fiber.Ctx) error {
+	var configs []models.MatchTypeConfig
+	if err := s.DB.Where("is_active = ?", true).Order("name ASC").Find(&configs).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch match types"})
+	}
+
+	matchTypes := make([]fiber.Map, len(configs))
+	for i, cfg := range configs {
+		matchTypes[i] = matchTypeSummary(cfg)
+	}
+	return c.JSON(fiber.Map{
+		"match_types": matchTypes,
+		"count":       len(matchTypes),
+	})
+}
+
+// GetMatchTypeConfigs lists every match type config, including inactive ones (admin)
+func (s *TournamentService) GetMatchTypeConfigs(c *fiber.Ctx) error {
+	var configs []models.MatchTypeConfig
+	if err := s.DB.Order("name ASC").Find(&configs).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch match types"})
+	}
+	return c.JSON(fiber.Map{"match_types": configs, "count": len(configs)})
+}
+
+// CreateMatchTypeConfig adds a new match type (admin)
+func (s *TournamentService) CreateMatchTypeConfig(c *fiber.Ctx) error {
+	cfg := models.MatchTypeConfig{IsActive: true, PlayersPerTeam: 1, NumberOfTeams: 2, DefaultRoundsPerMatch: 1, AdvanceCount: 1}
+	if err := c.BodyParser(&cfg); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+	cfg.ID = uuid.NewString()
+	cfg.MatchType = strings.ToUpper(strings.TrimSpace(cfg.MatchType))
+	if err := validateMatchTypeConfig(&cfg); err != nil {
+		return matchTypeErrorResponse(c, err)
+	}
+
+	var count int64
+	if err := s.DB.Model(&models.MatchTypeConfig{}).Where("match_type = ?", cfg.MatchType).Count(&count).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+	if count > 0 {
+		return matchTypeErrorResponse(c, ErrMatchTypeExists)
+	}
+
+	if err := s.DB.Create(&cfg).Error; err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			return matchTypeErrorResponse(c, ErrMatchTypeExists)
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to create match type", "details": err.Error()})
+	}
+	return c.Status(201).JSON(cfg)
+}
+
+// UpdateMatchTypeConfig edits a match type (admin). The match_type key itself can't change
+// since matches reference it.
+func (s *TournamentService) UpdateMatchTypeConfig(c *fiber.Ctx) error {
+	var cfg models.MatchTypeConfig
+	if err := s.DB.First(&cfg, "id = ?", c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "match type not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+
+	// Fields left out of the body keep their stored values; the keys never come from the body
+	req := cfg
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+	req.ID, req.MatchType, req.CreatedAt = cfg.ID, cfg.MatchType, cfg.CreatedAt
+	if err := validateMatchTypeConfig(&req); err != nil {
+		return matchTypeErrorResponse(c, err)
+	}
+
+	if err := s.DB.Model(&cfg).Select("*").Omit("id", "match_type", "created_at").Updates(&req).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update match type", "details": err.Error()})
+	}
+	return c.JSON(req)
+}
+
+// DeleteMatchTypeConfig removes a match type no match uses (admin); deactivate it otherwise
+func (s *TournamentService) DeleteMatchTypeConfig(c *fiber.Ctx) error {
+	var cfg models.MatchTypeConfig
+	if err := s.DB.First(&cfg, "id = ?", c.Params("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "match type not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var used int64
+	if err := s.DB.Model(&models.TournamentMatch{}).Where("match_type = ?", cfg.MatchType).Count(&used).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+	if used > 0 {
+		return matchTypeErrorResponse(c, fmt.Errorf("%w (%d matches); deactivate it instead", ErrMatchTypeInUse, used))
+	}
+
+	if err := s.DB.Delete(&cfg).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to delete match type"})
+	}
+	return c.JSON(fiber.Map{"message": "match type deleted", "match_type": cfg.MatchType})
+}
+
+// matchTypeErrorResponse maps match type errors to HTTP responses
+func matchTypeErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrUnknownMatchType), errors.Is(err, ErrInactiveMatchType), errors.Is(err, ErrInvalidMatchTypeConfig):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrMatchTypeExists), errors.Is(err, ErrMatchTypeInUse):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "match type lookup failed", "details": err.Error()})
+	}
+}
@@ -0,0 +1,521 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"sort"
+	"strings"
+)
+
+// Pairing constraint names, as listed in PairingConstraints.Hard
+const (
+	ConstraintRematch        = "rematch"
+	ConstraintTeam           = "team"
+	ConstraintReferral       = "referral"
+	ConstraintSeedProtection = "seed_protection"
+	ConstraintRegion         = "region"
+)
+
+// maxConstraintPasses bounds the swap search; each pass is O(pairs²)
+const maxConstraintPasses = 50
+
+var (
+	ErrDuplicatePairedPlayer = errors.New("player appears in more than one pair")
+	ErrMissingPairedPlayer   = errors.New("eligible player is missing from the pairings")
+	ErrUnknownPairedPlayer   = errors.New("player is not eligible for this match")
+	ErrUnknownConstraint     = errors.New("unknown pairing constraint")
+	ErrConstraintsViolated   = errors.New("pairings cannot satisfy the hard constraints")
+	ErrConstraintsFormat     = errors.New("pairing constraints only apply to head-to-head formats")
+)
+
+// PairingConstraints steer head-to-head pairing away from unwanted opponents. Each constraint
+// is soft (avoided where possible) unless it is listed in Hard, in which case a proposal that
+// still breaks it after solving is rejected.
+type PairingConstraints struct {
+	NoRematchWithin   int      `json:"no_rematch_within,omitempty"`  // avoid opponents from the last N published rounds of the tournament
+	SeparateTeams     bool     `json:"separate_teams,omitempty"`     // players sharing a team roster don't meet
+	SeparateReferrals bool     `json:"separate_referrals,omitempty"` // players from the same referral tree don't meet
+	SeparateRegions   bool     `json:"separate_regions,omitempty"`   // players who subscribed from the same region don't meet
+	ProtectTopSeeds   int      `json:"protect_top_seeds,omitempty"`  // the top N seeds avoid each other...
+	ProtectRounds     int      `json:"protect_rounds,omitempty"`     // ...in the match's first N rounds (default 1)
+	Hard              []string `json:"hard,omitempty"`
+}
+
+// ConstraintViolation is a constraint a proposed pair still breaks
+type ConstraintViolation struct {
+	MatchNumber int    `json:"match_number"`
+	Player1ID   string `json:"player1_id"`
+	Player2ID   string `json:"player2_id"`
+	Constraint  string `json:"constraint"`
+	Hard        bool   `json:"hard"`
+}
+
+func (pc *PairingConstraints) validate() error {
+	for _, name := range pc.Hard {
+		switch name {
+		case ConstraintRematch, ConstraintTeam, ConstraintReferral, ConstraintSeedProtection, ConstraintRegion:
+		default:
+			return fmt.Errorf("%w: %s", ErrUnknownConstraint, name)
+		}
+	}
+	if pc.NoRematchWithin < 0 || pc.ProtectTopSeeds < 0 || pc.ProtectRounds < 0 {
+		return fmt.Errorf("%w: values must not be negative", ErrUnknownConstraint)
+	}
+	return nil
+}
+
+// constraintContext holds what the constraints are checked against
+type constraintContext struct {
+	recent  map[string]map[string]bool // opponents within the rematch window
+	teams   map[string][]string        // user -> team ids
+	roots   map[string]string          // user -> top of their referral tree
+	regions map[string]string          // user -> region they subscribed from
+	seeds   map[string]int             // user -> seed number (0 = unseeded)
+	order   map[string]int             // user -> position in the generated order
+	layout  map[string]string          // user -> generated opponent; set, it replaces order (brackets)
+	round   int                        // round of the match being paired, 1-based
+	cons    PairingConstraints
+	hard    map[string]bool
+}
+
+// constraint penalties for soft violations; the order offset keeps pairs close to the
+// order the format produced (score groups, seed lines)
+var constraintWeights = map[string]float64{
+	ConstraintRematch:        100,
+	ConstraintTeam:           80,
+	ConstraintReferral:       40,
+	ConstraintSeedProtection: 60,
+	ConstraintRegion:         30,
+}
+
+const (
+	constraintOrderWeight = 0.5
+	// constraintLayoutWeight is what moving a player off their generated bracket line costs
+	constraintLayoutWeight = 1.0
+)
+
+// loadConstraintContext gathers opponent history, team rosters, referral trees and seeds
+// for the players of one match.
+func (ps *PairingService) loadConstraintContext(cons PairingConstraints, tournamentID, matchID string,
+	players []models.TournamentSubscription, seedings map[string]models.PlayerSeeding) (*constraintContext, error) {
+	ctx := &constraintContext{
+		recent:  make(map[string]map[string]bool),
+		teams:   make(map[string][]string),
+		roots:   make(map[string]string),
+		regions: make(map[string]string, len(players)),
+		seeds:   make(map[string]int),
+		order:   make(map[string]int, len(players)),
+		cons:    cons,
+		hard:    make(map[string]bool, len(cons.Hard)),
+	}
+	if ctx.cons.ProtectRounds == 0 {
+		ctx.cons.ProtectRounds = 1
+	}
+	for _, name := range cons.Hard {
+		ctx.hard[name] = true
+	}
+	ids := make([]string, len(players))
+	for i, p := range players {
+		ids[i] = p.ExternalUserID
+		ctx.order[p.ExternalUserID] = i
+		ctx.regions[p.ExternalUserID] = p.Region
+		if s, ok := seedings[p.ExternalUserID]; ok {
+			ctx.seeds[p.ExternalUserID] = s.SeedNumber
+		}
+	}
+
+	// Every published pairing of the match so far is one round
+	var played int64
+	if err := ps.DB.Model(&models.MatchPairing{}).
+		Where("match_id = ? AND status = ?", matchID, "published").Count(&played).Error; err != nil {
+		return nil, err
+	}
+	ctx.round = int(played) + 1
+
+	if cons.NoRematchWithin > 0 {
+		var pairings []models.MatchPairing
+		if err := ps.DB.Select("id", "pairs_json").
+			Where("tournament_id = ? AND status = ?", tournamentID, "published").
+			Order("published_at DESC").Limit(cons.NoRematchWithin).
+			Find(&pairings).Error; err != nil {
+			return nil, err
+		}
+		for _, pairing := range pairings {
+			var pairs []Pair
+			if err := json.Unmarshal([]byte(pairing.PairsJSON), &pairs); err != nil {
+				return nil, err
+			}
+			for _, pair := range pairs {
+				if pair.Player2ID != "" {
+					ctx.meet(pair.Player1ID, pair.Player2ID)
+				}
+			}
+		}
+	}
+
+	if cons.SeparateTeams {
+		var members []models.TeamMember
+		if err := ps.DB.Select("team_id", "external_user_id").
+			Where("external_user_id IN ?", ids).Find(&members).Error; err != nil {
+			return nil, err
+		}
+		for _, m := range members {
+			ctx.teams[m.ExternalUserID] = append(ctx.teams[m.ExternalUserID], m.TeamID)
+		}
+	}
+
+	if cons.SeparateReferrals {
+		roots, err := ps.referralRoots(ids)
+		if err != nil {
+			return nil, err
+		}
+		ctx.roots = roots
+	}
+	return ctx, nil
+}
+
+func (ctx *constraintContext) meet(a, b string) {
+	if ctx.recent[a] == nil {
+		ctx.recent[a] = make(map[string]bool)
+	}
+	if ctx.recent[b] == nil {
+		ctx.recent[b] = make(map[string]bool)
+	}
+	ctx.recent[a][b] = true
+	ctx.recent[b][a] = true
+}
+
+// referralRoots walks each player's referrer chain up to its top; players with the same root
+// belong to the same referral tree.
+func (ps *PairingService) referralRoots(ids []string) (map[string]string, error) {
+	parent := make(map[string]string)
+	frontier := ids
+	for len(frontier) > 0 {
+		var refs []models.Referral
+		if err := ps.DB.Select("referrer_id", "referred_id").
+			Where("referred_id IN ?", frontier).Find(&refs).Error; err != nil {
+			return nil, err
+		}
+		frontier = nil
+		for _, r := range refs {
+			if _, seen := parent[r.ReferredID]; seen {
+				continue
+			}
+			parent[r.ReferredID] = r.ReferrerID
+			if _, seen := parent[r.ReferrerID]; !seen {
+				frontier = append(frontier, r.ReferrerID)
+			}
+		}
+	}
+
+	roots := make(map[string]string, len(ids))
+	for _, id := range ids {
+		root, visited := id, map[string]bool{id: true}
+		for {
+			next, ok := parent[root]
+			if !ok || visited[next] {
+				break
+			}
+			visited[next] = true
+			root = next
+		}
+		roots[id] = root
+	}
+	return roots, nil
+}
+
+// keepLayout makes the solver stay close to the generated pairs rather than to the player order,
+// for formats whose pairs are laid out by seed (brackets)
+func (ctx *constraintContext) keepLayout(pairs []Pair) {
+	ctx.layout = make(map[string]string, 2*len(pairs))
+	for _, p := range pairs {
+		ctx.layout[p.Player1ID] = p.Player2ID
+		ctx.layout[p.Player2ID] = p.Player1ID
+	}
+}
+
+// violations lists the constraints a pairing of a and b breaks in the given round of the match
+func (ctx *constraintContext) violations(a, b string, round int) []string {
+	if a == "" || b == "" {
+		return nil
+	}
+	var broken []string
+	if ctx.recent[a][b] {
+		broken = append(broken, ConstraintRematch)
+	}
+	if ctx.cons.SeparateTeams && sharesTeam(ctx.teams[a], ctx.teams[b]) {
+		broken = append(broken, ConstraintTeam)
+	}
+	if ctx.cons.SeparateReferrals && ctx.roots[a] != "" && ctx.roots[a] == ctx.roots[b] {
+		broken = append(broken, ConstraintReferral)
+	}
+	if ctx.cons.SeparateRegions && ctx.regions[a] != "" && ctx.regions[a] == ctx.regions[b] {
+		broken = append(broken, ConstraintRegion)
+	}
+	if ctx.cons.ProtectTopSeeds > 0 && round <= ctx.cons.ProtectRounds {
+		sa, sb := ctx.seeds[a], ctx.seeds[b]
+		if sa > 0 && sb > 0 && sa <= ctx.cons.ProtectTopSeeds && sb <= ctx.cons.ProtectTopSeeds {
+			broken = append(broken, ConstraintSeedProtection)
+		}
+	}
+	return broken
+}
+
+func sharesTeam(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cost scores a pair played in round as (hard violations, soft penalty)
+func (ctx *constraintContext) cost(a, b string, round int) (int, float64) {
+	if a == "" || b == "" {
+		return 0, 0
+	}
+	hard, soft := 0, 0.0
+	for _, name := range ctx.violations(a, b, round) {
+		if ctx.hard[name] {
+			hard++
+		} else {
+			soft += constraintWeights[name]
+		}
+	}
+	if ctx.layout != nil {
+		if ctx.layout[a] != b {
+			soft += constraintLayoutWeight
+		}
+		return hard, soft
+	}
+	diff := ctx.order[a] - ctx.order[b]
+	if diff < 0 {
+		diff = -diff
+	}
+	return hard, soft + constraintOrderWeight*float64(diff)
+}
+
+// pairSlot is one side of a pair
+type pairSlot struct {
+	id   string
+	name string
+	seed int
+}
+
+func (p *Pair) slot(n int) pairSlot {
+	if n == 1 {
+		return pairSlot{p.Player1ID, p.Player1Name, p.Player1Seed}
+	}
+	return pairSlot{p.Player2ID, p.Player2Name, p.Player2Seed}
+}
+
+func (p *Pair) setSlot(n int, s pairSlot) {
+	if n == 1 {
+		p.Player1ID, p.Player1Name, p.Player1Seed = s.id, s.name, s.seed
+		return
+	}
+	p.Player2ID, p.Player2Name, p.Player2Seed = s.id, s.name, s.seed
+}
+
+// solve repairs head-to-head pairs by swapping players between games of the same round until
+// no swap lowers the cost. Byes stay where the format put them.
+func (ctx *constraintContext) solve(pairs []Pair) []Pair {
+	pairCost := func(p Pair) (int, float64) { return ctx.cost(p.Player1ID, p.Player2ID, ctx.round) }
+	better := func(h1 int, s1 float64, h2 int, s2 float64) bool {
+		return h1 < h2 || (h1 == h2 && s1 < s2-1e-9)
+	}
+
+	for pass := 0; pass < maxConstraintPasses; pass++ {
+		improved := false
+		for i := range pairs {
+			for j := i + 1; j < len(pairs); j++ {
+				a, b := &pairs[i], &pairs[j]
+				if a.Player2ID == "" || b.Player2ID == "" || a.RoundNumber != b.RoundNumber {
+					continue
+				}
+				ha, sa := pairCost(*a)
+				hb, sb := pairCost(*b)
+				bestH, bestS, bestSide := ha+hb, sa+sb, 0
+				// Swap a's second player with either of b's players
+				for side := 1; side <= 2; side++ {
+					x, y := *a, *b
+					moved := x.slot(2)
+					x.setSlot(2, y.slot(side))
+					y.setSlot(side, moved)
+					hx, sx := pairCost(x)
+					hy, sy := pairCost(y)
+					if better(hx+hy, sx+sy, bestH, bestS) {
+						bestH, bestS, bestSide = hx+hy, sx+sy, side
+					}
+				}
+				if bestSide != 0 {
+					moved := a.slot(2)
+					a.setSlot(2, b.slot(bestSide))
+					b.setSlot(bestSide, moved)
+					improved = true
+				}
+			}
+		}
+		if !improved {
+			break
+		}
+	}
+	return pairs
+}
+
+// orderRounds reorders the whole rounds of a fixed schedule (round robin), where swapping
+// players between games would break who-meets-whom: each round slot takes the remaining round
+// that breaks the fewest constraints when played there. RoundID stays with the slot.
+func (ctx *constraintContext) orderRounds(pairs []Pair) []Pair {
+	byRound := make(map[int][]Pair)
+	roundIDs := make(map[int]string)
+	var slots []int
+	for _, p := range pairs {
+		if _, ok := byRound[p.RoundNumber]; !ok {
+			slots = append(slots, p.RoundNumber)
+			roundIDs[p.RoundNumber] = p.RoundID
+		}
+		byRound[p.RoundNumber] = append(byRound[p.RoundNumber], p)
+	}
+	sort.Ints(slots)
+
+	remaining := append([]int(nil), slots...)
+	ordered := make([]Pair, 0, len(pairs))
+	for _, slot := range slots {
+		best, bestH, bestS := 0, 0, 0.0
+		for i, r := range remaining {
+			h, s := 0, 0.0
+			for _, p := range byRound[r] {
+				ph, ps := ctx.cost(p.Player1ID, p.Player2ID, slot)
+				h, s = h+ph, s+ps
+			}
+			if i == 0 || h < bestH || (h == bestH && s < bestS-1e-9) {
+				best, bestH, bestS = i, h, s
+			}
+		}
+		for _, p := range byRound[remaining[best]] {
+			p.RoundNumber, p.RoundID = slot, roundIDs[slot]
+			ordered = append(ordered, p)
+		}
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+	for i := range ordered {
+		ordered[i].MatchNumber = i + 1
+	}
+	return ordered
+}
+
+// check lists every constraint the pairs still break. With perRound, each pair is checked in its
+// own RoundNumber (a round robin lays out every round at once).
+func (ctx *constraintContext) check(pairs []Pair, perRound bool) []ConstraintViolation {
+	var out []ConstraintViolation
+	for _, p := range pairs {
+		round := ctx.round
+		if perRound {
+			round = p.RoundNumber
+		}
+		for _, name := range ctx.violations(p.Player1ID, p.Player2ID, round) {
+			out = append(out, ConstraintViolation{
+				MatchNumber: p.MatchNumber,
+				Player1ID:   p.Player1ID,
+				Player2ID:   p.Player2ID,
+				Constraint:  name,
+				Hard:        ctx.hard[name],
+			})
+		}
+	}
+	return out
+}
+
+// hardViolationError summarises the hard constraints a proposal breaks, or nil
+func hardViolationError(violations []ConstraintViolation) error {
+	var broken []string
+	for _, v := range violations {
+		if v.Hard {
+			broken = append(broken, fmt.Sprintf("match %d: %s vs %s (%s)", v.MatchNumber, v.Player1ID, v.Player2ID, v.Constraint))
+		}
+	}
+	if len(broken) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrConstraintsViolated, strings.Join(broken, "; "))
+}
+
+// pairPlayers lists everyone seated in a pair: both sides, or every lobby seat
+func pairPlayers(p Pair) []string {
+	if len(p.Players) > 0 {
+		ids := make([]string, len(p.Players))
+		for i, seat := range p.Players {
+			ids[i] = seat.UserID
+		}
+		return ids
+	}
+	var ids []string
+	if p.Player1ID != "" {
+		ids = append(ids, p.Player1ID)
+	}
+	if p.Player2ID != "" {
+		ids = append(ids, p.Player2ID)
+	}
+	return ids
+}
+
+// validatePairs rejects proposals where a player is seated twice in a round, an eligible
+// player is left out of a round, or someone who isn't eligible is seated. Pairs are grouped
+// by RoundNumber, so a round robin seats everyone once per round. An empty proposal (large
+// leaderboard matches play without pairs) passes.
+func validatePairs(pairs []Pair, eligible []models.TournamentSubscription) error {
+	if len(pairs) == 0 {
+		return nil
+	}
+	allowed := make(map[string]bool, len(eligible))
+	for _, p := range eligible {
+		allowed[p.ExternalUserID] = true
+	}
+
+	rounds := make(map[int]map[string]bool)
+	for _, pair := range pairs {
+		seated := rounds[pair.RoundNumber]
+		if seated == nil {
+			seated = make(map[string]bool)
+			rounds[pair.RoundNumber] = seated
+		}
+		for _, id := range pairPlayers(pair) {
+			if !allowed[id] {
+				return fmt.Errorf("%w: %s", ErrUnknownPairedPlayer, id)
+			}
+			if seated[id] {
+				return fmt.Errorf("%w: %s (match %d)", ErrDuplicatePairedPlayer, id, pair.MatchNumber)
+			}
+			seated[id] = true
+		}
+	}
+
+	roundNumbers := make([]int, 0, len(rounds))
+	for n := range rounds {
+		roundNumbers = append(roundNumbers, n)
+	}
+	sort.Ints(roundNumbers)
+	for _, n := range roundNumbers {
+		for _, p := range eligible {
+			if !rounds[n][p.ExternalUserID] {
+				if n == 0 {
+					return fmt.Errorf("%w: %s", ErrMissingPairedPlayer, p.ExternalUserID)
+				}
+				return fmt.Errorf("%w: %s (round %d)", ErrMissingPairedPlayer, p.ExternalUserID, n)
+			}
+		}
+	}
+	return nil
+}
+
+// isPairValidationError reports whether err came from validatePairs or the constraints
+func isPairValidationError(err error) bool {
+	return errors.Is(err, ErrDuplicatePairedPlayer) || errors.Is(err, ErrMissingPairedPlayer) ||
+		errors.Is(err, ErrUnknownPairedPlayer) || errors.Is(err, ErrUnknownConstraint) || errors.Is(err, ErrConstraintsFormat)
+}
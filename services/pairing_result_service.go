@@ -0,0 +1,180 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPairingNotPublished = errors.New("pairing is not published")
+	ErrPairNotFound        = errors.New("pair not found in pairing")
+	ErrPairIsBye           = errors.New("a bye has no result to report")
+	ErrInvalidPairWinner   = errors.New("winner must be one of the pair's players")
+)
+
+// findPair returns the pair with the given match number from a pairing
+func findPair(pairing *models.MatchPairing, matchNumber int) (*Pair, error) {
+	var pairs []Pair
+	if err := json.Unmarshal([]byte(pairing.PairsJSON), &pairs); err != nil {
+		return nil, err
+	}
+	for i := range pairs {
+		if pairs[i].MatchNumber == matchNumber {
+			return &pairs[i], nil
+		}
+	}
+	return nil, ErrPairNotFound
+}
+
+// pairOutcome converts a winner (or a draw) into a PairingResult outcome
+func pairOutcome(pair *Pair, winnerID string, draw bool) (string, error) {
+	switch {
+	case draw:
+		return models.PairingOutcomeDraw, nil
+	case winnerID == pair.Player1ID:
+		return models.PairingOutcomePlayer1, nil
+	case winnerID == pair.Player2ID:
+		return models.PairingOutcomePlayer2, nil
+	default:
+		return "", ErrInvalidPairWinner
+	}
+}
+
+// recordPairingResult stores the confirmed outcome of one published pair and rates it. It must
+// run inside a transaction so the rating event and the locked ratings commit together.
+// Corrections overwrite the stored outcome but do not re-rate: Glicko-2 updates cannot be undone
+// once later games have been rated on top of them, so ratings keep the first confirmed outcome.
+func recordPairingResult(tx *gorm.DB, pairing *models.MatchPairing, pair *Pair, outcome string, score1, score2 int64, recordedBy string) (*models.PairingResult, error) {
+	if pairing.Status != "published" {
+		return nil, ErrPairingNotPublished
+	}
+	if pair.Player2ID == "" {
+		return nil, ErrPairIsBye
+	}
+
+	result := models.PairingResult{
+		ID:           uuid.NewString(),
+		PairingID:    pairing.ID,
+		MatchNumber:  pair.MatchNumber,
+		MatchID:      pairing.MatchID,
+		TournamentID: pairing.TournamentID,
+		RoundNumber:  pair.RoundNumber,
+		Player1ID:    pair.Player1ID,
+		Player2ID:    pair.Player2ID,
+		Outcome:      outcome,
+		Player1Score: score1,
+		Player2Score: score2,
+		Status:       "confirmed",
+		RecordedBy:   recordedBy,
+	}
+	switch outcome {
+	case models.PairingOutcomePlayer1:
+		result.WinnerID = pair.Player1ID
+	case models.PairingOutcomePlayer2:
+		result.WinnerID = pair.Player2ID
+	}
+
+	var previous models.PairingResult
+	err := tx.Where("pairing_id = ? AND match_number = ?", pairing.ID, pair.MatchNumber).First(&previous).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if err == nil && previous.Outcome != outcome {
+		log.Printf("⚠️ Pairing %s match %d corrected from %s to %s; ratings keep the original outcome",
+			pairing.ID, pair.MatchNumber, previous.Outcome, outcome)
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "pairing_id"}, {Name: "match_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"outcome", "winner_id", "player1_score", "player2_score", "status", "recorded_by", "updated_at"}),
+	}).Create(&result).Error; err != nil {
+		return nil, err
+	}
+
+	sourceKey := fmt.Sprintf("pair:%s:%d", pairing.ID, pair.MatchNumber)
+	if err := applyRatedGames(tx, sourceKey, pairing.TournamentID, []ratedGame{{a: pair.Player1ID, b: pair.Player2ID, scoreA: outcomeScore(outcome)}}); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RecordPairingResult lets an admin enter or correct the result of a published pair (corrections
+// do not change ratings, see recordPairingResult)
+func (ps *PairingService) RecordPairingResult(c *fiber.Ctx) error {
+	pairingID := c.Params("pairing_id")
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		MatchNumber  int    `json:"match_number"`
+		WinnerID     string `json:"winner_id"`
+		Draw         bool   `json:"draw"`
+		Player1Score int64  `json:"player1_score"`
+		Player2Score int64  `json:"player2_score"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+
+	var pairing models.MatchPairing
+	if err := ps.DB.First(&pairing, "id = ?", pairingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "pairing not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+
+	pair, err := findPair(&pairing, req.MatchNumber)
+	if err != nil {
+		return pairingResultErrorResponse(c, err)
+	}
+	outcome, err := pairOutcome(pair, req.WinnerID, req.Draw)
+	if err != nil {
+		return pairingResultErrorResponse(c, err)
+	}
+
+	var result *models.PairingResult
+	err = ps.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		result, err = recordPairingResult(tx, &pairing, pair, outcome, req.Player1Score, req.Player2Score, userID)
+		return err
+	})
+	if err != nil {
+		return pairingResultErrorResponse(c, err)
+	}
+
+	log.Printf("✅ Result recorded for pairing %s match %d: %s", pairing.ID, pair.MatchNumber, outcome)
+	return c.JSON(result)
+}
+
+// GetPairingResults lists recorded results for a pairing
+func (ps *PairingService) GetPairingResults(c *fiber.Ctx) error {
+	var results []models.PairingResult
+	if err := ps.DB.Where("pairing_id = ?", c.Params("pairing_id")).
+		Order("match_number ASC").
+		Find(&results).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+	return c.JSON(fiber.Map{"pairing_id": c.Params("pairing_id"), "results": results, "count": len(results)})
+}
+
+// pairingResultErrorResponse maps result errors to HTTP responses
+func pairingResultErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrPairNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrInvalidPairWinner), errors.Is(err, ErrPairIsBye):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrPairingNotPublished):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "failed to record result", "details": err.Error()})
+	}
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"game-publish-system/models"
 	"log"
 	"math/rand"
@@ -29,9 +30,14 @@ type Pair struct {
 	Player1Name string `json:"player1_name"`
 	Player2ID   string `json:"player2_id"`
 	Player2Name string `json:"player2_name"`
+	Player1Seed int    `json:"player1_seed,omitempty"`
+	Player2Seed int    `json:"player2_seed,omitempty"` // empty Player2ID = bye
 	MatchNumber int    `json:"match_number"`
 	TableNumber int    `json:"table_number,omitempty"`
 	RoundNumber int    `json:"round_number,omitempty"`
+	RoundID     string `json:"round_id,omitempty"` // TournamentRound the game is scheduled in
+
+	Players []LobbySeat `json:"players,omitempty"` // FREE_FOR_ALL_LOBBY: the pair is a lobby of N players
 }
 
 // PairingRequest represents a request to generate pairings
@@ -41,6 +47,10 @@ type PairingRequest struct {
 	SeedingMethod string `json:"seeding_method" validate:"oneof=RANDOM RANK_BASED SKILL_BASED CUSTOM"`
 	CustomPairs   []Pair `json:"custom_pairs,omitempty"`
 	ForceRegenerate bool `json:"force_regenerate"`
+	DoubleRoundRobin bool `json:"double_round_robin"` // ROUND_ROBIN_1V1: everyone meets twice, home and away
+	LobbySize     int `json:"lobby_size,omitempty"`    // FREE_FOR_ALL_LOBBY: players per lobby
+	AdvanceCount  int `json:"advance_count,omitempty"` // FREE_FOR_ALL_LOBBY: top K of each lobby advance
+	Constraints   *PairingConstraints `json:"constraints,omitempty"` // head-to-head formats only
 }
 
 // PairingResponse represents the response from pairing generation
@@ -66,6 +76,12 @@ func (ps *PairingService) GeneratePairings(c *fiber.Ctx) error {
 
 	// Get user ID from context (assuming middleware sets it)
 	userID := c.Locals("user_id").(string)
+
+	if req.Constraints != nil {
+		if err := req.Constraints.validate(); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+	}
 	
 	// Fetch match details with batch information
 	var match models.TournamentMatch
@@ -90,13 +106,16 @@ func (ps *PairingService) GeneratePairings(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch tournament"})
 	}
 
-	// Get eligible players for this tournament
-	players, err := ps.getEligiblePlayerList(tournament.ID)
+	// Get eligible players for this match
+	players, err := ps.matchPlayers(tournament, match)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch eligible players"})
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch eligible players", "details": err.Error()})
 	}
 
 	if len(players) < 2 {
+		if tournament.IsTeamBased {
+			return c.Status(400).JSON(fiber.Map{"error": "not enough teams for pairing"})
+		}
 		return c.Status(400).JSON(fiber.Map{"error": "not enough players for pairing"})
 	}
 
@@ -106,7 +125,7 @@ func (ps *PairingService) GeneratePairings(c *fiber.Ctx) error {
 	
 	switch req.PairingType {
 	case "AUTO":
-		pairs, metadata, err = ps.generateAutoPairings(match, players, req.SeedingMethod)
+		pairs, metadata, err = ps.generateAutoPairings(match, players, req)
 	case "MANUAL":
 		if len(req.CustomPairs) == 0 {
 			return c.Status(400).JSON(fiber.Map{"error": "custom pairs required for manual pairing"})
@@ -119,7 +138,7 @@ func (ps *PairingService) GeneratePairings(c *fiber.Ctx) error {
 		}
 	case "HYBRID":
 		// Start with auto pairings, allow manual adjustments
-		pairs, metadata, err = ps.generateAutoPairings(match, players, req.SeedingMethod)
+		pairs, metadata, err = ps.generateAutoPairings(match, players, req)
 		if err == nil && len(req.CustomPairs) > 0 {
 			// Apply manual overrides
 			pairs = ps.applyManualOverrides(pairs, req.CustomPairs)
@@ -128,7 +147,25 @@ func (ps *PairingService) GeneratePairings(c *fiber.Ctx) error {
 		}
 	}
 	
+	if err == nil && metadata != nil && tournament.IsTeamBased {
+		metadata["team_based"] = true
+	}
+	if err == nil && metadata != nil && (tournament.CheckInRequired || match.CheckInRequired) {
+		metadata["checked_in_only"] = true
+	}
+
+	// Every eligible player is seated exactly once per round
+	if err == nil {
+		err = validatePairs(pairs, players)
+	}
+	
 	if err != nil {
+		if errors.Is(err, ErrSwissRoundIncomplete) || errors.Is(err, ErrConstraintsViolated) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, ErrInvalidLobbySettings) || errors.Is(err, ErrUnknownMatchType) || isPairValidationError(err) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to generate pairings", "details": err.Error()})
 	}
 
@@ -184,9 +221,23 @@ func (ps *PairingService) GeneratePairings(c *fiber.Ctx) error {
 
 // getEligiblePlayerList fetches eligible players for a tournament
 func (ps *PairingService) getEligiblePlayerList(tournamentID string) ([]models.TournamentSubscription, error) {
+	players, err := ps.eligibleSubscriptions(tournamentID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Team entries are paired as the team itself, not the captain who subscribed it
+	for i := range players {
+		players[i].ExternalUserID = entrantID(players[i])
+	}
+	return players, nil
+}
+
+// eligibleSubscriptions fetches the subscriptions that can be paired, as stored
+func (ps *PairingService) eligibleSubscriptions(tournamentID string) ([]models.TournamentSubscription, error) {
 	var players []models.TournamentSubscription
 	
-	query := ps.DB.Where("tournament_id = ? AND payment_status IN ('paid', 'pending')", tournamentID).
+	query := ps.DB.Where("tournament_id = ? AND payment_status IN ? AND forfeited_at IS NULL", tournamentID, activeSubscriptionStatuses).
 		Order("joined_at ASC")
 	
 	if err := query.Find(&players).Error; err != nil {
@@ -195,9 +246,32 @@ func (ps *PairingService) getEligiblePlayerList(tournamentID string) ([]models.T
 	return players, nil
 }
 
-// generateAutoPairings generates automatic pairings based on match type
+// matchPlayers lists who a match is paired from: eligible subscribers, narrowed to the
+// players who qualified from an earlier match (if any did) and to those checked in
+func (ps *PairingService) matchPlayers(tournament models.Tournament, match models.TournamentMatch) ([]models.TournamentSubscription, error) {
+	players, err := ps.getEligiblePlayerList(tournament.ID)
+	if err != nil {
+		return nil, err
+	}
+	players, err = ps.filterMatchEntrants(match.ID, players)
+	if err != nil {
+		return nil, err
+	}
+	return ps.filterCheckedIn(tournament, match, players)
+}
+
+// generateAutoPairings generates automatic pairings driven by the match type's config
 func (ps *PairingService) generateAutoPairings(match models.TournamentMatch, 
-	players []models.TournamentSubscription, seedingMethod string) ([]Pair, map[string]interface{}, error) {
+	players []models.TournamentSubscription, req PairingRequest) ([]Pair, map[string]interface{}, error) {
+	cfg, err := loadMatchTypeConfig(ps.DB, match.MatchType)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seedingMethod := req.SeedingMethod
+	if seedingMethod == "" {
+		seedingMethod = cfg.SeedingMethod
+	}
 	
 	// Get tournament ID through batch
 	var batch models.TournamentBatch
@@ -222,22 +296,70 @@ func (ps *PairingService) generateAutoPairings(match models.TournamentMatch,
 	var pairs []Pair
 	var metadata map[string]interface{}
 	
-	switch match.MatchType {
-	case "SINGLE_ELIMINATION_1V1", "DOUBLE_ELIMINATION_1V1":
-		pairs, metadata = ps.generateEliminationPairs(sortedPlayers, match.MatchType)
-	case "ROUND_ROBIN_1V1":
-		pairs, metadata = ps.generateRoundRobinPairs(sortedPlayers)
-	case "LEADERBOARD_CHALLENGE":
+	switch cfg.ProgressionType {
+	case models.ProgressionSingleElimination, models.ProgressionDoubleElimination:
+		pairs, metadata = ps.generateEliminationPairs(sortedPlayers, cfg.ProgressionType)
+	case models.ProgressionRoundRobin:
+		rounds, err := ps.ensureRoundRobinRounds(match, batch.TournamentID, roundRobinRoundCount(len(sortedPlayers), req.DoubleRoundRobin))
+		if err != nil {
+			return nil, nil, err
+		}
+		pairs, metadata = ps.generateRoundRobinPairs(sortedPlayers, rounds, req.DoubleRoundRobin)
+	case models.ProgressionLeaderboard:
 		pairs, metadata = ps.generateLeaderboardPairs(sortedPlayers)
-	case "SWISS_SYSTEM":
-		pairs, metadata = ps.generateSwissPairs(sortedPlayers, 1) // First round
+	case models.ProgressionSwiss:
+		history, err := ps.loadSwissHistory(match.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if history.unreported > 0 {
+			return nil, nil, fmt.Errorf("%w (%d games)", ErrSwissRoundIncomplete, history.unreported)
+		}
+		pairs, metadata = ps.generateSwissPairs(sortedPlayers, history)
+	case models.ProgressionLobbies:
+		lobbySize, advance := lobbySettings(cfg, req)
+		pairs, metadata, err = ps.generateLobbyPairs(sortedPlayers, seedingMap, lobbySize, advance)
+		if err != nil {
+			return nil, nil, err
+		}
 	default:
 		// Default to simple pairing
 		pairs, metadata = ps.generateSimplePairs(sortedPlayers)
 	}
+
+	// Swiss and simple pairs are reshuffled around the constraints, bracket lines only move where
+	// a constraint asks for it, and a round robin (everyone meets anyway) reorders its rounds.
+	// Lobbies and leaderboards have no opponents to constrain.
+	if req.Constraints != nil {
+		if cfg.IsLobbies() || cfg.ProgressionType == models.ProgressionLeaderboard {
+			return nil, nil, fmt.Errorf("%w (%s)", ErrConstraintsFormat, cfg.ProgressionType)
+		}
+		ctx, err := ps.loadConstraintContext(*req.Constraints, batch.TournamentID, match.ID, sortedPlayers, seedingMap)
+		if err != nil {
+			return nil, nil, err
+		}
+		roundRobin := cfg.ProgressionType == models.ProgressionRoundRobin
+		switch {
+		case roundRobin:
+			pairs = ctx.orderRounds(pairs)
+		case cfg.IsElimination():
+			ctx.keepLayout(pairs)
+			pairs = ctx.solve(pairs)
+		default:
+			pairs = ctx.solve(pairs)
+		}
+		violations := ctx.check(pairs, roundRobin)
+		if err := hardViolationError(violations); err != nil {
+			return nil, nil, err
+		}
+		metadata["constraints"] = req.Constraints
+		metadata["constraint_violations"] = violations
+	}
 	
 	metadata["seeding_method"] = seedingMethod
 	metadata["match_type"] = match.MatchType
+	metadata["progression_type"] = cfg.ProgressionType
+	metadata["advancement_rule"] = cfg.AdvancementRule
 	metadata["player_count"] = len(sortedPlayers)
 	metadata["pair_count"] = len(pairs)
 	
@@ -297,75 +419,41 @@ func (ps *PairingService) sortPlayersBySeeding(players []models.TournamentSubscr
 	return players
 }
 
-// generateEliminationPairs generates pairs for elimination brackets
-func (ps *PairingService) generateEliminationPairs(players []models.TournamentSubscription, matchType string) ([]Pair, map[string]interface{}) {
+// generateEliminationPairs generates the first bracket round using the standard seed
+// layout. The field is padded to a power of two; top seeds get the byes (Player2 empty).
+func (ps *PairingService) generateEliminationPairs(players []models.TournamentSubscription, progression string) ([]Pair, map[string]interface{}) {
 	var pairs []Pair
 	n := len(players)
-	
-	// For odd number of players, create a bye
-	hasBye := n%2 != 0
-	
-	// Seed 1 vs Seed n, Seed 2 vs Seed n-1, etc.
-	for i := 0; i < n/2; i++ {
-		p1 := players[i]
-		p2 := players[n-i-1]
-		
-		pairs = append(pairs, Pair{
+	size := nextPowerOfTwo(n)
+	order := bracketSeedOrder(size)
+
+	for i := 0; i < size/2; i++ {
+		seedA, seedB := order[2*i], order[2*i+1]
+		if seedA > seedB {
+			seedA, seedB = seedB, seedA
+		}
+		if seedA > n {
+			continue // bye vs bye never happens with the standard layout
+		}
+
+		p1 := players[seedA-1]
+		pair := Pair{
 			Player1ID:   p1.ExternalUserID,
 			Player1Name: p1.UserName,
-			Player2ID:   p2.ExternalUserID,
-			Player2Name: p2.UserName,
+			Player1Seed: seedA,
 			MatchNumber: i + 1,
 			RoundNumber: 1,
-		})
-	}
-	
-	metadata := map[string]interface{}{
-		"bracket_type":    matchType,
-		"total_players":   n,
-		"has_bye":         hasBye,
-		"bye_player":      nil,
-	}
-	
-	if hasBye && n > 0 {
-		byePlayer := players[n/2]
-		metadata["bye_player"] = map[string]interface{}{
-			"id":   byePlayer.ExternalUserID,
-			"name": byePlayer.UserName,
 		}
-	}
-	
-	return pairs, metadata
-}
-
-// generateRoundRobinPairs generates all possible pairs for round robin
-func (ps *PairingService) generateRoundRobinPairs(players []models.TournamentSubscription) ([]Pair, map[string]interface{}) {
-	var pairs []Pair
-	n := len(players)
-	matchNum := 1
-	
-	// Generate all unique pairs
-	for i := 0; i < n; i++ {
-		for j := i + 1; j < n; j++ {
-			pairs = append(pairs, Pair{
-				Player1ID:   players[i].ExternalUserID,
-				Player1Name: players[i].UserName,
-				Player2ID:   players[j].ExternalUserID,
-				Player2Name: players[j].UserName,
-				MatchNumber: matchNum,
-			})
-			matchNum++
+		if seedB <= n {
+			p2 := players[seedB-1]
+			pair.Player2ID = p2.ExternalUserID
+			pair.Player2Name = p2.UserName
+			pair.Player2Seed = seedB
 		}
+		pairs = append(pairs, pair)
 	}
-	
-	metadata := map[string]interface{}{
-		"format":        "round_robin",
-		"total_players": n,
-		"total_matches": len(pairs),
-		"rounds_needed": n - 1 + n%2,
-	}
-	
-	return pairs, metadata
+
+	return pairs, describeBracketPairs(pairs, n, progression)
 }
 
 // generateLeaderboardPairs creates initial pairings for leaderboard (all play simultaneously)
@@ -377,7 +465,7 @@ func (ps *PairingService) generateLeaderboardPairs(players []models.TournamentSu
 	// Or create round robin style if needed
 	if n <= 8 {
 		// For small groups, create round robin
-		return ps.generateRoundRobinPairs(players)
+		return ps.generateRoundRobinPairs(players, nil, false)
 	}
 	
 	// For large groups, create groups
@@ -391,39 +479,6 @@ func (ps *PairingService) generateLeaderboardPairs(players []models.TournamentSu
 	return pairs, metadata
 }
 
-// generateSwissPairs generates Swiss system pairings
-func (ps *PairingService) generateSwissPairs(players []models.TournamentSubscription, round int) ([]Pair, map[string]interface{}) {
-	var pairs []Pair
-	n := len(players)
-	
-	// Sort by current score (for later rounds) or seeding (for first round)
-	// This is simplified - in reality you'd track scores from previous rounds
-	
-	// Pair top half with bottom half
-	for i := 0; i < n/2; i++ {
-		p1 := players[i]
-		p2 := players[n/2+i]
-		
-		pairs = append(pairs, Pair{
-			Player1ID:   p1.ExternalUserID,
-			Player1Name: p1.UserName,
-			Player2ID:   p2.ExternalUserID,
-			Player2Name: p2.UserName,
-			MatchNumber: i + 1,
-			RoundNumber: round,
-		})
-	}
-	
-	metadata := map[string]interface{}{
-		"format":        "swiss",
-		"round":         round,
-		"total_players": n,
-		"pairing_rule":  "top_vs_bottom",
-	}
-	
-	return pairs, metadata
-}
-
 // generateSimplePairs creates simple sequential pairings
 func (ps *PairingService) generateSimplePairs(players []models.TournamentSubscription) ([]Pair, map[string]interface{}) {
 	var pairs []Pair
@@ -441,6 +496,13 @@ func (ps *PairingService) generateSimplePairs(players []models.TournamentSubscri
 				Player2Name: p2.UserName,
 				MatchNumber: i/2 + 1,
 			})
+		} else {
+			// Odd one out gets a bye
+			pairs = append(pairs, Pair{
+				Player1ID:   players[i].ExternalUserID,
+				Player1Name: players[i].UserName,
+				MatchNumber: i/2 + 1,
+			})
 		}
 	}
 	
@@ -455,26 +517,68 @@ func (ps *PairingService) generateSimplePairs(players []models.TournamentSubscri
 
 // applyManualOverrides applies manual adjustments to auto-generated pairs
 func (ps *PairingService) applyManualOverrides(autoPairs []Pair, manualPairs []Pair) []Pair {
-	// Create a map of match numbers to manual pairs
-	manualMap := make(map[int]Pair)
+	// Players placed by hand are taken out of whichever auto pair they were in
+	manualNumbers := make(map[int]bool, len(manualPairs))
+	manualPlayers := make(map[string]bool)
 	for _, mp := range manualPairs {
-		manualMap[mp.MatchNumber] = mp
+		manualNumbers[mp.MatchNumber] = true
+		for _, id := range pairPlayers(mp) {
+			manualPlayers[id] = true
+		}
 	}
-	
-	// Apply manual overrides
-	for i, pair := range autoPairs {
-		if manualPair, exists := manualMap[pair.MatchNumber]; exists {
-			autoPairs[i] = manualPair
-			delete(manualMap, pair.MatchNumber)
+
+	result := make([]Pair, 0, len(autoPairs)+len(manualPairs))
+	displaced := make(map[int][]pairSlot) // round number -> players left without an opponent
+	roundIDs := make(map[int]string)
+	maxNumber := 0
+	for _, pair := range autoPairs {
+		if pair.MatchNumber > maxNumber {
+			maxNumber = pair.MatchNumber
+		}
+		roundIDs[pair.RoundNumber] = pair.RoundID
+		replaced := manualNumbers[pair.MatchNumber]
+		if !replaced && !manualPlayers[pair.Player1ID] && !manualPlayers[pair.Player2ID] {
+			result = append(result, pair)
+			continue
+		}
+		if len(pair.Players) > 0 {
+			continue // lobbies are replaced whole
+		}
+		for side := 1; side <= 2; side++ {
+			if slot := pair.slot(side); slot.id != "" && !manualPlayers[slot.id] {
+				displaced[pair.RoundNumber] = append(displaced[pair.RoundNumber], slot)
+			}
 		}
 	}
-	
-	// Add any new manual pairs
-	for _, manualPair := range manualMap {
-		autoPairs = append(autoPairs, manualPair)
+
+	for _, mp := range manualPairs {
+		if mp.MatchNumber > maxNumber {
+			maxNumber = mp.MatchNumber
+		}
+		result = append(result, mp)
+	}
+
+	// Re-pair displaced players with each other, in the order they were generated
+	rounds := make([]int, 0, len(displaced))
+	for n := range displaced {
+		rounds = append(rounds, n)
+	}
+	sort.Ints(rounds)
+	for _, n := range rounds {
+		slots := displaced[n]
+		for i := 0; i < len(slots); i += 2 {
+			maxNumber++
+			pair := Pair{MatchNumber: maxNumber, RoundNumber: n, RoundID: roundIDs[n]}
+			pair.setSlot(1, slots[i])
+			if i+1 < len(slots) {
+				pair.setSlot(2, slots[i+1])
+			}
+			result = append(result, pair)
+		}
 	}
-	
-	return autoPairs
+
+	sort.SliceStable(result, func(i, j int) bool { return result[i].MatchNumber < result[j].MatchNumber })
+	return result
 }
 
 // UpdatePairings allows editing of proposed pairings
@@ -504,6 +608,22 @@ func (ps *PairingService) UpdatePairings(c *fiber.Ctx) error {
 	if pairing.Status != "proposed" && pairing.Status != "pending" {
 		return c.Status(400).JSON(fiber.Map{"error": "pairing cannot be edited in current status"})
 	}
+
+	var tournament models.Tournament
+	var match models.TournamentMatch
+	if err := ps.DB.First(&tournament, "id = ?", pairing.TournamentID).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch tournament"})
+	}
+	if err := ps.DB.First(&match, "id = ?", pairing.MatchID).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch match"})
+	}
+	players, err := ps.matchPlayers(tournament, match)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch eligible players", "details": err.Error()})
+	}
+	if err := validatePairs(req.Pairs, players); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
 	
 	// Update pairs
 	pairsJSON, _ := json.Marshal(req.Pairs)
@@ -591,7 +711,11 @@ func (ps *PairingService) ApprovePairings(c *fiber.Ctx) error {
 	
 	// If finalize is true, also publish
 	if req.Finalize {
-		return ps.publishPairingsInternal(pairing.ID, userID)
+		resp, err := ps.publishPairingsInternal(pairing.ID, userID)
+		if err != nil {
+			return err
+		}
+		return c.JSON(resp)
 	}
 	
 	return c.JSON(fiber.Map{
@@ -607,55 +731,86 @@ func (ps *PairingService) PublishPairings(c *fiber.Ctx) error {
 	pairingID := c.Params("pairing_id")
 	userID := c.Locals("user_id").(string)
 	
-	return ps.publishPairingsInternal(pairingID, userID)
+	resp, err := ps.publishPairingsInternal(pairingID, userID)
+	if err != nil {
+		return err
+	}
+	return c.JSON(resp)
 }
 
-func (ps *PairingService) publishPairingsInternal(pairingID string, userID string) error {
+func (ps *PairingService) publishPairingsInternal(pairingID string, userID string) (fiber.Map, error) {
 	// Fetch pairing
 	var pairing models.MatchPairing
 	if err := ps.DB.First(&pairing, "id = ?", pairingID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fiber.NewError(404, "pairing not found")
+			return nil, fiber.NewError(404, "pairing not found")
 		}
-		return fiber.NewError(500, "database error")
+		return nil, fiber.NewError(500, "database error")
 	}
 	
 	// Check if pairing can be published
 	if pairing.Status != "approved" {
-		return fiber.NewError(400, "pairing cannot be published in current status")
+		return nil, fiber.NewError(400, "pairing cannot be published in current status")
 	}
 	
 	// Parse pairs
 	var pairs []Pair
 	if err := json.Unmarshal([]byte(pairing.PairsJSON), &pairs); err != nil {
-		return fiber.NewError(500, "failed to parse pairing data")
+		return nil, fiber.NewError(500, "failed to parse pairing data")
 	}
-	
-	// Update match with actual player assignments
-	for _, pair := range pairs {
-		// Update the match record with player assignments
-		// This would depend on your match structure
-		// For 1v1 matches, update player1 and player2 fields
-		// For team matches, update differently
-		// Note: Since we removed player fields from TournamentMatch,
-		// this logic needs to be updated based on your new design
-		log.Printf("Would assign players to match: %s vs %s", pair.Player1ID, pair.Player2ID)
+
+	var match models.TournamentMatch
+	if err := ps.DB.Select("id", "match_type").First(&match, "id = ?", pairing.MatchID).Error; err != nil {
+		return nil, fiber.NewError(500, "failed to fetch match")
+	}
+	cfg, err := loadMatchTypeConfig(ps.DB, match.MatchType)
+	if err != nil {
+		return nil, fiber.NewError(400, err.Error())
 	}
 	
-	// Update pairing status
 	now := time.Now()
-	updates := map[string]interface{}{
+	resp := fiber.Map{
+		"message":      "pairings published successfully",
+		"pairing_id":   pairing.ID,
 		"status":       "published",
-		"published_by": userID,
-		"published_at": &now,
+		"published_at": now,
 	}
-	
-	if err := ps.DB.Model(&pairing).Updates(updates).Error; err != nil {
+
+	err = ps.DB.Transaction(func(tx *gorm.DB) error {
+		// Elimination matches are played out on a persisted bracket
+		if cfg.IsElimination() {
+			bracket, err := publishBracket(tx, &pairing, cfg.ProgressionType, pairs)
+			if err != nil {
+				return err
+			}
+			resp["bracket"] = bracket
+		}
+		// Free-for-all matches are played in lobbies
+		if cfg.IsLobbies() {
+			lobbies, err := publishLobbies(tx, &pairing, pairs)
+			if err != nil {
+				return err
+			}
+			resp["lobbies"] = lobbies
+		}
+
+		// Update pairing status
+		updates := map[string]interface{}{
+			"status":       "published",
+			"published_by": userID,
+			"published_at": &now,
+		}
+		return tx.Model(&pairing).Updates(updates).Error
+	})
+	if err != nil {
 		log.Printf("DB Error publishing pairing: %v", err)
-		return fiber.NewError(500, "failed to publish pairing")
+		if errors.Is(err, ErrBracketHasResults) || errors.Is(err, ErrLobbyHasResults) {
+			return nil, fiber.NewError(409, err.Error())
+		}
+		return nil, fiber.NewError(500, "failed to publish pairing")
 	}
 	
-	return nil
+	return resp, nil
 }
 
 func (s *PairingService) GetPairingStatus(c *fiber.Ctx) error {
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CardPaymentAdapter verifies a charge against a card processor's REST API. It expects
+// GET {BaseURL}/v1/charges/{id} to return the charge with its amount in minor units of its
+// currency; any processor can sit behind it through a thin proxy that speaks this shape. Charges
+// are created with the subscriber as customer, or with user_id / subscription_id metadata.
+type CardPaymentAdapter struct {
+	BaseURL         string
+	APIKey          string
+	MerchantAccount string // charges must settle to this account
+	HTTPClient      *http.Client
+}
+
+func NewCardPaymentAdapter(baseURL, apiKey, merchantAccount string) *CardPaymentAdapter {
+	return &CardPaymentAdapter{
+		BaseURL:         strings.TrimRight(baseURL, "/"),
+		APIKey:          apiKey,
+		MerchantAccount: merchantAccount,
+		HTTPClient:      &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (a *CardPaymentAdapter) Name() string { return "card" }
+
+type cardCharge struct {
+	ID              string            `json:"id"`
+	Status          string            `json:"status"` // pending | succeeded | failed | refunded
+	Amount          int64             `json:"amount"`
+	Currency        string            `json:"currency"`
+	MerchantAccount string            `json:"merchant_account"`
+	Customer        string            `json:"customer"`
+	Metadata        map[string]string `json:"metadata"`
+	CapturedAt      *int64            `json:"captured_at"`
+}
+
+// paidBy reports whether the charge was made for claim's subscriber or subscription
+func (charge cardCharge) paidBy(claim PaymentClaim) bool {
+	if claim.SubscriptionID != "" && charge.Metadata["subscription_id"] == claim.SubscriptionID {
+		return true
+	}
+	return claim.UserID != "" && (charge.Customer == claim.UserID || charge.Metadata["user_id"] == claim.UserID)
+}
+
+// Verify checks the charge succeeded, settled to MerchantAccount, covers claim.Amount in
+// claim.Currency, and was made for the subscriber after they subscribed
+func (a *CardPaymentAdapter) Verify(ctx context.Context, claim PaymentClaim) (*PaymentVerification, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		fmt.Sprintf("%s/v1/charges/%s", a.BaseURL, url.PathEscape(claim.Reference)), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.APIKey)
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("card processor: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrPaymentNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("card processor returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var charge cardCharge
+	if err := json.NewDecoder(resp.Body).Decode(&charge); err != nil {
+		return nil, fmt.Errorf("card processor: failed to decode charge: %w", err)
+	}
+
+	switch charge.Status {
+	case "succeeded":
+	case "pending":
+		return nil, ErrPaymentNotConfirmed
+	default:
+		return nil, fmt.Errorf("%w: charge is %s", ErrPaymentFailed, charge.Status)
+	}
+	if a.MerchantAccount == "" || charge.MerchantAccount != a.MerchantAccount {
+		return nil, ErrPaymentWrongRecipient
+	}
+	if !strings.EqualFold(charge.Currency, claim.Currency) {
+		return nil, fmt.Errorf("%w: charged in %s, due in %s", ErrPaymentCurrencyMismatch, strings.ToUpper(charge.Currency), claim.Currency)
+	}
+	if charge.Amount < claim.Amount {
+		return nil, fmt.Errorf("%w: received %s, due %s", ErrPaymentAmountMismatch,
+			formatAmount(charge.Amount, claim.Currency), formatAmount(claim.Amount, claim.Currency))
+	}
+	if !charge.paidBy(claim) {
+		return nil, fmt.Errorf("%w: charge belongs to customer %q", ErrPaymentWrongPayer, charge.Customer)
+	}
+	// A succeeded charge is captured; without the time it can't be dated against the subscription
+	if charge.CapturedAt == nil {
+		return nil, ErrPaymentNotConfirmed
+	}
+	confirmedAt := time.Unix(*charge.CapturedAt, 0)
+	if confirmedAt.Before(claim.NotBefore) {
+		return nil, fmt.Errorf("%w: captured %s", ErrPaymentTooOld, confirmedAt.UTC().Format(time.RFC3339))
+	}
+	return &PaymentVerification{
+		Provider:    a.Name(),
+		Reference:   claim.Reference,
+		Amount:      charge.Amount,
+		Currency:    claim.Currency,
+		Recipient:   charge.MerchantAccount,
+		Payer:       charge.Customer,
+		ConfirmedAt: confirmedAt,
+	}, nil
+}
+
+// Refund returns amount of the charge through the processor; the refund ID is returned
+func (a *CardPaymentAdapter) Refund(ctx context.Context, reference string, amount int64, currency, key string) (string, error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"charge":   reference,
+		"amount":   amount,
+		"currency": strings.ToLower(currency),
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.BaseURL+"/v1/refunds", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+a.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Idempotency-Key", key)
+
+	resp, err := a.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("card processor: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("card processor refused refund (%d): %s", resp.StatusCode, string(body))
+	}
+
+	var out struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("card processor: failed to decode refund: %w", err)
+	}
+	if out.Status == "failed" {
+		return "", fmt.Errorf("card processor refund %s failed", out.ID)
+	}
+	return out.ID, nil
+}
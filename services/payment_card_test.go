@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeCardProcessor serves charges by ID and records refunds; requests without the API key are
+// refused
+func fakeCardProcessor(t *testing.T, apiKey string, charges map[string]cardCharge) (*httptest.Server, *[]http.Header) {
+	t.Helper()
+	var refunds []http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+apiKey {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/charges/"):
+			charge, ok := charges[strings.TrimPrefix(r.URL.Path, "/v1/charges/")]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			json.NewEncoder(w).Encode(charge)
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/refunds":
+			refunds = append(refunds, r.Header.Clone())
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"id": "re_1", "status": "succeeded"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &refunds
+}
+
+func TestCardPaymentAdapterVerify(t *testing.T) {
+	const merchant = "acct_platform"
+	subscribed := time.Now().Add(-time.Hour)
+	captured := subscribed.Add(5 * time.Minute).Unix()
+	early := subscribed.Add(-time.Hour).Unix()
+
+	charge := func(status string, amount int64, currency, account, customer string) cardCharge {
+		return cardCharge{Status: status, Amount: amount, Currency: currency, MerchantAccount: account,
+			Customer: customer, CapturedAt: &captured}
+	}
+	charges := map[string]cardCharge{
+		"ch_paid":       charge("succeeded", 2000, "usd", merchant, "user-1"),
+		"ch_pending":    charge("pending", 2000, "usd", merchant, "user-1"),
+		"ch_failed":     charge("failed", 2000, "usd", merchant, "user-1"),
+		"ch_refunded":   charge("refunded", 2000, "usd", merchant, "user-1"),
+		"ch_elsewhere":  charge("succeeded", 2000, "usd", "acct_other", "user-1"),
+		"ch_euros":      charge("succeeded", 2000, "eur", merchant, "user-1"),
+		"ch_short":      charge("succeeded", 1999, "usd", merchant, "user-1"),
+		"ch_stranger":   charge("succeeded", 2000, "usd", merchant, "cus_someone"),
+		"ch_by_sub":     {Status: "succeeded", Amount: 2000, Currency: "usd", MerchantAccount: merchant, Customer: "cus_123", Metadata: map[string]string{"subscription_id": "sub-1"}, CapturedAt: &captured},
+		"ch_by_user":    {Status: "succeeded", Amount: 2000, Currency: "usd", MerchantAccount: merchant, Customer: "cus_123", Metadata: map[string]string{"user_id": "user-1"}, CapturedAt: &captured},
+		"ch_old":        {Status: "succeeded", Amount: 2000, Currency: "usd", MerchantAccount: merchant, Customer: "user-1", CapturedAt: &early},
+		"ch_uncaptured": {Status: "succeeded", Amount: 2000, Currency: "usd", MerchantAccount: merchant, Customer: "user-1"},
+	}
+	srv, _ := fakeCardProcessor(t, "sk_test", charges)
+	adapter := NewCardPaymentAdapter(srv.URL+"/", "sk_test", merchant)
+
+	tests := []struct {
+		reference string
+		wantErr   error
+	}{
+		{"ch_paid", nil},
+		{"ch_by_sub", nil},
+		{"ch_by_user", nil},
+		{"ch_pending", ErrPaymentNotConfirmed},
+		{"ch_uncaptured", ErrPaymentNotConfirmed},
+		{"ch_failed", ErrPaymentFailed},
+		{"ch_refunded", ErrPaymentFailed},
+		{"ch_missing", ErrPaymentNotFound},
+		{"ch_elsewhere", ErrPaymentWrongRecipient},
+		{"ch_euros", ErrPaymentCurrencyMismatch},
+		{"ch_short", ErrPaymentAmountMismatch},
+		{"ch_stranger", ErrPaymentWrongPayer},
+		{"ch_old", ErrPaymentTooOld},
+	}
+	for _, tt := range tests {
+		t.Run(tt.reference, func(t *testing.T) {
+			got, err := adapter.Verify(context.Background(), PaymentClaim{
+				Reference:      tt.reference,
+				Amount:         2000,
+				Currency:       "USD",
+				UserID:         "user-1",
+				SubscriptionID: "sub-1",
+				NotBefore:      subscribed,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if !isPaymentRejection(err) {
+					t.Errorf("%v is not treated as a rejection", err)
+				}
+				return
+			}
+			if got.Amount != 2000 || got.Currency != "USD" || got.Recipient != merchant || got.Reference != tt.reference {
+				t.Errorf("verification = %+v", got)
+			}
+			if got.ConfirmedAt.Unix() != captured {
+				t.Errorf("confirmed at %v, want the capture time", got.ConfirmedAt)
+			}
+		})
+	}
+
+	t.Run("wrong api key", func(t *testing.T) {
+		_, err := NewCardPaymentAdapter(srv.URL, "sk_wrong", merchant).Verify(context.Background(),
+			PaymentClaim{Reference: "ch_paid", Amount: 2000, Currency: "USD", UserID: "user-1"})
+		if err == nil || isPaymentRejection(err) {
+			t.Errorf("err = %v, want a provider error", err)
+		}
+	})
+	t.Run("no merchant account configured", func(t *testing.T) {
+		_, err := NewCardPaymentAdapter(srv.URL, "sk_test", "").Verify(context.Background(),
+			PaymentClaim{Reference: "ch_paid", Amount: 2000, Currency: "USD", UserID: "user-1"})
+		if !errors.Is(err, ErrPaymentWrongRecipient) {
+			t.Errorf("err = %v, want %v", err, ErrPaymentWrongRecipient)
+		}
+	})
+}
+
+func TestCardPaymentAdapterRefund(t *testing.T) {
+	srv, refunds := fakeCardProcessor(t, "sk_test", nil)
+	adapter := NewCardPaymentAdapter(srv.URL, "sk_test", "acct_platform")
+
+	id, err := adapter.Refund(context.Background(), "ch_paid", 2000, "USD", "refund-key")
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if id != "re_1" {
+		t.Errorf("refund id = %q, want re_1", id)
+	}
+	if len(*refunds) != 1 || (*refunds)[0].Get("Idempotency-Key") != "refund-key" {
+		t.Errorf("refund requests = %v, want one with the idempotency key", *refunds)
+	}
+}
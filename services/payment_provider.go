@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"game-publish-system/models"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownPaymentProvider  = errors.New("unknown payment provider")
+	ErrPaymentNotFound         = errors.New("payment not found at the provider")
+	ErrPaymentNotConfirmed     = errors.New("payment is not confirmed yet; retry shortly")
+	ErrPaymentFailed           = errors.New("payment failed at the provider")
+	ErrPaymentAmountMismatch   = errors.New("payment amount is less than the amount due")
+	ErrPaymentWrongRecipient   = errors.New("payment was not sent to a platform account")
+	ErrPaymentCurrencyMismatch = errors.New("payment is not in the tournament's currency")
+	ErrPaymentReferenceUsed    = errors.New("payment reference already settles another subscription")
+	ErrPaymentNotPending       = errors.New("subscription has no pending payment")
+	ErrPaymentWrongPayer       = errors.New("payment was not made by this subscriber")
+	ErrPaymentTooOld           = errors.New("payment was made before the subscription")
+)
+
+// paymentClockSkew is how far a provider's clock may run behind ours when a confirmation time is
+// compared with when the subscription was created
+const paymentClockSkew = 2 * time.Minute
+
+// PaymentClaim is what a subscriber says they paid: a provider reference (Solana tx signature,
+// card charge ID) and the amount due, in minor units of the tournament's currency. The payment
+// must come from UserID and be confirmed no earlier than NotBefore.
+type PaymentClaim struct {
+	Reference      string
+	Amount         int64
+	Currency       string
+	UserID         string
+	SubscriptionID string
+	NotBefore      time.Time
+}
+
+// paymentClaimFor is the claim that reference pays sub
+func paymentClaimFor(sub models.TournamentSubscription, reference string) PaymentClaim {
+	return PaymentClaim{
+		Reference:      reference,
+		Amount:         sub.PaymentAmount,
+		Currency:       sub.Currency,
+		UserID:         sub.ExternalUserID,
+		SubscriptionID: sub.ID,
+		NotBefore:      sub.JoinedAt.Add(-paymentClockSkew),
+	}
+}
+
+// PaymentVerification is a payment the provider has confirmed, settled to a platform account.
+// Amount is in minor units of Currency.
+type PaymentVerification struct {
+	Provider    string
+	Reference   string
+	Amount      int64
+	Currency    string
+	Recipient   string
+	Payer       string
+	ConfirmedAt time.Time
+}
+
+// PaymentProvider checks a claimed payment server-side. Verify returns one of the ErrPayment*
+// errors when the payment is missing, unconfirmed, short, in another currency, sent elsewhere,
+// made by someone else or older than the subscription; any other error means the provider could
+// not be reached.
+type PaymentProvider interface {
+	Name() string
+	Verify(ctx context.Context, claim PaymentClaim) (*PaymentVerification, error)
+}
+
+// PaymentRefunder is implemented by providers that can send money back on their own. amount is in
+// minor units of currency; key makes the call idempotent, so a retried refund is not paid twice.
+type PaymentRefunder interface {
+	Refund(ctx context.Context, reference string, amount int64, currency, key string) (string, error)
+}
+
+// paymentProvidersFromEnv registers each provider whose endpoint is configured
+func paymentProvidersFromEnv(db *gorm.DB) map[string]PaymentProvider {
+	providers := map[string]PaymentProvider{}
+	if rpcURL := os.Getenv("SOLANA_RPC_URL"); rpcURL != "" {
+		p := NewSolanaPaymentVerifier(db, rpcURL)
+		providers[p.Name()] = p
+	}
+	if baseURL := os.Getenv("CARD_PROCESSOR_URL"); baseURL != "" {
+		p := NewCardPaymentAdapter(baseURL, os.Getenv("CARD_PROCESSOR_API_KEY"), os.Getenv("CARD_MERCHANT_ACCOUNT"))
+		providers[p.Name()] = p
+	}
+	for name := range providers {
+		log.Printf("💳 Payment provider enabled: %s", name)
+	}
+	return providers
+}
+
+// isPaymentRejection reports whether err is a verdict on the payment rather than a provider outage
+func isPaymentRejection(err error) bool {
+	return errors.Is(err, ErrPaymentNotFound) || errors.Is(err, ErrPaymentNotConfirmed) ||
+		errors.Is(err, ErrPaymentFailed) || errors.Is(err, ErrPaymentAmountMismatch) ||
+		errors.Is(err, ErrPaymentWrongRecipient) || errors.Is(err, ErrPaymentCurrencyMismatch) ||
+		errors.Is(err, ErrPaymentWrongPayer) || errors.Is(err, ErrPaymentTooOld)
+}
+
+// rescaleMinorUnits converts an amount with from decimals (e.g. a token's on-chain precision) to
+// one with to decimals, truncating any finer remainder
+func rescaleMinorUnits(amount int64, from, to int) int64 {
+	for ; from < to; from++ {
+		amount *= 10
+	}
+	for ; from > to; from-- {
+		amount /= 10
+	}
+	return amount
+}
@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"game-publish-system/models"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const defaultPendingPaymentTTL = 60 * time.Minute
+
+// retryableSubscriptionStatuses never took money, so the user may subscribe again over them
+var retryableSubscriptionStatuses = []string{"failed", "expired"}
+
+// pendingPaymentTTL reads PENDING_PAYMENT_TTL_MINUTES, defaulting to 60 minutes
+func pendingPaymentTTL() time.Duration {
+	if v := os.Getenv("PENDING_PAYMENT_TTL_MINUTES"); v != "" {
+		if minutes, err := strconv.Atoi(v); err == nil && minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return defaultPendingPaymentTTL
+}
+
+// expirePendingPayments expires subscriptions left pending past the TTL: the waiver balance they
+// consumed is restored and their slot goes to the waitlist
+func (s *TournamentService) expirePendingPayments(now time.Time) {
+	cutoff := now.Add(-pendingPaymentTTL())
+	var ids []string
+	if err := s.DB.Model(&models.TournamentSubscription{}).
+		Where("payment_status = ? AND joined_at <= ? AND revoked_at IS NULL AND forfeited_at IS NULL", "pending", cutoff).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("[Payments] DB error: %v", err)
+		return
+	}
+
+	freed := map[string]bool{}
+	for _, id := range ids {
+		var sub models.TournamentSubscription
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sub, "id = ?", id).Error; err != nil {
+				return err
+			}
+			// A verify may have settled it since the scan
+			if sub.PaymentStatus != "pending" {
+				sub.ID = ""
+				return nil
+			}
+			if err := tx.Model(&sub).Updates(map[string]interface{}{
+				"payment_status": "expired",
+				"payment_at":     &now,
+			}).Error; err != nil {
+				return err
+			}
+			return restoreWaiverBalance(tx, sub, "pending payment expired")
+		})
+		if err != nil {
+			log.Printf("❌ Failed to expire pending subscription %s: %v", id, err)
+			continue
+		}
+		if sub.ID != "" {
+			log.Printf("⌛ Expired unpaid subscription of %s in tournament %s", sub.ExternalUserID, sub.TournamentID)
+			freed[sub.TournamentID] = true
+		}
+	}
+	for tournamentID := range freed {
+		s.releaseSlot(tournamentID)
+	}
+}
+
+type reconciliationDiscrepancy struct {
+	SubscriptionID string  `json:"subscription_id"`
+	UserID         string  `json:"user_id"`
+	Provider       string  `json:"provider,omitempty"`
+	Reference      string  `json:"reference,omitempty"`
+	Amount         float64 `json:"amount"` // in the tournament's currency
+	Reason         string  `json:"reason"`
+}
+
+// reconcilePayment re-confirms a paid subscription with its provider; "" means it checks out
+func (s *TournamentService) reconcilePayment(sub models.TournamentSubscription) string {
+	if sub.PaymentProvider == "" {
+		return "paid without provider verification"
+	}
+	provider, ok := s.Payments[sub.PaymentProvider]
+	if !ok {
+		return "payment provider " + sub.PaymentProvider + " is not configured"
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), paymentVerifyTimeout)
+	defer cancel()
+	if _, err := provider.Verify(ctx, paymentClaimFor(sub, sub.TransactionID)); err != nil {
+		return err.Error()
+	}
+	return ""
+}
+
+// generateReconciliationReports builds one report per tournament with payment activity on day
+// (UTC). Re-running a day replaces its reports.
+func (s *TournamentService) generateReconciliationReports(day time.Time) ([]models.ReconciliationReport, error) {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+
+	var subs []models.TournamentSubscription
+	if err := s.DB.Where("payment_status IN ? AND payment_at >= ? AND payment_at < ?",
+		[]string{"paid", "refunded", "failed", "expired"}, day, day.Add(24*time.Hour)).
+		Order("tournament_id, payment_at").
+		Find(&subs).Error; err != nil {
+		return nil, err
+	}
+
+	byTournament := map[string]*models.ReconciliationReport{}
+	discrepancies := map[string][]reconciliationDiscrepancy{}
+	var order []string
+	for _, sub := range subs {
+		r, ok := byTournament[sub.TournamentID]
+		if !ok {
+			r = &models.ReconciliationReport{ID: uuid.NewString(), ReportDate: day, TournamentID: sub.TournamentID, Currency: sub.Currency}
+			byTournament[sub.TournamentID] = r
+			order = append(order, sub.TournamentID)
+		}
+		switch sub.PaymentStatus {
+		case "paid":
+			r.PaidCount++
+			r.PaidTotal += sub.PaymentAmount
+			if reason := s.reconcilePayment(sub); reason != "" {
+				discrepancies[sub.TournamentID] = append(discrepancies[sub.TournamentID], reconciliationDiscrepancy{
+					SubscriptionID: sub.ID,
+					UserID:         sub.ExternalUserID,
+					Provider:       sub.PaymentProvider,
+					Reference:      sub.TransactionID,
+					Amount:         majorUnits(sub.PaymentAmount, sub.Currency),
+					Reason:         reason,
+				})
+			} else {
+				r.VerifiedCount++
+			}
+		case "refunded":
+			r.RefundedCount++
+			r.RefundedTotal += sub.PaymentAmount
+		case "failed":
+			r.FailedCount++
+			r.FailedTotal += sub.PaymentAmount
+		case "expired":
+			r.ExpiredCount++
+			r.ExpiredTotal += sub.PaymentAmount
+		}
+	}
+
+	reports := make([]models.ReconciliationReport, 0, len(order))
+	now := time.Now()
+	for _, id := range order {
+		r := byTournament[id]
+		list := discrepancies[id]
+		if list == nil {
+			list = []reconciliationDiscrepancy{}
+		}
+		raw, _ := json.Marshal(list)
+		r.Discrepancies = string(raw)
+		r.DiscrepancyCount = len(list)
+		r.GeneratedAt = now
+		reports = append(reports, *r)
+	}
+	if len(reports) == 0 {
+		return reports, nil
+	}
+
+	err := s.DB.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "report_date"}, {Name: "tournament_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"currency", "paid_count", "paid_total_minor", "refunded_count", "refunded_total_minor",
+			"failed_count", "failed_total_minor", "expired_count", "expired_total_minor",
+			"verified_count", "discrepancy_count", "discrepancies", "generated_at",
+		}),
+	}).Create(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("🧾 Reconciliation for %s: %d tournament report(s)", day.Format("2006-01-02"), len(reports))
+	return reports, nil
+}
+
+// parseReportDate reads ?date=YYYY-MM-DD, defaulting to yesterday (UTC)
+func parseReportDate(c *fiber.Ctx) (time.Time, error) {
+	if v := c.Query("date"); v != "" {
+		return time.Parse("2006-01-02", v)
+	}
+	return time.Now().UTC().AddDate(0, 0, -1), nil
+}
+
+// GetReconciliationReports lists the reports for a day, optionally for one tournament
+func (s *TournamentService) GetReconciliationReports(c *fiber.Ctx) error {
+	day, err := parseReportDate(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "date must be YYYY-MM-DD"})
+	}
+
+	query := s.DB.Where("report_date = ?", day.Format("2006-01-02"))
+	if tournamentID := c.Query("tournament_id"); tournamentID != "" {
+		query = query.Where("tournament_id = ?", tournamentID)
+	}
+	var reports []models.ReconciliationReport
+	if err := query.Order("discrepancy_count DESC, tournament_id").Find(&reports).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch reconciliation reports", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"date": day.Format("2006-01-02"), "reports": reports, "total": len(reports)})
+}
+
+// RunReconciliation regenerates a day's reports on demand
+func (s *TournamentService) RunReconciliation(c *fiber.Ctx) error {
+	day, err := parseReportDate(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "date must be YYYY-MM-DD"})
+	}
+	reports, err := s.generateReconciliationReports(day)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "reconciliation failed", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"date": day.Format("2006-01-02"), "reports": reports, "total": len(reports)})
+}
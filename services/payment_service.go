@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const paymentVerifyTimeout = 20 * time.Second
+
+// VerifySubscriptionPayment settles the caller's pending subscription once the named provider
+// confirms the referenced payment covers the amount due, in the tournament's currency, reached
+// a platform account, and was made by the caller after they subscribed
+func (s *TournamentService) VerifySubscriptionPayment(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	var req struct {
+		Provider  string `json:"provider"`
+		Reference string `json:"reference"` // Solana tx signature or card charge ID
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+	}
+	req.Provider = strings.ToLower(strings.TrimSpace(req.Provider))
+	req.Reference = strings.TrimSpace(req.Reference)
+	if req.Provider == "" || req.Reference == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "provider and reference are required"})
+	}
+
+	provider, ok := s.Payments[req.Provider]
+	if !ok {
+		return paymentErrorResponse(c, fmt.Errorf("%w: %s", ErrUnknownPaymentProvider, req.Provider))
+	}
+
+	var sub models.TournamentSubscription
+	if err := s.DB.Where("tournament_id = ? AND external_user_id = ?", tournamentID, userID).First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "subscription not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error", "details": err.Error()})
+	}
+	if sub.PaymentStatus != "pending" {
+		return c.Status(409).JSON(fiber.Map{"error": ErrPaymentNotPending.Error(), "current": sub.PaymentStatus})
+	}
+
+	var used int64
+	if err := s.DB.Model(&models.TournamentSubscription{}).
+		Where("payment_provider = ? AND transaction_id = ?", provider.Name(), req.Reference).
+		Count(&used).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "DB error", "details": err.Error()})
+	}
+	if used > 0 {
+		return paymentErrorResponse(c, ErrPaymentReferenceUsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentVerifyTimeout)
+	defer cancel()
+	verification, err := provider.Verify(ctx, paymentClaimFor(sub, req.Reference))
+	if err != nil {
+		if isPaymentRejection(err) {
+			log.Printf("⚠️ Payment %s/%s for %s in tournament %s not accepted: %v", provider.Name(), req.Reference, userID, tournamentID, err)
+		} else {
+			log.Printf("❌ Payment provider %s failed verifying %s: %v", provider.Name(), req.Reference, err)
+		}
+		return paymentErrorResponse(c, err)
+	}
+
+	// Settle under a row lock so a concurrent verify, expiry or revoke cannot interleave
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sub, "id = ?", sub.ID).Error; err != nil {
+			return err
+		}
+		if sub.PaymentStatus != "pending" {
+			return ErrPaymentNotPending
+		}
+		// Providers check this too; a provider that did not is not trusted with another currency
+		if !strings.EqualFold(verification.Currency, sub.Currency) {
+			return fmt.Errorf("%w: paid in %s, due in %s", ErrPaymentCurrencyMismatch, verification.Currency, sub.Currency)
+		}
+		now := time.Now()
+		updates := map[string]interface{}{
+			"payment_status":      "paid",
+			"payment_id":          verification.Reference,
+			"transaction_id":      verification.Reference,
+			"payment_provider":    verification.Provider,
+			"payment_method":      verification.Provider,
+			"payment_recipient":   verification.Recipient,
+			"payment_at":          verification.ConfirmedAt,
+			"payment_verified_at": &now,
+		}
+		if err := tx.Model(&sub).Updates(updates).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrPaymentReferenceUsed
+			}
+			return err
+		}
+
+		// Anything paid beyond the amount due is owed back to the user
+		received := verification.Amount
+		due := sub.PaymentAmount
+		return postLedger(tx, ledgerEntry{
+			Kind:           models.LedgerKindEntryFee,
+			Currency:       sub.Currency,
+			IdempotencyKey: "entry_fee:" + verification.Provider + ":" + verification.Reference,
+			TournamentID:   sub.TournamentID,
+			SubscriptionID: sub.ID,
+			Description:    fmt.Sprintf("%s payment %s", verification.Provider, verification.Reference),
+			Actor:          userID,
+			Legs: []ledgerLeg{
+				debit(ledgerCash, received),
+				credit(poolLedgerAccount(sub.TournamentID), due),
+				credit(userLedgerAccount(sub.ExternalUserID), received-due),
+			},
+		})
+	})
+	if errors.Is(err, ErrPaymentNotPending) || errors.Is(err, ErrPaymentReferenceUsed) || errors.Is(err, ErrPaymentCurrencyMismatch) {
+		return paymentErrorResponse(c, err)
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to record payment", "details": err.Error()})
+	}
+
+	log.Printf("💳 Verified %s payment %s (%s from %s to %s) for %s in tournament %s",
+		verification.Provider, verification.Reference, formatAmount(verification.Amount, sub.Currency), verification.Payer,
+		verification.Recipient, userID, tournamentID)
+	s.DB.First(&sub, "id = ?", sub.ID)
+	return c.JSON(fiber.Map{"message": "payment verified", "subscription": sub})
+}
+
+// paymentErrorResponse maps payment verification errors to HTTP responses
+func paymentErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrUnknownPaymentProvider), errors.Is(err, ErrPaymentNotFound):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrPaymentNotConfirmed):
+		return c.Status(202).JSON(fiber.Map{"error": err.Error(), "retry": true})
+	case errors.Is(err, ErrPaymentFailed), errors.Is(err, ErrPaymentAmountMismatch), errors.Is(err, ErrPaymentWrongRecipient),
+		errors.Is(err, ErrPaymentCurrencyMismatch):
+		return c.Status(402).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrPaymentWrongPayer), errors.Is(err, ErrPaymentTooOld):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrPaymentReferenceUsed), errors.Is(err, ErrPaymentNotPending):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(502).JSON(fiber.Map{"error": "payment provider unavailable", "details": err.Error()})
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"game-publish-system/models"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	solanaChain          = "solana"
+	solanaNativeToken    = "SOL"
+	solanaNativeDecimals = 9
+	// mainnet USDC, used when SOLANA_TOKEN_MINTS does not say otherwise
+	solanaUSDCMint = "EPjFWdd5AufqSSqeM2qN1xzybapC8G4wEGGkZwyTDt1v"
+)
+
+// SolanaPaymentVerifier confirms a transfer by its transaction signature. The transfer must have
+// landed at the configured commitment, succeeded, been signed by one of the subscriber's active
+// wallets, and credited an active treasury wallet from wallet_mirror with at least the amount due
+// in that wallet's token.
+type SolanaPaymentVerifier struct {
+	Wallets    SolanaWallets
+	RPCURL     string
+	Commitment string            // "finalized" (default) or "confirmed"
+	Mints      map[string]string // token symbol -> SPL mint address
+	HTTPClient *http.Client
+}
+
+// SolanaWallets looks up the wallets a Solana payment is checked against
+type SolanaWallets interface {
+	Treasuries(ctx context.Context) ([]models.WalletMirror, error)      // active treasury wallets
+	UserAddresses(ctx context.Context, userID string) ([]string, error) // userID's active wallets
+}
+
+// walletMirrorWallets reads SolanaWallets from wallet_mirror
+type walletMirrorWallets struct {
+	DB *gorm.DB
+}
+
+func (w walletMirrorWallets) Treasuries(ctx context.Context) ([]models.WalletMirror, error) {
+	var treasuries []models.WalletMirror
+	err := w.DB.WithContext(ctx).Where("is_treasury = ? AND is_active = ? AND LOWER(chain) = ?", true, true, solanaChain).
+		Find(&treasuries).Error
+	return treasuries, err
+}
+
+func (w walletMirrorWallets) UserAddresses(ctx context.Context, userID string) ([]string, error) {
+	var addresses []string
+	err := w.DB.WithContext(ctx).Model(&models.WalletMirror{}).
+		Where("user_id = ? AND is_active = ? AND LOWER(chain) = ?", userID, true, solanaChain).
+		Pluck("address", &addresses).Error
+	return addresses, err
+}
+
+// NewSolanaPaymentVerifier reads SOLANA_COMMITMENT and SOLANA_TOKEN_MINTS ("USDC=<mint>,USDT=<mint>")
+func NewSolanaPaymentVerifier(db *gorm.DB, rpcURL string) *SolanaPaymentVerifier {
+	commitment := os.Getenv("SOLANA_COMMITMENT")
+	if commitment == "" {
+		commitment = "finalized"
+	}
+	mints := map[string]string{"USDC": solanaUSDCMint}
+	for _, pair := range strings.Split(os.Getenv("SOLANA_TOKEN_MINTS"), ",") {
+		if symbol, mint, ok := strings.Cut(strings.TrimSpace(pair), "="); ok {
+			mints[strings.ToUpper(symbol)] = mint
+		}
+	}
+	return &SolanaPaymentVerifier{
+		Wallets:    walletMirrorWallets{DB: db},
+		RPCURL:     rpcURL,
+		Commitment: commitment,
+		Mints:      mints,
+		HTTPClient: &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+func (v *SolanaPaymentVerifier) Name() string { return "solana" }
+
+type solanaTokenBalance struct {
+	AccountIndex  int    `json:"accountIndex"`
+	Mint          string `json:"mint"`
+	Owner         string `json:"owner"`
+	UITokenAmount struct {
+		Amount   string `json:"amount"`
+		Decimals int    `json:"decimals"`
+	} `json:"uiTokenAmount"`
+}
+
+type solanaTransaction struct {
+	BlockTime *int64 `json:"blockTime"`
+	Meta      *struct {
+		Err               json.RawMessage      `json:"err"`
+		PreBalances       []int64              `json:"preBalances"`
+		PostBalances      []int64              `json:"postBalances"`
+		PreTokenBalances  []solanaTokenBalance `json:"preTokenBalances"`
+		PostTokenBalances []solanaTokenBalance `json:"postTokenBalances"`
+	} `json:"meta"`
+	Transaction struct {
+		Message struct {
+			AccountKeys []struct {
+				Pubkey string `json:"pubkey"`
+				Signer bool   `json:"signer"`
+			} `json:"accountKeys"`
+		} `json:"message"`
+	} `json:"transaction"`
+}
+
+// getTransaction fetches a parsed transaction; nil means the node has not seen it at v.Commitment
+func (v *SolanaPaymentVerifier) getTransaction(ctx context.Context, signature string) (*solanaTransaction, error) {
+	payload, _ := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "getTransaction",
+		"params": []interface{}{signature, map[string]interface{}{
+			"encoding":                       "jsonParsed",
+			"commitment":                     v.Commitment,
+			"maxSupportedTransactionVersion": 0,
+		}},
+	})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.RPCURL, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := v.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("solana rpc: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("solana rpc returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var out struct {
+		Result *solanaTransaction `json:"result"`
+		Error  *struct {
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("solana rpc: failed to decode response: %w", err)
+	}
+	if out.Error != nil {
+		// -32602 is an invalid params error, i.e. a malformed signature
+		if out.Error.Code == -32602 {
+			return nil, fmt.Errorf("%w: %s", ErrPaymentNotFound, out.Error.Message)
+		}
+		return nil, fmt.Errorf("solana rpc error %d: %s", out.Error.Code, out.Error.Message)
+	}
+	return out.Result, nil
+}
+
+// credited is how much the transaction moved into wallet, in minor units of its token
+func (v *SolanaPaymentVerifier) credited(tx *solanaTransaction, wallet models.WalletMirror) (int64, int, bool) {
+	if strings.EqualFold(wallet.Token, solanaNativeToken) {
+		for i, key := range tx.Transaction.Message.AccountKeys {
+			if key.Pubkey == wallet.Address && i < len(tx.Meta.PreBalances) && i < len(tx.Meta.PostBalances) {
+				return tx.Meta.PostBalances[i] - tx.Meta.PreBalances[i], solanaNativeDecimals, true
+			}
+		}
+		return 0, solanaNativeDecimals, false
+	}
+
+	mint, ok := v.Mints[strings.ToUpper(wallet.Token)]
+	if !ok {
+		return 0, 0, false
+	}
+	// Sum per token account so a fresh (pre-less) associated account still counts
+	sum := func(balances []solanaTokenBalance) (int64, int, bool) {
+		var total int64
+		decimals, found := 0, false
+		for _, b := range balances {
+			if b.Owner != wallet.Address || b.Mint != mint {
+				continue
+			}
+			n, err := strconv.ParseInt(b.UITokenAmount.Amount, 10, 64)
+			if err != nil {
+				continue
+			}
+			total += n
+			decimals, found = b.UITokenAmount.Decimals, true
+		}
+		return total, decimals, found
+	}
+	pre, _, _ := sum(tx.Meta.PreTokenBalances)
+	post, decimals, found := sum(tx.Meta.PostTokenBalances)
+	return post - pre, decimals, found
+}
+
+// subscriberSigner returns the transaction signer that is one of userID's active Solana wallets
+func (v *SolanaPaymentVerifier) subscriberSigner(ctx context.Context, tx *solanaTransaction, userID string) (string, error) {
+	var addresses []string
+	if userID != "" {
+		var err error
+		if addresses, err = v.Wallets.UserAddresses(ctx, userID); err != nil {
+			return "", err
+		}
+	}
+	owned := make(map[string]bool, len(addresses))
+	for _, a := range addresses {
+		owned[a] = true
+	}
+	for _, key := range tx.Transaction.Message.AccountKeys {
+		if key.Signer && owned[key.Pubkey] {
+			return key.Pubkey, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no signer is a wallet of this user", ErrPaymentWrongPayer)
+}
+
+// Verify checks the transaction was signed by the subscriber after they subscribed and credited
+// a treasury wallet holding claim.Currency with at least claim.Amount; a transfer of another
+// token to a treasury is a currency mismatch
+func (v *SolanaPaymentVerifier) Verify(ctx context.Context, claim PaymentClaim) (*PaymentVerification, error) {
+	tx, err := v.getTransaction(ctx, claim.Reference)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil || tx.Meta == nil {
+		return nil, ErrPaymentNotConfirmed
+	}
+	if len(tx.Meta.Err) > 0 && string(tx.Meta.Err) != "null" {
+		return nil, fmt.Errorf("%w: %s", ErrPaymentFailed, string(tx.Meta.Err))
+	}
+
+	// The node fills blockTime in once the block is rooted; until then the transfer can't be dated
+	if tx.BlockTime == nil {
+		return nil, ErrPaymentNotConfirmed
+	}
+	confirmedAt := time.Unix(*tx.BlockTime, 0)
+	if confirmedAt.Before(claim.NotBefore) {
+		return nil, fmt.Errorf("%w: confirmed %s", ErrPaymentTooOld, confirmedAt.UTC().Format(time.RFC3339))
+	}
+	payer, err := v.subscriberSigner(ctx, tx, claim.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	treasuries, err := v.Wallets.Treasuries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var short *PaymentVerification
+	otherToken := ""
+	for _, wallet := range treasuries {
+		amount, decimals, found := v.credited(tx, wallet)
+		if !found || amount <= 0 {
+			continue
+		}
+		if !strings.EqualFold(wallet.Token, claim.Currency) {
+			otherToken = strings.ToUpper(wallet.Token)
+			continue
+		}
+		verification := &PaymentVerification{
+			Provider:    v.Name(),
+			Reference:   claim.Reference,
+			Amount:      rescaleMinorUnits(amount, decimals, currencyDecimals(claim.Currency)),
+			Currency:    strings.ToUpper(wallet.Token),
+			Recipient:   wallet.Address,
+			Payer:       payer,
+			ConfirmedAt: confirmedAt,
+		}
+		if verification.Amount >= claim.Amount {
+			return verification, nil
+		}
+		short = verification
+	}
+	if short != nil {
+		return nil, fmt.Errorf("%w: received %s, due %s", ErrPaymentAmountMismatch,
+			formatAmount(short.Amount, claim.Currency), formatAmount(claim.Amount, claim.Currency))
+	}
+	if otherToken != "" {
+		return nil, fmt.Errorf("%w: paid in %s, due in %s", ErrPaymentCurrencyMismatch, otherToken, claim.Currency)
+	}
+	return nil, ErrPaymentWrongRecipient
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"game-publish-system/models"
+)
+
+// fakeSolanaWallets serves fixed treasury and user wallets
+type fakeSolanaWallets struct {
+	treasuries []models.WalletMirror
+	users      map[string][]string
+}
+
+func (f fakeSolanaWallets) Treasuries(ctx context.Context) ([]models.WalletMirror, error) {
+	return f.treasuries, nil
+}
+
+func (f fakeSolanaWallets) UserAddresses(ctx context.Context, userID string) ([]string, error) {
+	return f.users[userID], nil
+}
+
+// fakeSolanaRPC answers getTransaction with txs[signature]; a missing signature is not yet seen
+// and "malformed" is an invalid-params error
+func fakeSolanaRPC(t *testing.T, txs map[string]interface{}) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string            `json:"method"`
+			Params []json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "getTransaction" || len(req.Params) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var signature string
+		json.Unmarshal(req.Params[0], &signature)
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": 1, "result": txs[signature]}
+		if signature == "malformed" {
+			resp = map[string]interface{}{"jsonrpc": "2.0", "id": 1,
+				"error": map[string]interface{}{"code": -32602, "message": "Invalid param: WrongSize"}}
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// solanaTx builds a jsonParsed getTransaction result. Account 0 is the signer; token moves are
+// recorded against account 1.
+type solanaTx struct {
+	signer    string
+	blockTime int64
+	err       interface{}
+	lamports  [2][2]int64 // pre/post balances of accounts 0 and 1
+	account1  string
+	mint      string
+	owner     string // token account owner
+	tokens    [2]int64
+	decimals  int
+}
+
+func (s solanaTx) result() map[string]interface{} {
+	balance := func(amount int64) []interface{} {
+		if s.mint == "" {
+			return []interface{}{}
+		}
+		return []interface{}{map[string]interface{}{
+			"accountIndex": 1,
+			"mint":         s.mint,
+			"owner":        s.owner,
+			"uiTokenAmount": map[string]interface{}{
+				"amount":   strconv.FormatInt(amount, 10),
+				"decimals": s.decimals,
+			},
+		}}
+	}
+	return map[string]interface{}{
+		"blockTime": s.blockTime,
+		"meta": map[string]interface{}{
+			"err":               s.err,
+			"preBalances":       []int64{s.lamports[0][0], s.lamports[1][0]},
+			"postBalances":      []int64{s.lamports[0][1], s.lamports[1][1]},
+			"preTokenBalances":  balance(s.tokens[0]),
+			"postTokenBalances": balance(s.tokens[1]),
+		},
+		"transaction": map[string]interface{}{
+			"message": map[string]interface{}{
+				"accountKeys": []interface{}{
+					map[string]interface{}{"pubkey": s.signer, "signer": true},
+					map[string]interface{}{"pubkey": s.account1, "signer": false},
+				},
+			},
+		},
+	}
+}
+
+func TestSolanaPaymentVerifier(t *testing.T) {
+	const (
+		userID      = "user-1"
+		userWallet  = "UserWa11et111111111111111111111111111111111"
+		strangerKey = "Stranger11111111111111111111111111111111111"
+		usdcVault   = "TreasuryUSDC1111111111111111111111111111111"
+		usdtVault   = "TreasuryUSDT1111111111111111111111111111111"
+		solVault    = "TreasurySOL11111111111111111111111111111111"
+		usdtMint    = "Es9vMFrzaCERmJfrF4H2FYD4KCoNkY11McCe8BenwNYB"
+	)
+	subscribed := time.Now().Add(-time.Hour)
+	after := subscribed.Add(10 * time.Minute).Unix()
+
+	usdc := func(signer string, amount int64) solanaTx {
+		return solanaTx{signer: signer, blockTime: after, account1: "ata", mint: solanaUSDCMint, owner: usdcVault,
+			tokens: [2]int64{0, amount}, decimals: 6}
+	}
+	txs := map[string]interface{}{
+		"paid":         usdc(userWallet, 1500000).result(),
+		"overpaid":     usdc(userWallet, 2000000).result(),
+		"short":        usdc(userWallet, 1000000).result(),
+		"stranger":     usdc(strangerKey, 1500000).result(),
+		"before-join":  solanaTx{signer: userWallet, blockTime: subscribed.Add(-time.Hour).Unix(), account1: "ata", mint: solanaUSDCMint, owner: usdcVault, tokens: [2]int64{0, 1500000}, decimals: 6}.result(),
+		"unknown-mint": solanaTx{signer: userWallet, blockTime: after, account1: "ata", mint: "SomeOtherMint", owner: usdcVault, tokens: [2]int64{0, 1500000}, decimals: 6}.result(),
+		"usdt":         solanaTx{signer: userWallet, blockTime: after, account1: "ata", mint: usdtMint, owner: usdtVault, tokens: [2]int64{0, 1500000}, decimals: 6}.result(),
+		"elsewhere":    solanaTx{signer: userWallet, blockTime: after, account1: "ata", mint: solanaUSDCMint, owner: strangerKey, tokens: [2]int64{0, 1500000}, decimals: 6}.result(),
+		"failed": solanaTx{signer: userWallet, blockTime: after, account1: "ata", mint: solanaUSDCMint, owner: usdcVault, tokens: [2]int64{0, 0}, decimals: 6,
+			err: map[string]interface{}{"InstructionError": []interface{}{0, "Custom"}}}.result(),
+		"sol": solanaTx{signer: userWallet, blockTime: after, account1: solVault,
+			lamports: [2][2]int64{{1000000000, 749995000}, {0, 250000000}}}.result(),
+	}
+	pending := usdc(userWallet, 1500000).result()
+	pending["blockTime"] = nil
+	txs["no-block-time"] = pending
+
+	verifier := &SolanaPaymentVerifier{
+		Wallets: fakeSolanaWallets{
+			treasuries: []models.WalletMirror{
+				{Chain: "solana", Token: "USDC", Address: usdcVault, IsTreasury: true, IsActive: true},
+				{Chain: "solana", Token: "USDT", Address: usdtVault, IsTreasury: true, IsActive: true},
+				{Chain: "solana", Token: "SOL", Address: solVault, IsTreasury: true, IsActive: true},
+			},
+			users: map[string][]string{userID: {userWallet}},
+		},
+		RPCURL:     fakeSolanaRPC(t, txs).URL,
+		Commitment: "finalized",
+		Mints:      map[string]string{"USDC": solanaUSDCMint, "USDT": usdtMint},
+		HTTPClient: http.DefaultClient,
+	}
+
+	tests := []struct {
+		name      string
+		reference string
+		currency  string
+		amount    int64
+		userID    string
+		wantErr   error
+		wantPaid  int64
+		wantTo    string
+	}{
+		{"token transfer", "paid", "USDC", 1500000, userID, nil, 1500000, usdcVault},
+		{"overpayment is accepted", "overpaid", "USDC", 1500000, userID, nil, 2000000, usdcVault},
+		{"native transfer", "sol", "SOL", 250000000, userID, nil, 250000000, solVault},
+		{"amount too small", "short", "USDC", 1500000, userID, ErrPaymentAmountMismatch, 0, ""},
+		{"another token to a treasury", "usdt", "USDC", 1500000, userID, ErrPaymentCurrencyMismatch, 0, ""},
+		{"unknown mint", "unknown-mint", "USDC", 1500000, userID, ErrPaymentWrongRecipient, 0, ""},
+		{"not sent to a treasury", "elsewhere", "USDC", 1500000, userID, ErrPaymentWrongRecipient, 0, ""},
+		{"failed transaction", "failed", "USDC", 1500000, userID, ErrPaymentFailed, 0, ""},
+		{"not yet confirmed", "unseen", "USDC", 1500000, userID, ErrPaymentNotConfirmed, 0, ""},
+		{"no block time yet", "no-block-time", "USDC", 1500000, userID, ErrPaymentNotConfirmed, 0, ""},
+		{"malformed signature", "malformed", "USDC", 1500000, userID, ErrPaymentNotFound, 0, ""},
+		{"signed by someone else", "stranger", "USDC", 1500000, userID, ErrPaymentWrongPayer, 0, ""},
+		{"claimed by another user", "paid", "USDC", 1500000, "user-2", ErrPaymentWrongPayer, 0, ""},
+		{"sent before subscribing", "before-join", "USDC", 1500000, userID, ErrPaymentTooOld, 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := verifier.Verify(context.Background(), PaymentClaim{
+				Reference: tt.reference,
+				Amount:    tt.amount,
+				Currency:  tt.currency,
+				UserID:    tt.userID,
+				NotBefore: subscribed,
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if !isPaymentRejection(err) {
+					t.Errorf("%v is not treated as a rejection", err)
+				}
+				return
+			}
+			if got.Amount != tt.wantPaid || got.Recipient != tt.wantTo || got.Currency != tt.currency {
+				t.Errorf("verified %d %s to %s, want %d %s to %s", got.Amount, got.Currency, got.Recipient, tt.wantPaid, tt.currency, tt.wantTo)
+			}
+			if got.Payer != userWallet {
+				t.Errorf("payer = %s, want %s", got.Payer, userWallet)
+			}
+			if got.ConfirmedAt.Unix() != after {
+				t.Errorf("confirmed at %v, want the block time", got.ConfirmedAt)
+			}
+		})
+	}
+}
@@ -95,53 +95,69 @@ func (s *ProgressionService) EnsureProgressRecord(externalUserID string) (*model
 func (s *ProgressionService) AwardXP(externalUserID string, xp int64, reason string) (*models.UserProgress, error) {
 	var updatedProg *models.UserProgress
 	err := s.DB.Transaction(func(tx *gorm.DB) error {
-		var prog models.UserProgress
-		if err := tx.Where("external_user_id = ?", externalUserID).First(&prog).Error; err != nil {
-			return fmt.Errorf("progress record not found for %s", externalUserID)
-		}
+		var err error
+		updatedProg, err = s.awardXPTx(tx, externalUserID, xp, reason)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
 
-		oldRank := prog.Rank
+	// Auto-award badges
+	badgeSvc := NewBadgeService(s.DB)
+	_ = badgeSvc.AutoAwardBadges(externalUserID) // fire-and-forget
 
-		prog.TotalXP += xp
+	return updatedProg, nil
+}
 
-		// Level-up logic: accumulate until enough for next level
-		for prog.TotalXP >= int64(BaseXPPerLevel)*int64(prog.Level)+xpForNextLevel(prog.Level) {
-			prog.Level++
-			now := time.Now()
-			prog.LastLevelUpAt = &now
-		}
+// awardXPTx applies XP inside the caller's transaction so callers that already
+// hold the progress row don't block on a second connection
+func (s *ProgressionService) awardXPTx(tx *gorm.DB, externalUserID string, xp int64, reason string) (*models.UserProgress, error) {
+	var prog models.UserProgress
+	if err := tx.Where("external_user_id = ?", externalUserID).First(&prog).Error; err != nil {
+		return nil, fmt.Errorf("progress record not found for %s", externalUserID)
+	}
 
-		// Rank-up logic
-		newRank := determineRank(prog.Level)
-		if newRank > oldRank {
-			now := time.Now()
-			prog.Rank = newRank
-			prog.LastRankUpAt = &now
-		}
+	oldRank := prog.Rank
 
-		// Save via tx
-		if err := tx.Save(&prog).Error; err != nil {
-			return err
-		}
-		
-		// Auto-award badges
-		badgeSvc := NewBadgeService(s.DB)
-		_ = badgeSvc.AutoAwardBadges(externalUserID) // fire-and-forget
+	prog.TotalXP += xp
 
-		// Copy for return (avoid pointer to stack var)
-		updatedProg = &models.UserProgress{}
-		*updatedProg = prog
+	// Level-up logic: accumulate until enough for next level
+	for prog.TotalXP >= int64(BaseXPPerLevel)*int64(prog.Level)+xpForNextLevel(prog.Level) {
+		prog.Level++
+		now := time.Now()
+		prog.LastLevelUpAt = &now
+	}
 
-		// Log
-		fmt.Printf("🎮 XP Awarded: %s → XP=%d, Lvl=%d, Rank=%d (reason: %s)\n",
-			externalUserID, prog.TotalXP, prog.Level, prog.Rank, reason)
+	// Rank-up logic
+	newRank := determineRank(prog.Level)
+	if newRank > oldRank {
+		now := time.Now()
+		prog.Rank = newRank
+		prog.LastRankUpAt = &now
+	}
 
-		return nil
-	})
-	if err != nil {
+	// Save via tx
+	if err := tx.Save(&prog).Error; err != nil {
 		return nil, err
 	}
-	return updatedProg, nil
+
+	// Log
+	fmt.Printf("🎮 XP Awarded: %s → XP=%d, Lvl=%d, Rank=%d (reason: %s)\n",
+		externalUserID, prog.TotalXP, prog.Level, prog.Rank, reason)
+
+	return &prog, nil
+}
+
+// tournamentXP returns base tournament XP weighted by final rank (0 = unranked)
+func tournamentXP(finalRank int) int64 {
+	baseXP := DefaultXPWeights.TournamentXP
+	if finalRank == 1 {
+		baseXP *= 3 // triple for winner
+	} else if finalRank > 0 && finalRank <= 3 {
+		baseXP *= 2 // double for podium
+	}
+	return baseXP
 }
 
 // RecordMatch creates Match entry + awards XP
@@ -163,14 +179,17 @@ func (s *ProgressionService) RecordMatch(match *models.Match) error {
 		}
 
 		// Award XP (match weight)
-		_, err := s.AwardXP(match.ExternalUserID, DefaultXPWeights.MatchXP, "match_played")
+		_, err := s.awardXPTx(tx, match.ExternalUserID, DefaultXPWeights.MatchXP, "match_played")
 		return err
 	})
 }
 
 // RecordTournamentParticipation finalizes tournament XP + counters
 func (s *ProgressionService) RecordTournamentParticipation(tp *models.TournamentParticipation) error {
-	return s.DB.Transaction(func(tx *gorm.DB) error {
+	// Award base tournament XP + bonus for rank
+	tp.XPEarned = tournamentXP(tp.FinalRank)
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Create(tp).Error; err != nil {
 			return err
 		}
@@ -185,16 +204,15 @@ func (s *ProgressionService) RecordTournamentParticipation(tp *models.Tournament
 			return err
 		}
 
-		// Award base tournament XP + bonus for rank
-		baseXP := DefaultXPWeights.TournamentXP
-		if tp.FinalRank == 1 {
-			baseXP *= 3 // triple for winner
-		} else if tp.FinalRank <= 3 {
-			baseXP *= 2 // double for podium
-		}
-		_, err := s.AwardXP(tp.ExternalUserID, baseXP, fmt.Sprintf("tournament_%s_rank_%d", tp.TournamentID, tp.FinalRank))
+		_, err := s.awardXPTx(tx, tp.ExternalUserID, tp.XPEarned, fmt.Sprintf("tournament_%s_rank_%d", tp.TournamentID, tp.FinalRank))
 		return err
 	})
+	if err != nil {
+		return err
+	}
+
+	_ = NewBadgeService(s.DB).AutoAwardBadges(tp.ExternalUserID) // fire-and-forget
+	return nil
 }
 
 // RecordBountyClaim
@@ -214,7 +232,7 @@ func (s *ProgressionService) RecordBountyClaim(claim *models.BountyClaim) error
 			return err
 		}
 
-		_, err := s.AwardXP(claim.ExternalUserID, claim.XPEarned, fmt.Sprintf("bounty_%s", claim.BountyID))
+		_, err := s.awardXPTx(tx, claim.ExternalUserID, claim.XPEarned, fmt.Sprintf("bounty_%s", claim.BountyID))
 		return err
 	})
 }
@@ -255,7 +273,7 @@ func (s *ProgressionService) ProcessReferralAward(referralID string) error {
 		}
 
 		// Award XP
-		_, err := s.AwardXP(r.ReferrerID,
+		_, err := s.awardXPTx(tx, r.ReferrerID,
 			DefaultXPWeights.ReferralXP+DefaultXPWeights.FirstDepositXP,
 			fmt.Sprintf("referral_%s_deposit", r.ReferredID),
 		)
@@ -345,23 +363,27 @@ func (s *ProgressionService) GetUserHistory(externalUserID string, page, size in
 	}, nil
 }
 
-// GetTournamentLeaderboardForUser returns leaderboard entries around user ±5
-func (s *ProgressionService) GetTournamentLeaderboardForUser(tournamentID, externalUserID string) ([]models.LeaderboardEntry, error) {
-	var userEntry models.LeaderboardEntry
-	if err := s.DB.Where("tournament_id = ? AND user_id = ?", tournamentID, externalUserID).
-		First(&userEntry).Error; err != nil {
+// GetTournamentLeaderboardForUser returns overall standings around user ±5
+func (s *ProgressionService) GetTournamentLeaderboardForUser(tournamentID, externalUserID string) ([]Standing, error) {
+	standings, err := NewStandingsService(s.DB).ComputeStandings(tournamentID, StandingsScopeTournament, "", nil)
+	if err != nil {
 		return nil, err
 	}
 
-	lower := userEntry.Rank - 5
-	if lower < 1 {
-		lower = 1
+	for i, st := range standings {
+		if st.UserID != externalUserID {
+			continue
+		}
+		lower := i - 5
+		if lower < 0 {
+			lower = 0
+		}
+		upper := i + 6
+		if upper > len(standings) {
+			upper = len(standings)
+		}
+		return standings[lower:upper], nil
 	}
-	upper := userEntry.Rank + 5
 
-	var entries []models.LeaderboardEntry
-	err := s.DB.Where("tournament_id = ? AND rank BETWEEN ? AND ?", tournamentID, lower, upper).
-		Order("rank ASC").
-		Find(&entries).Error
-	return entries, err
+	return nil, gorm.ErrRecordNotFound
 }
@@ -0,0 +1,397 @@
+package services
+
+import (
+	"errors"
+	"game-publish-system/models"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Glicko-2 system constants
+const (
+	glickoScale   = 173.7178 // converts between the Glicko and Glicko-2 scales
+	glickoTau     = 0.5      // constrains volatility change
+	glickoEpsilon = 0.000001 // volatility iteration tolerance
+)
+
+// glickoRating is a rating on the public (Glicko) scale
+type glickoRating struct {
+	rating     float64
+	deviation  float64
+	volatility float64
+}
+
+// glickoGame is one game against an opponent: score 1 = win, 0.5 = draw, 0 = loss
+type glickoGame struct {
+	opponent glickoRating
+	score    float64
+}
+
+// ratedGame is one pairwise game of a confirmed result, scoreA from A's side
+type ratedGame struct {
+	a, b   string
+	scoreA float64
+}
+
+func glickoG(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+func glickoE(mu, muJ, phiJ float64) float64 {
+	return 1 / (1 + math.Exp(-glickoG(phiJ)*(mu-muJ)))
+}
+
+// glicko2Update rates one player over a rating period (Glickman's Glicko-2 steps 2-8).
+// Every confirmed result is its own rating period here.
+func glicko2Update(p glickoRating, games []glickoGame) glickoRating {
+	mu := (p.rating - models.DefaultRating) / glickoScale
+	phi := p.deviation / glickoScale
+	sigma := p.volatility
+
+	if len(games) == 0 {
+		phiStar := math.Sqrt(phi*phi + sigma*sigma)
+		return glickoRating{p.rating, math.Min(phiStar*glickoScale, models.DefaultRatingDeviation), sigma}
+	}
+
+	var vInv, improvement float64
+	for _, g := range games {
+		muJ := (g.opponent.rating - models.DefaultRating) / glickoScale
+		phiJ := g.opponent.deviation / glickoScale
+		gJ := glickoG(phiJ)
+		e := glickoE(mu, muJ, phiJ)
+		vInv += gJ * gJ * e * (1 - e)
+		improvement += gJ * (g.score - e)
+	}
+	v := 1 / vInv
+	delta := v * improvement
+
+	// New volatility by the Illinois algorithm
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		d := phi*phi + v + ex
+		return ex*(delta*delta-phi*phi-v-ex)/(2*d*d) - (x-a)/(glickoTau*glickoTau)
+	}
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*glickoTau) < 0 {
+			k++
+		}
+		B = a - k*glickoTau
+	}
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > glickoEpsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA /= 2
+		}
+		B, fB = C, fC
+	}
+	newSigma := math.Exp(A / 2)
+
+	phiStar := math.Sqrt(phi*phi + newSigma*newSigma)
+	newPhi := 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	newMu := mu + newPhi*newPhi*improvement
+
+	return glickoRating{
+		rating:     newMu*glickoScale + models.DefaultRating,
+		deviation:  newPhi * glickoScale,
+		volatility: newSigma,
+	}
+}
+
+// outcomeScore converts a head-to-head outcome into player1's score
+func outcomeScore(outcome string) float64 {
+	switch outcome {
+	case models.PairingOutcomePlayer1:
+		return 1
+	case models.PairingOutcomeDraw:
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// placementGames decomposes a free-for-all result into pairwise games: everyone beat
+// everyone who placed below them
+func placementGames(players []models.LobbyPlayer) []ratedGame {
+	var games []ratedGame
+	for i := range players {
+		for j := i + 1; j < len(players); j++ {
+			a, b := players[i], players[j]
+			score := 0.5
+			if a.Placement < b.Placement {
+				score = 1
+			} else if a.Placement > b.Placement {
+				score = 0
+			}
+			games = append(games, ratedGame{a: a.UserID, b: b.UserID, scoreA: score})
+		}
+	}
+	return games
+}
+
+// applyRatedGames updates the ratings of everyone in a confirmed result, once per sourceKey.
+// All players are rated against their opponents' ratings from before the result. tx must be a
+// transaction, or the event marker can commit without the ratings it stands for.
+func applyRatedGames(tx *gorm.DB, sourceKey, tournamentID string, games []ratedGame) error {
+	if len(games) == 0 {
+		return nil
+	}
+	var tournament models.Tournament
+	if err := tx.Select("id", "game_id").First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		return err
+	}
+	if tournament.GameID == "" {
+		return nil
+	}
+
+	perPlayer := make(map[string][]ratedGame)
+	for _, g := range games {
+		if g.a == "" || g.b == "" {
+			continue
+		}
+		perPlayer[g.a] = append(perPlayer[g.a], g)
+		perPlayer[g.b] = append(perPlayer[g.b], ratedGame{a: g.b, b: g.a, scoreA: 1 - g.scoreA})
+	}
+	if len(perPlayer) == 0 {
+		return nil
+	}
+
+	event := models.RatingEvent{
+		ID:           uuid.NewString(),
+		SourceKey:    sourceKey,
+		GameID:       tournament.GameID,
+		TournamentID: tournamentID,
+		Players:      len(perPlayer),
+	}
+	res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return nil // already rated
+	}
+
+	userIDs := make([]string, 0, len(perPlayer))
+	for id := range perPlayer {
+		userIDs = append(userIDs, id)
+	}
+	var existing []models.PlayerRating
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("game_id = ? AND user_id IN ?", tournament.GameID, userIDs).
+		Find(&existing).Error; err != nil {
+		return err
+	}
+	ratings := make(map[string]*models.PlayerRating, len(userIDs))
+	for i := range existing {
+		ratings[existing[i].UserID] = &existing[i]
+	}
+	for _, id := range userIDs {
+		if ratings[id] == nil {
+			ratings[id] = &models.PlayerRating{
+				UserID:     id,
+				GameID:     tournament.GameID,
+				Rating:     models.DefaultRating,
+				Deviation:  models.DefaultRatingDeviation,
+				Volatility: models.DefaultRatingVolatility,
+			}
+		}
+	}
+
+	before := make(map[string]glickoRating, len(ratings))
+	for id, r := range ratings {
+		before[id] = glickoRating{r.Rating, r.Deviation, r.Volatility}
+	}
+
+	now := time.Now()
+	for _, id := range userIDs {
+		var glickoGames []glickoGame
+		total := 0.0
+		for _, g := range perPlayer[id] {
+			glickoGames = append(glickoGames, glickoGame{opponent: before[g.b], score: g.scoreA})
+			total += g.scoreA
+		}
+		updated := glicko2Update(before[id], glickoGames)
+
+		r := ratings[id]
+		r.Rating, r.Deviation, r.Volatility = updated.rating, updated.deviation, updated.volatility
+		r.GamesPlayed++
+		switch {
+		case total == float64(len(glickoGames)):
+			r.Wins++
+		case total*2 == float64(len(glickoGames)) && len(glickoGames) == 1:
+			r.Draws++
+		default:
+			r.Losses++
+		}
+		r.LastPlayedAt = &now
+
+		if r.ID == "" {
+			r.ID = uuid.NewString()
+			if err := tx.Create(r).Error; err != nil {
+				return err
+			}
+		} else if err := tx.Save(r).Error; err != nil {
+			return err
+		}
+	}
+
+	log.Printf("📈 Ratings updated for %s (%d players)", sourceKey, len(userIDs))
+	return nil
+}
+
+// backfillSkillRatings copies every entrant's current game rating into the tournament's
+// PlayerSeeding rows so SKILL_BASED seeding has something to sort by
+func (s *TournamentService) backfillSkillRatings(t *models.Tournament) error {
+	// Seeds already laid out in an approved pairing stay as they are
+	if locked, err := seedingLocked(s.DB, t.ID); err != nil || locked {
+		return err
+	}
+
+	var subs []models.TournamentSubscription
+	if err := s.DB.Where("tournament_id = ? AND payment_status IN ? AND revoked_at IS NULL", t.ID, activeSubscriptionStatuses).
+		Find(&subs).Error; err != nil {
+		return err
+	}
+	if len(subs) == 0 {
+		return nil
+	}
+
+	entrantIDs := make([]string, len(subs))
+	for i, sub := range subs {
+		entrantIDs[i] = entrantID(sub)
+	}
+
+	var ratings []models.PlayerRating
+	if err := s.DB.Where("game_id = ? AND user_id IN ?", t.GameID, entrantIDs).Find(&ratings).Error; err != nil {
+		return err
+	}
+	byUser := make(map[string]float64, len(ratings))
+	for _, r := range ratings {
+		byUser[r.UserID] = r.Rating
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for i, sub := range subs {
+			userID := entrantIDs[i]
+			rating, ok := byUser[userID]
+			if !ok {
+				rating = models.DefaultRating
+			}
+
+			var seeding models.PlayerSeeding
+			err := tx.Where("tournament_id = ? AND user_id = ?", t.ID, userID).First(&seeding).Error
+			if err == nil {
+				if err := tx.Model(&seeding).Update("skill_rating", rating).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+			seeding = models.PlayerSeeding{
+				ID:            uuid.NewString(),
+				TournamentID:  t.ID,
+				UserID:        userID,
+				UserName:      sub.UserName,
+				SkillRating:   rating,
+				SeedingMethod: SeedingMethodAutomatic,
+			}
+			if err := tx.Omit("Tournament").Create(&seeding).Error; err != nil {
+				return err
+			}
+		}
+		log.Printf("📈 Backfilled skill ratings for %d entrants of tournament %s", len(subs), t.ID)
+		return nil
+	})
+}
+
+// ratingsWithGames attaches game names to a list of ratings
+func (s *TournamentService) ratingsWithGames(ratings []models.PlayerRating) []fiber.Map {
+	gameIDs := make([]string, 0, len(ratings))
+	for _, r := range ratings {
+		gameIDs = append(gameIDs, r.GameID)
+	}
+	var games []models.Game
+	s.DB.Select("id", "name").Where("id IN ?", gameIDs).Find(&games)
+	names := make(map[string]string, len(games))
+	for _, g := range games {
+		names[g.ID] = g.Name
+	}
+
+	out := make([]fiber.Map, len(ratings))
+	for i, r := range ratings {
+		out[i] = fiber.Map{
+			"game_id":        r.GameID,
+			"game_name":      names[r.GameID],
+			"rating":         math.Round(r.Rating),
+			"deviation":      math.Round(r.Deviation),
+			"provisional":    r.Deviation > 110, // still settling
+			"games_played":   r.GamesPlayed,
+			"wins":           r.Wins,
+			"losses":         r.Losses,
+			"draws":          r.Draws,
+			"last_played_at": r.LastPlayedAt,
+		}
+	}
+	return out
+}
+
+// GetPlayerRatings returns a player's rating in every game they've played rated matches in
+func (s *TournamentService) GetPlayerRatings(c *fiber.Ctx) error {
+	userID := c.Params("user_id")
+	if userID == "" {
+		userID, _ = c.Locals("user_id").(string)
+	}
+
+	var ratings []models.PlayerRating
+	if err := s.DB.Where("user_id = ?", userID).Order("games_played DESC").Find(&ratings).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch ratings"})
+	}
+	return c.JSON(fiber.Map{"user_id": userID, "ratings": s.ratingsWithGames(ratings), "count": len(ratings)})
+}
+
+// GetGameRatingLeaderboard lists the top rated players of a game
+func (s *TournamentService) GetGameRatingLeaderboard(c *fiber.Ctx) error {
+	gameID := c.Params("game_id")
+	limit := 50
+	if v, err := strconv.Atoi(c.Query("limit")); err == nil && v > 0 && v <= 500 {
+		limit = v
+	}
+
+	var ratings []models.PlayerRating
+	if err := s.DB.Where("game_id = ?", gameID).
+		Order("rating DESC").
+		Limit(limit).
+		Find(&ratings).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch ratings"})
+	}
+
+	entries := make([]fiber.Map, len(ratings))
+	for i, r := range ratings {
+		entries[i] = fiber.Map{
+			"rank":         i + 1,
+			"user_id":      r.UserID,
+			"rating":       math.Round(r.Rating),
+			"deviation":    math.Round(r.Deviation),
+			"games_played": r.GamesPlayed,
+		}
+	}
+	return c.JSON(fiber.Map{"game_id": gameID, "leaderboard": entries, "count": len(entries), "limit": limit})
+}
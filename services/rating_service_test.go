@@ -0,0 +1,130 @@
+package services
+
+import (
+	"math"
+	"testing"
+
+	"game-publish-system/models"
+)
+
+func TestGlicko2Update(t *testing.T) {
+	player := glickoRating{rating: 1500, deviation: 200, volatility: 0.06}
+	equal := glickoRating{rating: 1500, deviation: 200, volatility: 0.06}
+
+	tests := []struct {
+		name   string
+		player glickoRating
+		games  []glickoGame
+		want   glickoRating
+		tol    glickoRating
+	}{
+		{
+			// Worked example from Glickman's "Example of the Glicko-2 system"
+			name:   "glickman example",
+			player: player,
+			games: []glickoGame{
+				{glickoRating{1400, 30, 0.06}, 1},
+				{glickoRating{1550, 100, 0.06}, 0},
+				{glickoRating{1700, 300, 0.06}, 0},
+			},
+			want: glickoRating{1464.06, 151.52, 0.05999},
+			tol:  glickoRating{0.01, 0.01, 0.00001},
+		},
+		{
+			name:   "no games only widens the deviation",
+			player: player,
+			want:   glickoRating{1500, 200.27, 0.06},
+			tol:    glickoRating{0, 0.01, 0},
+		},
+		{
+			name:   "deviation never grows past the default",
+			player: glickoRating{1500, models.DefaultRatingDeviation, 0.06},
+			want:   glickoRating{1500, models.DefaultRatingDeviation, 0.06},
+		},
+		{
+			name:   "draw between equals keeps the rating",
+			player: player,
+			games:  []glickoGame{{equal, 0.5}},
+			want:   glickoRating{1500, 0, 0},
+			tol:    glickoRating{0.000001, math.Inf(1), math.Inf(1)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := glicko2Update(tt.player, tt.games)
+			if math.Abs(got.rating-tt.want.rating) > tt.tol.rating {
+				t.Errorf("rating = %.4f, want %.4f", got.rating, tt.want.rating)
+			}
+			if math.Abs(got.deviation-tt.want.deviation) > tt.tol.deviation {
+				t.Errorf("deviation = %.4f, want %.4f", got.deviation, tt.want.deviation)
+			}
+			if math.Abs(got.volatility-tt.want.volatility) > tt.tol.volatility {
+				t.Errorf("volatility = %.6f, want %.6f", got.volatility, tt.want.volatility)
+			}
+		})
+	}
+}
+
+func TestGlicko2UpdateDirection(t *testing.T) {
+	player := glickoRating{rating: 1500, deviation: 200, volatility: 0.06}
+	tests := []struct {
+		name     string
+		opponent glickoRating
+		score    float64
+		sign     float64 // expected sign of the rating change
+	}{
+		{"win against equal", player, 1, 1},
+		{"loss against equal", player, 0, -1},
+		{"draw against stronger", glickoRating{1800, 50, 0.06}, 0.5, 1},
+		{"draw against weaker", glickoRating{1200, 50, 0.06}, 0.5, -1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := glicko2Update(player, []glickoGame{{tt.opponent, tt.score}})
+			if change := got.rating - player.rating; change*tt.sign <= 0 {
+				t.Errorf("rating change = %.4f, want sign %+.0f", change, tt.sign)
+			}
+			if got.deviation >= player.deviation {
+				t.Errorf("deviation = %.4f, want below %.4f after a game", got.deviation, player.deviation)
+			}
+		})
+	}
+}
+
+func TestOutcomeScore(t *testing.T) {
+	tests := []struct {
+		outcome string
+		want    float64
+	}{
+		{models.PairingOutcomePlayer1, 1},
+		{models.PairingOutcomeDraw, 0.5},
+		{models.PairingOutcomePlayer2, 0},
+	}
+	for _, tt := range tests {
+		if got := outcomeScore(tt.outcome); got != tt.want {
+			t.Errorf("outcomeScore(%q) = %v, want %v", tt.outcome, got, tt.want)
+		}
+	}
+}
+
+func TestPlacementGames(t *testing.T) {
+	players := []models.LobbyPlayer{
+		{UserID: "a", Placement: 1},
+		{UserID: "b", Placement: 2},
+		{UserID: "c", Placement: 2},
+	}
+	want := []ratedGame{
+		{a: "a", b: "b", scoreA: 1},
+		{a: "a", b: "c", scoreA: 1},
+		{a: "b", b: "c", scoreA: 0.5},
+	}
+	got := placementGames(players)
+	if len(got) != len(want) {
+		t.Fatalf("got %d games, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("game %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
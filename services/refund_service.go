@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrNotRefundable    = errors.New("only 'paid' or 'waived' subscriptions can be refunded")
+	ErrAlreadyRefunded  = errors.New("subscription already refunded")
+	ErrRefundNotPending = errors.New("only pending or failed refunds can be retried")
+	ErrAlreadyRevoked   = errors.New("subscription already revoked")
+)
+
+// RefundOptions describes who refunds a subscription, why, and how the money goes back
+type RefundOptions struct {
+	Reason     string
+	RefundedBy string
+	AsCashback bool // issue a cashback waiver for the paid amount instead of returning cash
+	Revoke     bool // leave the subscription revoked rather than refunded
+}
+
+// refundSubscription records the refund, restores consumed waiver balance, and either issues a
+// cashback waiver or pays the amount back through the payment provider
+func (s *TournamentService) refundSubscription(subscriptionID string, opts RefundOptions) (*models.SubscriptionRefund, error) {
+	var refund models.SubscriptionRefund
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var sub models.TournamentSubscription
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&sub, "id = ?", subscriptionID).Error; err != nil {
+			return err
+		}
+		switch sub.PaymentStatus {
+		case "refunded":
+			return ErrAlreadyRefunded
+		case "paid", "waived":
+		default:
+			return ErrNotRefundable
+		}
+
+		now := time.Now()
+		refund = models.SubscriptionRefund{
+			ID:               uuid.NewString(),
+			SubscriptionID:   sub.ID,
+			TournamentID:     sub.TournamentID,
+			ExternalUserID:   sub.ExternalUserID,
+			Currency:         sub.Currency,
+			WaiverRestored:   sub.WaiverAmountUsed,
+			WaiverID:         sub.WaiverIDUsed,
+			Method:           models.RefundMethodNone,
+			Status:           models.RefundStatusCompleted,
+			Provider:         sub.PaymentProvider,
+			PaymentReference: sub.TransactionID,
+			Reason:           opts.Reason,
+			RefundedBy:       opts.RefundedBy,
+			CompletedAt:      &now,
+		}
+		if sub.PaymentStatus == "paid" && sub.PaymentAmount > 0 {
+			refund.Amount = sub.PaymentAmount
+			if opts.AsCashback {
+				waiver, err := issueCashbackWaiver(tx, sub, opts.RefundedBy)
+				if err != nil {
+					return err
+				}
+				refund.Method = models.RefundMethodCashback
+				refund.CashbackWaiverID = waiver.ID
+			} else {
+				refund.Method = models.RefundMethodOriginal
+				refund.Status = models.RefundStatusPending
+				refund.CompletedAt = nil
+			}
+		}
+
+		if err := restoreWaiverBalance(tx, sub, "refund"); err != nil {
+			return err
+		}
+		if err := tx.Create(&refund).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrAlreadyRefunded
+			}
+			return err
+		}
+		// Cash refunds become owed to the user until paid out; cashback becomes waiver liability
+		owedTo := userLedgerAccount(sub.ExternalUserID)
+		if refund.Method == models.RefundMethodCashback {
+			owedTo = ledgerWaiverLiability
+		}
+		if err := postLedger(tx, ledgerEntry{
+			Kind:           models.LedgerKindRefund,
+			Currency:       sub.Currency,
+			TournamentID:   sub.TournamentID,
+			SubscriptionID: sub.ID,
+			Description:    fmt.Sprintf("%s refund: %s", refund.Method, opts.Reason),
+			Actor:          opts.RefundedBy,
+			Legs: []ledgerLeg{
+				debit(poolLedgerAccount(sub.TournamentID), refund.Amount),
+				credit(owedTo, refund.Amount),
+			},
+		}); err != nil {
+			return err
+		}
+		updates := map[string]interface{}{
+			"payment_status": "refunded",
+			"payment_at":     &now,
+		}
+		if opts.Revoke {
+			updates["payment_status"] = "revoked"
+			updates["revoked_at"] = &now
+			updates["revoked_reason"] = opts.Reason
+		}
+		return tx.Model(&sub).Updates(updates).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("💸 Refunded subscription %s of %s in tournament %s: %s via %s, waiver %s restored (%s)",
+		refund.SubscriptionID, refund.ExternalUserID, refund.TournamentID, formatAmount(refund.Amount, refund.Currency), refund.Method,
+		formatAmount(refund.WaiverRestored, refund.Currency), opts.Reason)
+	if refund.Status == models.RefundStatusPending {
+		s.payOutRefund(&refund)
+	}
+	return &refund, nil
+}
+
+// issueCashbackWaiver credits the paid amount to the user as a cashback waiver
+func issueCashbackWaiver(tx *gorm.DB, sub models.TournamentSubscription, issuedBy string) (*models.UserWaiver, error) {
+	waiver := models.UserWaiver{
+		ID:          uuid.NewString(),
+		UserID:      sub.ExternalUserID,
+		Code:        "REFUND-" + strings.ToUpper(uuid.NewString()[:8]),
+		Title:       "Refund credit",
+		Type:        "cashback",
+		Amount:      sub.PaymentAmount,
+		Currency:    sub.Currency,
+		Emoji:       "💸",
+		Description: fmt.Sprintf("Credit for your refunded entry to tournament %s", sub.TournamentID),
+		IsActive:    true,
+		IssuedByID:  issuedBy,
+	}
+	if err := tx.Create(&waiver).Error; err != nil {
+		return nil, fmt.Errorf("failed to issue cashback waiver: %w", err)
+	}
+	return &waiver, nil
+}
+
+// payOutRefund sends a pending refund through the subscription's provider. Providers without a
+// refund API leave it pending for a manual payout.
+func (s *TournamentService) payOutRefund(refund *models.SubscriptionRefund) {
+	provider, ok := s.Payments[refund.Provider]
+	refunder, canRefund := provider.(PaymentRefunder)
+	if !ok || !canRefund || refund.PaymentReference == "" {
+		log.Printf("⏳ Refund %s (%s to %s) awaits a manual payout", refund.ID, formatAmount(refund.Amount, refund.Currency), refund.ExternalUserID)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), paymentVerifyTimeout)
+	defer cancel()
+	updates := map[string]interface{}{}
+	providerRefundID, err := refunder.Refund(ctx, refund.PaymentReference, refund.Amount, refund.Currency, refund.ID)
+	if err != nil {
+		log.Printf("❌ Refund %s via %s failed: %v", refund.ID, refund.Provider, err)
+		updates["status"] = models.RefundStatusFailed
+		updates["failure_reason"] = err.Error()
+	} else {
+		now := time.Now()
+		updates["status"] = models.RefundStatusCompleted
+		updates["provider_refund_id"] = providerRefundID
+		updates["failure_reason"] = ""
+		updates["completed_at"] = &now
+	}
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(refund).Updates(updates).Error; err != nil {
+			return err
+		}
+		if updates["status"] != models.RefundStatusCompleted {
+			return nil
+		}
+		return postLedger(tx, ledgerEntry{
+			Kind:           models.LedgerKindRefundPayout,
+			Currency:       refund.Currency,
+			IdempotencyKey: "refund_payout:" + refund.ID,
+			TournamentID:   refund.TournamentID,
+			SubscriptionID: refund.SubscriptionID,
+			Description:    fmt.Sprintf("refund paid out via %s (%s)", refund.Provider, providerRefundID),
+			Legs: []ledgerLeg{
+				debit(userLedgerAccount(refund.ExternalUserID), refund.Amount),
+				credit(ledgerCash, refund.Amount),
+			},
+		})
+	})
+	if err != nil {
+		log.Printf("❌ Failed to record payout of refund %s: %v", refund.ID, err)
+	}
+}
+
+// refundTournament refunds every paid or waived subscriber and releases the waivers held by
+// unpaid ones. Subscriptions already refunded are skipped, so it is safe to re-run.
+func (s *TournamentService) refundTournament(tournamentID string, opts RefundOptions) (int, int, error) {
+	var ids []string
+	if err := s.DB.Model(&models.TournamentSubscription{}).
+		Where("tournament_id = ? AND payment_status IN ?", tournamentID, []string{"paid", "waived"}).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, 0, err
+	}
+	refunded, failed := 0, 0
+	for _, id := range ids {
+		if _, err := s.refundSubscription(id, opts); err != nil && !errors.Is(err, ErrAlreadyRefunded) {
+			log.Printf("❌ Failed to refund subscription %s: %v", id, err)
+			failed++
+			continue
+		}
+		refunded++
+	}
+
+	// Unpaid subscriptions have nothing to refund but may hold waiver balance
+	var pending []models.TournamentSubscription
+	if err := s.DB.Where("tournament_id = ? AND payment_status = ?", tournamentID, "pending").Find(&pending).Error; err != nil {
+		return refunded, failed, err
+	}
+	for _, sub := range pending {
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			res := tx.Model(&models.TournamentSubscription{}).
+				Where("id = ? AND payment_status = ?", sub.ID, "pending").
+				Updates(map[string]interface{}{
+					"payment_status": "revoked",
+					"revoked_at":     &now,
+					"revoked_reason": opts.Reason,
+				})
+			if res.Error != nil || res.RowsAffected == 0 {
+				return res.Error
+			}
+			return restoreWaiverBalance(tx, sub, opts.Reason)
+		})
+		if err != nil {
+			log.Printf("❌ Failed to release pending subscription %s: %v", sub.ID, err)
+			failed++
+		}
+	}
+	return refunded, failed, nil
+}
+
+// GetTournamentRefunds lists a tournament's refund ledger, newest first
+func (s *TournamentService) GetTournamentRefunds(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	query := s.DB.Where("tournament_id = ?", tournamentID)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var refunds []models.SubscriptionRefund
+	if err := query.Order("created_at DESC").Find(&refunds).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch refunds", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"tournament_id": tournamentID, "refunds": refunds, "total": len(refunds)})
+}
+
+// RefundAllSubscriptions refunds every subscriber of a cancelled tournament (re-runnable)
+func (s *TournamentService) RefundAllSubscriptions(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	var req struct {
+		Reason     string `json:"reason"`
+		AsCashback bool   `json:"as_cashback"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
+		}
+	}
+
+	var t models.Tournament
+	if err := s.DB.First(&t, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	if t.Status != models.TournamentStatusCancelled {
+		return c.Status(409).JSON(fiber.Map{"error": "bulk refunds are only issued for cancelled tournaments", "status": t.Status})
+	}
+	if req.Reason == "" {
+		req.Reason = "tournament cancelled"
+	}
+
+	refunded, failed, err := s.refundTournament(tournamentID, RefundOptions{
+		Reason:     req.Reason,
+		RefundedBy: c.Locals("user_id").(string),
+		AsCashback: req.AsCashback,
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "bulk refund failed", "details": err.Error()})
+	}
+	return c.JSON(fiber.Map{"message": "bulk refund processed", "refunded": refunded, "failed": failed})
+}
+
+// RetryRefund sends a pending or failed refund through its provider again
+func (s *TournamentService) RetryRefund(c *fiber.Ctx) error {
+	var refund models.SubscriptionRefund
+	if err := s.DB.First(&refund, "id = ?", c.Params("refund_id")).Error; err != nil {
+		return refundErrorResponse(c, err)
+	}
+	if refund.Method != models.RefundMethodOriginal ||
+		(refund.Status != models.RefundStatusPending && refund.Status != models.RefundStatusFailed) {
+		return refundErrorResponse(c, ErrRefundNotPending)
+	}
+	s.payOutRefund(&refund)
+	s.DB.First(&refund, "id = ?", refund.ID)
+	return c.JSON(fiber.Map{"refund": refund})
+}
+
+// refundErrorResponse maps refund errors to HTTP responses
+func refundErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": "refund or subscription not found"})
+	case errors.Is(err, ErrAlreadyRefunded), errors.Is(err, ErrNotRefundable), errors.Is(err, ErrRefundNotPending),
+		errors.Is(err, ErrAlreadyRevoked):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "refund failed", "details": err.Error()})
+	}
+}
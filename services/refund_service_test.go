@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"game-publish-system/models"
+
+	"github.com/google/uuid"
+)
+
+// TestRefundSubscription refunds subscriptions paid in different ways and checks the refund
+// record, the restored waiver balance, the cashback credit and the ledger
+func TestRefundSubscription(t *testing.T) {
+	db := openTestDB(t, &models.Game{}, &models.Tournament{}, &models.TournamentSubscription{},
+		&models.UserWaiver{}, &models.SubscriptionRefund{}, &models.WaitlistEntry{},
+		&models.LedgerAccount{}, &models.LedgerTransaction{}, &models.LedgerPosting{})
+	tournament := createTestTournament(t, db, models.Tournament{EntryFee: 2000, Currency: "USD"})
+	t.Cleanup(func() {
+		db.Where("tournament_id = ?", tournament.ID).Delete(&models.SubscriptionRefund{})
+	})
+	service := &TournamentService{DB: db}
+
+	tests := []struct {
+		name       string
+		status     string
+		paid       int64
+		waiverUsed int64 // of a 1000 waiver already 600 spent, this subscription's part
+		opts       RefundOptions
+		wantErr    error
+		wantStatus string // subscription payment_status afterwards
+		wantMethod string
+		wantRefund string // refund status
+	}{
+		{"waived entry only restores the waiver", "waived", 0, 600, RefundOptions{Reason: "cancelled"},
+			nil, "refunded", models.RefundMethodNone, models.RefundStatusCompleted},
+		{"paid entry as cashback", "paid", 1500, 500, RefundOptions{Reason: "cancelled", AsCashback: true},
+			nil, "refunded", models.RefundMethodCashback, models.RefundStatusCompleted},
+		{"paid entry without a refund API stays pending", "paid", 2000, 0, RefundOptions{Reason: "cancelled"},
+			nil, "refunded", models.RefundMethodOriginal, models.RefundStatusPending},
+		{"revoked entry", "paid", 2000, 0, RefundOptions{Reason: "cheating", Revoke: true},
+			nil, "revoked", models.RefundMethodOriginal, models.RefundStatusPending},
+		{"pending entry is not refundable", "pending", 0, 0, RefundOptions{},
+			ErrNotRefundable, "pending", "", ""},
+		{"refunded entry is not refunded twice", "refunded", 2000, 0, RefundOptions{},
+			ErrAlreadyRefunded, "refunded", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.NewString()
+			waiver := models.UserWaiver{
+				ID:         uuid.NewString(),
+				UserID:     userID,
+				Code:       "TEST-" + uuid.NewString()[:8],
+				Title:      "test waiver",
+				Amount:     1000,
+				UsedAmount: 600,
+				Currency:   "USD",
+				IsActive:   true,
+				IsRedeemed: true,
+			}
+			if err := db.Create(&waiver).Error; err != nil {
+				t.Fatalf("create waiver: %v", err)
+			}
+			t.Cleanup(func() { db.Where("user_id = ?", userID).Delete(&models.UserWaiver{}) })
+
+			sub := models.TournamentSubscription{
+				ID:               uuid.NewString(),
+				TournamentID:     tournament.ID,
+				ExternalUserID:   userID,
+				PaymentStatus:    tt.status,
+				PaymentAmount:    tt.paid,
+				Currency:         "USD",
+				WaiverIDUsed:     waiver.ID,
+				WaiverAmountUsed: tt.waiverUsed,
+			}
+			if err := db.Create(&sub).Error; err != nil {
+				t.Fatalf("create subscription: %v", err)
+			}
+
+			refund, err := service.refundSubscription(sub.ID, tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+
+			if err := db.First(&sub, "id = ?", sub.ID).Error; err != nil {
+				t.Fatalf("reload subscription: %v", err)
+			}
+			if sub.PaymentStatus != tt.wantStatus {
+				t.Errorf("payment_status = %s, want %s", sub.PaymentStatus, tt.wantStatus)
+			}
+			if tt.opts.Revoke && sub.RevokedAt == nil {
+				t.Error("revoked_at not set")
+			}
+			if err := db.First(&waiver, "id = ?", waiver.ID).Error; err != nil {
+				t.Fatalf("reload waiver: %v", err)
+			}
+			if want := 600 - tt.waiverUsed; tt.wantErr == nil && waiver.UsedAmount != want {
+				t.Errorf("waiver used = %d, want %d", waiver.UsedAmount, want)
+			}
+			if tt.wantErr != nil {
+				if waiver.UsedAmount != 600 {
+					t.Errorf("waiver used = %d after a failed refund, want it untouched", waiver.UsedAmount)
+				}
+				return
+			}
+
+			if refund.Method != tt.wantMethod || refund.Status != tt.wantRefund {
+				t.Errorf("refund = %s/%s, want %s/%s", refund.Method, refund.Status, tt.wantMethod, tt.wantRefund)
+			}
+			if refund.Amount != tt.paid || refund.WaiverRestored != tt.waiverUsed {
+				t.Errorf("refund amount = %d, waiver restored = %d; want %d, %d",
+					refund.Amount, refund.WaiverRestored, tt.paid, tt.waiverUsed)
+			}
+			if tt.wantMethod == models.RefundMethodCashback {
+				var cashback models.UserWaiver
+				if err := db.First(&cashback, "id = ?", refund.CashbackWaiverID).Error; err != nil {
+					t.Fatalf("load cashback waiver: %v", err)
+				}
+				if cashback.UserID != userID || cashback.Amount != tt.paid || cashback.Type != "cashback" {
+					t.Errorf("cashback waiver = %s %d %s, want %s %d cashback",
+						cashback.UserID, cashback.Amount, cashback.Type, userID, tt.paid)
+				}
+			}
+
+			var posted struct{ Count, Sum int64 }
+			if err := db.Model(&models.LedgerPosting{}).Select("COUNT(*) AS count, COALESCE(SUM(amount), 0) AS sum").
+				Where("transaction_id IN (?)", db.Model(&models.LedgerTransaction{}).Select("id").
+					Where("subscription_id = ?", sub.ID)).
+				Scan(&posted).Error; err != nil {
+				t.Fatalf("load postings: %v", err)
+			}
+			if posted.Sum != 0 {
+				t.Errorf("refund postings sum to %d, want 0", posted.Sum)
+			}
+			wantPostings := int64(0)
+			if tt.paid > 0 {
+				wantPostings += 2
+			}
+			if tt.waiverUsed > 0 {
+				wantPostings += 2
+			}
+			if posted.Count != wantPostings {
+				t.Errorf("postings = %d, want %d", posted.Count, wantPostings)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"fmt"
+	"game-publish-system/models"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// defaultRoundRobinRoundLength spaces generated rounds when neither an existing round nor the
+// match window says how long one should be
+const defaultRoundRobinRoundLength = time.Hour
+
+// roundRobinRoundCount is how many rounds roundRobinSchedule needs for n players (a double round
+// robin plays them twice)
+func roundRobinRoundCount(n int, double bool) int {
+	if n < 1 {
+		return 0
+	}
+	rounds := n - 1
+	if n%2 != 0 {
+		rounds = n
+	}
+	if double {
+		rounds *= 2
+	}
+	return rounds
+}
+
+// ensureRoundRobinRounds returns the match's rounds in order, first creating any missing up to
+// needed so every scheduled round has a TournamentRound to score into. New rounds follow on
+// from the last one with the same length, or split the match window when it has none.
+func (ps *PairingService) ensureRoundRobinRounds(match models.TournamentMatch, tournamentID string, needed int) ([]models.TournamentRound, error) {
+	var rounds []models.TournamentRound
+	if err := ps.DB.Where("match_id = ?", match.ID).Order("sort_order ASC").Find(&rounds).Error; err != nil {
+		return nil, err
+	}
+	missing := needed - len(rounds)
+	if missing <= 0 {
+		return rounds, nil
+	}
+
+	start, length, sortOrder, scoreType := match.StartDate, defaultRoundRobinRoundLength, len(rounds)+1, "highest"
+	if len(rounds) > 0 {
+		last := rounds[len(rounds)-1]
+		start, sortOrder, scoreType = last.EndDate, last.SortOrder+1, last.ScoreType
+		if last.EndDate.After(last.StartDate) {
+			length = last.EndDate.Sub(last.StartDate)
+		}
+	} else if match.EndDate.After(match.StartDate) {
+		length = match.EndDate.Sub(match.StartDate) / time.Duration(missing)
+	}
+
+	created := make([]models.TournamentRound, missing)
+	for i := range created {
+		roundStart := start.Add(time.Duration(i) * length)
+		created[i] = models.TournamentRound{
+			ID:           uuid.NewString(),
+			MatchID:      match.ID,
+			BatchID:      match.BatchID,
+			TournamentID: tournamentID,
+			Name:         fmt.Sprintf("Round %d", len(rounds)+i+1),
+			SortOrder:    sortOrder + i,
+			StartDate:    roundStart,
+			EndDate:      roundStart.Add(length),
+			DurationMins: int(length / time.Minute),
+			Status:       "pending",
+			ScoreType:    scoreType,
+			Attempts:     1,
+		}
+	}
+	if err := ps.DB.Create(&created).Error; err != nil {
+		return nil, fmt.Errorf("failed to create round robin rounds: %w", err)
+	}
+	return append(rounds, created...), nil
+}
+
+// roundRobinSchedule splits a full round robin into rounds with the circle method: the first
+// player stays fixed while the rest rotate one place per round. An odd field gets a ghost
+// player; whoever meets the ghost has a bye that round (returned as [id, ""]).
+func roundRobinSchedule(ids []string) [][][2]string {
+	field := append([]string(nil), ids...)
+	if len(field)%2 != 0 {
+		field = append(field, "")
+	}
+	n := len(field)
+	if n < 2 {
+		return nil
+	}
+
+	rounds := make([][][2]string, 0, n-1)
+	for r := 0; r < n-1; r++ {
+		games := make([][2]string, 0, n/2)
+		for i := 0; i < n/2; i++ {
+			a, b := field[i], field[n-1-i]
+			if a == "" {
+				a, b = b, a
+			}
+			games = append(games, [2]string{a, b})
+		}
+		rounds = append(rounds, games)
+
+		// rotate everyone but the first player clockwise
+		last := field[n-1]
+		copy(field[2:], field[1:n-1])
+		field[1] = last
+	}
+	return rounds
+}
+
+// balanceHomeAway orders each game so player1 ("home") goes to whoever has hosted less so far,
+// alternating on ties so nobody hosts several rounds in a row
+func balanceHomeAway(rounds [][][2]string) {
+	balance := make(map[string]int)   // home games minus away games
+	lastHome := make(map[string]bool) // hosted in their previous game
+	for _, games := range rounds {
+		for i, g := range games {
+			a, b := g[0], g[1]
+			if b == "" {
+				continue
+			}
+			swap := balance[a] > balance[b] || (balance[a] == balance[b] && lastHome[a] && !lastHome[b])
+			if swap {
+				a, b = b, a
+			}
+			games[i] = [2]string{a, b}
+			balance[a]++
+			balance[b]--
+			lastHome[a], lastHome[b] = true, false
+		}
+	}
+}
+
+// generateRoundRobinPairs schedules every player against every other across n-1 rounds
+// (n with an odd field, one bye each), mapped in order onto the match's TournamentRounds
+// (see ensureRoundRobinRounds). A double round robin repeats the schedule with home and away
+// swapped.
+func (ps *PairingService) generateRoundRobinPairs(players []models.TournamentSubscription, matchRounds []models.TournamentRound, double bool) ([]Pair, map[string]interface{}) {
+	n := len(players)
+	names := make(map[string]string, n)
+	ids := make([]string, n)
+	for i, p := range players {
+		ids[i] = p.ExternalUserID
+		names[p.ExternalUserID] = p.UserName
+	}
+
+	schedule := roundRobinSchedule(ids)
+	balanceHomeAway(schedule)
+	if double {
+		firstLeg := len(schedule)
+		for r := 0; r < firstLeg; r++ {
+			games := make([][2]string, len(schedule[r]))
+			for i, g := range schedule[r] {
+				if g[1] == "" {
+					games[i] = g
+				} else {
+					games[i] = [2]string{g[1], g[0]}
+				}
+			}
+			schedule = append(schedule, games)
+		}
+	}
+
+	var pairs []Pair
+	matchNum := 1
+	games := 0
+	for r, round := range schedule {
+		roundID := ""
+		if r < len(matchRounds) {
+			roundID = matchRounds[r].ID
+		}
+		for _, g := range round {
+			pairs = append(pairs, Pair{
+				Player1ID:   g[0],
+				Player1Name: names[g[0]],
+				Player2ID:   g[1],
+				Player2Name: names[g[1]],
+				MatchNumber: matchNum,
+				RoundNumber: r + 1,
+				RoundID:     roundID,
+			})
+			matchNum++
+			if g[1] != "" {
+				games++
+			}
+		}
+	}
+
+	metadata := map[string]interface{}{
+		"format":          "round_robin",
+		"double":          double,
+		"total_players":   n,
+		"total_matches":   games,
+		"rounds_needed":   len(schedule),
+		"rounds_mapped":   len(matchRounds),
+		"has_bye":         n%2 != 0,
+		"rounds_unmapped": 0,
+	}
+	if len(schedule) > len(matchRounds) {
+		metadata["rounds_unmapped"] = len(schedule) - len(matchRounds)
+	}
+
+	return pairs, metadata
+}
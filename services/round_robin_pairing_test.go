@@ -0,0 +1,68 @@
+package services
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRoundRobinSchedule(t *testing.T) {
+	tests := []struct {
+		players int
+		rounds  int
+	}{
+		{0, 0},
+		{1, 1}, // a lone bye
+		{2, 1},
+		{3, 3},
+		{4, 3},
+		{7, 7},
+		{8, 7},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d players", tt.players), func(t *testing.T) {
+			ids := make([]string, tt.players)
+			for i := range ids {
+				ids[i] = fmt.Sprintf("p%d", i)
+			}
+			schedule := roundRobinSchedule(ids)
+			if len(schedule) != tt.rounds {
+				t.Fatalf("rounds = %d, want %d", len(schedule), tt.rounds)
+			}
+			if got := roundRobinRoundCount(tt.players, false); got != tt.rounds {
+				t.Errorf("roundRobinRoundCount = %d, want %d", got, tt.rounds)
+			}
+			if got := roundRobinRoundCount(tt.players, true); got != 2*tt.rounds {
+				t.Errorf("double roundRobinRoundCount = %d, want %d", got, 2*tt.rounds)
+			}
+
+			met := map[[2]string]int{}
+			for _, round := range schedule {
+				seen := map[string]bool{}
+				for _, g := range round {
+					for _, id := range g {
+						if id != "" && seen[id] {
+							t.Fatalf("%s plays twice in one round", id)
+						}
+						seen[id] = true
+					}
+					if g[1] == "" {
+						continue
+					}
+					a, b := g[0], g[1]
+					if a > b {
+						a, b = b, a
+					}
+					met[[2]string{a, b}]++
+				}
+			}
+			if want := tt.players * (tt.players - 1) / 2; len(met) != want {
+				t.Errorf("%d distinct pairings, want %d", len(met), want)
+			}
+			for pair, n := range met {
+				if n != 1 {
+					t.Errorf("%v met %d times", pair, n)
+				}
+			}
+		})
+	}
+}
@@ -37,4 +37,100 @@ func (s *GameService) StartPublishScheduler() {
 			}
 		}),
 	)
-}
\ No newline at end of file
+}
+// StartLifecycleScheduler advances tournaments (and their matches/rounds) through the
+// draft → scheduled → published → active → completed lifecycle every minute
+func (s *TournamentService) StartLifecycleScheduler() {
+	sched, _ := gocron.NewScheduler()
+	sched.Start()
+
+	_, _ = sched.NewJob(
+		gocron.DurationJob(1*time.Minute),
+		gocron.NewTask(func() {
+			s.advanceTournamentLifecycles(time.Now())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+}
+
+// StartResultConfirmationScheduler confirms lone result reports once their
+// RESULT_CONFIRM_TIMEOUT_HOURS deadline passes
+func (ps *PairingService) StartResultConfirmationScheduler() {
+	sched, _ := gocron.NewScheduler()
+	sched.Start()
+
+	_, _ = sched.NewJob(
+		gocron.DurationJob(1*time.Minute),
+		gocron.NewTask(func() {
+			ps.autoConfirmExpiredResults(time.Now())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+}
+
+// StartCheckInScheduler forfeits no-shows once a check-in window with auto-forfeit closes
+func (s *TournamentService) StartCheckInScheduler() {
+	sched, _ := gocron.NewScheduler()
+	sched.Start()
+
+	_, _ = sched.NewJob(
+		gocron.DurationJob(1*time.Minute),
+		gocron.NewTask(func() {
+			s.forfeitNoShows(time.Now())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+}
+
+// StartWaitlistScheduler expires lapsed waitlist holds and offers free slots to the next in line
+func (s *TournamentService) StartWaitlistScheduler() {
+	sched, _ := gocron.NewScheduler()
+	sched.Start()
+
+	_, _ = sched.NewJob(
+		gocron.DurationJob(1*time.Minute),
+		gocron.NewTask(func() {
+			s.processWaitlists(time.Now())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+}
+
+// StartPaymentReconciliationScheduler expires pending payments past PENDING_PAYMENT_TTL_MINUTES
+// every minute and writes the previous day's reconciliation reports shortly after midnight UTC
+func (s *TournamentService) StartPaymentReconciliationScheduler() {
+	sched, _ := gocron.NewScheduler(gocron.WithLocation(time.UTC))
+	sched.Start()
+
+	_, _ = sched.NewJob(
+		gocron.DurationJob(1*time.Minute),
+		gocron.NewTask(func() {
+			s.expirePendingPayments(time.Now())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+
+	_, _ = sched.NewJob(
+		gocron.DailyJob(1, gocron.NewAtTimes(gocron.NewAtTime(0, 15, 0))),
+		gocron.NewTask(func() {
+			if _, err := s.generateReconciliationReports(time.Now().UTC().AddDate(0, 0, -1)); err != nil {
+				log.Printf("[Reconciliation] failed: %v", err)
+			}
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+}
+
+// StartScoreWebhookCleanupScheduler prunes score webhook nonces that can no longer be replayed
+func (s *TournamentService) StartScoreWebhookCleanupScheduler() {
+	sched, _ := gocron.NewScheduler()
+	sched.Start()
+
+	_, _ = sched.NewJob(
+		gocron.DurationJob(10*time.Minute),
+		gocron.NewTask(func() {
+			s.pruneScoreWebhookNonces(time.Now())
+		}),
+		gocron.WithSingletonMode(gocron.LimitModeReschedule),
+	)
+}
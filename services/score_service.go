@@ -0,0 +1,218 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"game-publish-system/models"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// activeSubscriptionStatuses are the payment states that entitle a subscriber to play
+var activeSubscriptionStatuses = []string{"paid", "waived"}
+
+var (
+	ErrRoundNotFound        = errors.New("round not found")
+	ErrNotSubscribed        = errors.New("user does not hold an active subscription for this tournament")
+	ErrRoundNotOpen         = errors.New("round is not open for submissions")
+	ErrRoundAttemptsReached = errors.New("no attempts remaining for this round")
+)
+
+// SubmitScoreRequest is the body accepted by the round score endpoint
+type SubmitScoreRequest struct {
+	Score    int64                  `json:"score"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ScoreSubmissionResult is returned after a score has been recorded
+type ScoreSubmissionResult struct {
+	Entry             models.LeaderboardEntry `json:"entry"`
+	Rank              int                     `json:"rank"`
+	AttemptsUsed      int64                   `json:"attempts_used"`
+	AttemptsRemaining int64                   `json:"attempts_remaining"` // -1 = unlimited
+}
+
+// roundWithParents is a round joined with its match's batch so entries can be
+// tagged even when the round row itself predates BatchID/TournamentID being set
+type roundWithParents struct {
+	models.TournamentRound
+	ParentBatchID      string `gorm:"column:parent_batch_id"`
+	ParentTournamentID string `gorm:"column:parent_tournament_id"`
+	TournamentStatus   string `gorm:"column:tournament_status"`
+}
+
+// SubmitRoundScore records a score for the authenticated user in a round
+func (s *TournamentService) SubmitRoundScore(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	roundID := c.Params("round_id")
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(401).JSON(fiber.Map{"error": "user context required"})
+	}
+
+	var req SubmitScoreRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+
+	// Games with a webhook secret are server-authoritative; clients can't self-report
+	var serverOnly int64
+	s.DB.Table("games").
+		Joins("JOIN tournaments ON tournaments.game_id = games.id").
+		Where("tournaments.id = ? AND games.webhook_secret <> ''", tournamentID).
+		Count(&serverOnly)
+	if serverOnly > 0 {
+		return c.Status(403).JSON(fiber.Map{"error": "scores for this game must be reported by the game server"})
+	}
+
+	result, err := s.recordRoundScore(tournamentID, roundID, userID, req.Score, req.Metadata)
+	if err != nil {
+		return scoreErrorResponse(c, err)
+	}
+
+	return c.Status(201).JSON(result)
+}
+
+// scoreErrorResponse maps score recording errors to HTTP responses
+func scoreErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrRoundNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrNotSubscribed), errors.Is(err, ErrRoundNotOpen):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrRoundAttemptsReached):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "failed to record score", "details": err.Error()})
+	}
+}
+
+// isScoreRejection reports whether err is a validation failure rather than a DB error
+func isScoreRejection(err error) bool {
+	return errors.Is(err, ErrRoundNotFound) || errors.Is(err, ErrNotSubscribed) ||
+		errors.Is(err, ErrRoundNotOpen) || errors.Is(err, ErrRoundAttemptsReached)
+}
+
+// recordRoundScore validates and stores a single attempt, then re-ranks the round.
+// The round row is locked for the duration so concurrent attempts are counted correctly.
+func (s *TournamentService) recordRoundScore(tournamentID, roundID, userID string, score int64, metadata map[string]interface{}) (*ScoreSubmissionResult, error) {
+	result := &ScoreSubmissionResult{}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var round roundWithParents
+		if err := tx.Table("tournament_rounds").
+			Select("tournament_rounds.*, tournament_matches.batch_id AS parent_batch_id, tournament_batches.tournament_id AS parent_tournament_id, tournaments.status AS tournament_status").
+			Joins("JOIN tournament_matches ON tournament_rounds.match_id = tournament_matches.id").
+			Joins("JOIN tournament_batches ON tournament_matches.batch_id = tournament_batches.id").
+			Joins("JOIN tournaments ON tournament_batches.tournament_id = tournaments.id").
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "tournament_rounds"}}).
+			Where("tournament_rounds.id = ? AND tournament_batches.tournament_id = ?", roundID, tournamentID).
+			Take(&round).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRoundNotFound
+			}
+			return err
+		}
+
+		var sub models.TournamentSubscription
+		if err := tx.Where("tournament_id = ? AND external_user_id = ? AND payment_status IN ?",
+			tournamentID, userID, activeSubscriptionStatuses).
+			First(&sub).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotSubscribed
+			}
+			return err
+		}
+
+		now := time.Now()
+		if round.TournamentStatus != models.TournamentStatusActive ||
+			round.Status == models.StageStatusCancelled || round.Status == models.StageStatusCompleted ||
+			now.Before(round.StartDate) ||
+			(!round.EndDate.IsZero() && now.After(round.EndDate)) {
+			return ErrRoundNotOpen
+		}
+
+		var used int64
+		if err := tx.Model(&models.LeaderboardEntry{}).
+			Where("round_id = ? AND user_id = ?", round.ID, userID).
+			Count(&used).Error; err != nil {
+			return err
+		}
+		if round.Attempts > 0 && used >= int64(round.Attempts) {
+			return ErrRoundAttemptsReached
+		}
+
+		if metadata == nil {
+			metadata = map[string]interface{}{}
+		}
+		metadata["attempt"] = used + 1
+		metadataJSON, _ := json.Marshal(metadata)
+
+		entry := models.LeaderboardEntry{
+			ID:           uuid.NewString(),
+			TournamentID: round.ParentTournamentID,
+			BatchID:      round.ParentBatchID,
+			MatchID:      round.MatchID,
+			RoundID:      round.ID,
+			UserID:       userID,
+			Score:        score,
+			SubmittedAt:  now,
+			Metadata:     string(metadataJSON),
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		ranks, err := rankRoundEntries(tx, &round.TournamentRound)
+		if err != nil {
+			return err
+		}
+
+		entry.Rank = ranks[userID]
+		result.Entry = entry
+		result.Rank = entry.Rank
+		result.AttemptsUsed = used + 1
+		result.AttemptsRemaining = -1
+		if round.Attempts > 0 {
+			result.AttemptsRemaining = int64(round.Attempts) - result.AttemptsUsed
+		}
+		return nil
+	})
+	if err != nil {
+		if !isScoreRejection(err) {
+			log.Printf("❌ Failed to record score for user %s in round %s: %v", userID, roundID, err)
+		}
+		return nil, err
+	}
+
+	log.Printf("✅ Recorded score %d for user %s in round %s (rank %d)", score, userID, roundID, result.Rank)
+	return result, nil
+}
+
+// rankRoundEntries aggregates every attempt in the round by user according to the
+// round's ScoreType and writes the resulting rank onto each of the user's entries
+func rankRoundEntries(tx *gorm.DB, round *models.TournamentRound) (map[string]int, error) {
+	var entries []models.LeaderboardEntry
+	if err := tx.Where("round_id = ?", round.ID).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	standings := aggregateStandings(entries, map[string]string{round.ID: round.ScoreType})
+
+	ranks := make(map[string]int, len(standings))
+	for _, st := range standings {
+		ranks[st.UserID] = st.Rank
+		if err := tx.Model(&models.LeaderboardEntry{}).
+			Where("round_id = ? AND user_id = ? AND rank <> ?", round.ID, st.UserID, st.Rank).
+			Update("rank", st.Rank).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return ranks, nil
+}
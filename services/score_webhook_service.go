@@ -0,0 +1,280 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"game-publish-system/models"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Score webhook headers. The signature is hex(HMAC-SHA256(secret, timestamp + "." + nonce + "." + body)).
+const (
+	ScoreWebhookSignatureHeader = "X-Webhook-Signature"
+	ScoreWebhookTimestampHeader = "X-Webhook-Timestamp"
+	ScoreWebhookNonceHeader     = "X-Webhook-Nonce"
+	IdempotencyKeyHeader        = "Idempotency-Key"
+)
+
+const (
+	defaultScoreWebhookMaxSkew = 5 * time.Minute
+
+	// defaultScoreWebhookSecretGrace is how long a rotated-out secret keeps verifying
+	defaultScoreWebhookSecretGrace = time.Hour
+
+	// maxDeadLetterPayload caps how much of a rejected body is kept
+	maxDeadLetterPayload = 64 << 10
+)
+
+// ScoreWebhookPayload is the body a game server posts for a finished attempt
+type ScoreWebhookPayload struct {
+	TournamentID string                 `json:"tournament_id"`
+	RoundID      string                 `json:"round_id"`
+	UserID       string                 `json:"user_id"` // external user id
+	Score        int64                  `json:"score"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// scoreWebhookMaxSkew reads SCORE_WEBHOOK_MAX_SKEW_SECONDS, defaulting to 5 minutes
+func scoreWebhookMaxSkew() time.Duration {
+	if v := os.Getenv("SCORE_WEBHOOK_MAX_SKEW_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return defaultScoreWebhookMaxSkew
+}
+
+// scoreWebhookSecretGrace reads SCORE_WEBHOOK_SECRET_GRACE_SECONDS, defaulting to an hour; 0 ends
+// the old secret at once
+func scoreWebhookSecretGrace() time.Duration {
+	if v := os.Getenv("SCORE_WEBHOOK_SECRET_GRACE_SECONDS"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	return defaultScoreWebhookSecretGrace
+}
+
+// scoreWebhookSecrets lists the secrets a signature may be made with: the game's current one and,
+// during the grace window after a rotation, the one it replaced
+func scoreWebhookSecrets(game models.Game, now time.Time) []string {
+	secrets := []string{game.WebhookSecret}
+	if game.PreviousWebhookSecret != "" && game.PreviousWebhookSecretExpiresAt != nil &&
+		now.Before(*game.PreviousWebhookSecretExpiresAt) {
+		secrets = append(secrets, game.PreviousWebhookSecret)
+	}
+	return secrets
+}
+
+// signScoreWebhook computes the expected signature for a payload
+func signScoreWebhook(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(nonce))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ReceiveScoreWebhook accepts signed score submissions from a game server
+func (s *TournamentService) ReceiveScoreWebhook(c *fiber.Ctx) error {
+	gameID := c.Params("game_id")
+	body := c.Body()
+	signature := c.Get(ScoreWebhookSignatureHeader)
+	timestamp := c.Get(ScoreWebhookTimestampHeader)
+	nonce := c.Get(ScoreWebhookNonceHeader)
+	if nonce == "" {
+		nonce = c.Get(IdempotencyKeyHeader)
+	}
+
+	// The route is unauthenticated, so until the signature checks out rejections are only logged;
+	// otherwise any caller could fill the dead-letter table
+	deny := func(status int, reason string) error {
+		log.Printf("⚠️ Unverified score webhook for game %s from %s rejected: %s", gameID, c.IP(), reason)
+		return c.Status(status).JSON(fiber.Map{"error": reason})
+	}
+	reject := func(status int, reason string, details string) error {
+		s.deadLetterScoreWebhook(c, gameID, reason, details, nonce)
+		return c.Status(status).JSON(fiber.Map{"error": reason})
+	}
+
+	var game models.Game
+	if err := s.DB.Select("id", "name", "webhook_secret", "previous_webhook_secret", "previous_webhook_secret_expires_at").First(&game, "id = ?", gameID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return deny(404, "game not found")
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	if game.WebhookSecret == "" {
+		return deny(403, "score webhook not enabled for this game")
+	}
+
+	if signature == "" || timestamp == "" || nonce == "" {
+		return deny(401, "missing signature, timestamp or nonce")
+	}
+	if len(nonce) > 128 {
+		return deny(400, "nonce too long")
+	}
+
+	// ⏱️ Timestamp skew
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return deny(401, "invalid timestamp")
+	}
+	skew := time.Since(time.Unix(unix, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > scoreWebhookMaxSkew() {
+		return deny(401, "timestamp outside allowed window")
+	}
+
+	// 🔏 Signature
+	signed := false
+	for _, secret := range scoreWebhookSecrets(game, time.Now()) {
+		expected := signScoreWebhook(secret, timestamp, nonce, body)
+		signed = signed || hmac.Equal([]byte(expected), []byte(signature))
+	}
+	if !signed {
+		return deny(401, "invalid signature")
+	}
+
+	var payload ScoreWebhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return reject(400, "invalid JSON", err.Error())
+	}
+	if payload.TournamentID == "" || payload.RoundID == "" || payload.UserID == "" {
+		return reject(400, "tournament_id, round_id and user_id are required", "")
+	}
+
+	var tournament models.Tournament
+	if err := s.DB.Select("id", "game_id").First(&tournament, "id = ?", payload.TournamentID).Error; err != nil {
+		return reject(404, "tournament not found", "")
+	}
+	if tournament.GameID != game.ID {
+		return reject(403, "tournament does not belong to this game", "")
+	}
+
+	// 🔁 Replay protection: claim the nonce before inserting the score
+	record := models.ScoreWebhookNonce{
+		ID:     uuid.NewString(),
+		GameID: game.ID,
+		Nonce:  nonce,
+	}
+	res := s.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if res.Error != nil {
+		log.Printf("❌ Failed to store webhook nonce for game %s: %v", game.ID, res.Error)
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	if res.RowsAffected == 0 {
+		var existing models.ScoreWebhookNonce
+		s.DB.Where("game_id = ? AND nonce = ?", game.ID, nonce).First(&existing)
+		if existing.EntryID == "" {
+			return reject(409, "nonce already used", "")
+		}
+		return c.JSON(fiber.Map{"duplicate": true, "entry_id": existing.EntryID})
+	}
+
+	result, err := s.recordRoundScore(payload.TournamentID, payload.RoundID, payload.UserID, payload.Score, payload.Metadata)
+	if err != nil {
+		if isScoreRejection(err) {
+			s.deadLetterScoreWebhook(c, gameID, "score rejected", err.Error(), nonce)
+		} else {
+			// Transient failure: release the nonce so the game server can retry
+			s.DB.Delete(&record)
+		}
+		return scoreErrorResponse(c, err)
+	}
+
+	s.DB.Model(&record).Update("entry_id", result.Entry.ID)
+
+	return c.Status(201).JSON(result)
+}
+
+// deadLetterScoreWebhook stores a rejected, correctly signed payload (truncated to
+// maxDeadLetterPayload); failures are only logged
+func (s *TournamentService) deadLetterScoreWebhook(c *fiber.Ctx, gameID, reason, details, nonce string) {
+	payload := c.Body()
+	if len(payload) > maxDeadLetterPayload {
+		payload = payload[:maxDeadLetterPayload]
+	}
+	dl := models.ScoreWebhookDeadLetter{
+		ID:        uuid.NewString(),
+		GameID:    gameID,
+		Reason:    reason,
+		Details:   details,
+		Payload:   string(payload),
+		Signature: c.Get(ScoreWebhookSignatureHeader),
+		Timestamp: c.Get(ScoreWebhookTimestampHeader),
+		Nonce:     nonce,
+		RemoteIP:  c.IP(),
+	}
+	if err := s.DB.Create(&dl).Error; err != nil {
+		log.Printf("❌ Failed to dead-letter score webhook for game %s: %v", gameID, err)
+		return
+	}
+	log.Printf("⚠️ Score webhook rejected for game %s: %s", gameID, reason)
+}
+
+// pruneScoreWebhookNonces deletes nonces older than twice the allowed timestamp skew. A replay of
+// one of them would carry a timestamp outside the window and be refused before the nonce check.
+func (s *TournamentService) pruneScoreWebhookNonces(now time.Time) {
+	cutoff := now.Add(-2 * scoreWebhookMaxSkew())
+	res := s.DB.Where("received_at < ?", cutoff).Delete(&models.ScoreWebhookNonce{})
+	if res.Error != nil {
+		log.Printf("[ScoreWebhook] failed to prune nonces: %v", res.Error)
+		return
+	}
+	if res.RowsAffected > 0 {
+		log.Printf("🧹 Pruned %d score webhook nonces older than %s", res.RowsAffected, cutoff.Format(time.RFC3339))
+	}
+}
+
+// GetScoreWebhookDeadLetters lists rejected webhook payloads (admin)
+func (s *TournamentService) GetScoreWebhookDeadLetters(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	size := c.QueryInt("size", 50)
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 200 {
+		size = 50
+	}
+
+	query := s.DB.Model(&models.ScoreWebhookDeadLetter{})
+	if gameID := c.Query("game_id"); gameID != "" {
+		query = query.Where("game_id = ?", gameID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+
+	var letters []models.ScoreWebhookDeadLetter
+	if err := query.Order("received_at DESC").
+		Offset((page - 1) * size).
+		Limit(size).
+		Find(&letters).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch dead letters"})
+	}
+
+	return c.JSON(fiber.Map{
+		"data":  letters,
+		"page":  page,
+		"size":  size,
+		"total": total,
+	})
+}
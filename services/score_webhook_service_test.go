@@ -0,0 +1,225 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"game-publish-system/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestSignScoreWebhook(t *testing.T) {
+	body := []byte(`{"score":10}`)
+	base := signScoreWebhook("secret", "1700000000", "abc", body)
+	if want := "40ed236e28fd7c6d0da5c918df0fb7b9c620c9ccbd532c8c7e7ffbf360b705c7"; base != want {
+		t.Fatalf("signature = %s, want %s", base, want)
+	}
+
+	tests := []struct {
+		name                     string
+		secret, timestamp, nonce string
+		body                     []byte
+	}{
+		{"other secret", "secret2", "1700000000", "abc", body},
+		{"other timestamp", "secret", "1700000001", "abc", body},
+		{"other nonce", "secret", "1700000000", "abd", body},
+		{"other body", "secret", "1700000000", "abc", []byte(`{"score":11}`)},
+		{"separator moved", "secret", "1700000000.abc", "", body},
+	}
+	for _, tt := range tests {
+		if got := signScoreWebhook(tt.secret, tt.timestamp, tt.nonce, tt.body); got == base {
+			t.Errorf("%s: signature unchanged", tt.name)
+		}
+	}
+}
+
+func TestScoreWebhookMaxSkew(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", defaultScoreWebhookMaxSkew},
+		{"60", time.Minute},
+		{"0", defaultScoreWebhookMaxSkew},
+		{"-30", defaultScoreWebhookMaxSkew},
+		{"soon", defaultScoreWebhookMaxSkew},
+	}
+	for _, tt := range tests {
+		t.Setenv("SCORE_WEBHOOK_MAX_SKEW_SECONDS", tt.env)
+		if got := scoreWebhookMaxSkew(); got != tt.want {
+			t.Errorf("SCORE_WEBHOOK_MAX_SKEW_SECONDS=%q: skew = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestScoreWebhookSecretGrace(t *testing.T) {
+	tests := []struct {
+		env  string
+		want time.Duration
+	}{
+		{"", defaultScoreWebhookSecretGrace},
+		{"600", 10 * time.Minute},
+		{"0", 0},
+		{"-30", defaultScoreWebhookSecretGrace},
+		{"later", defaultScoreWebhookSecretGrace},
+	}
+	for _, tt := range tests {
+		t.Setenv("SCORE_WEBHOOK_SECRET_GRACE_SECONDS", tt.env)
+		if got := scoreWebhookSecretGrace(); got != tt.want {
+			t.Errorf("SCORE_WEBHOOK_SECRET_GRACE_SECONDS=%q: grace = %v, want %v", tt.env, got, tt.want)
+		}
+	}
+}
+
+func TestScoreWebhookSecrets(t *testing.T) {
+	now := time.Now()
+	later, earlier := now.Add(time.Minute), now.Add(-time.Minute)
+	tests := []struct {
+		name     string
+		previous string
+		expires  *time.Time
+		want     int
+	}{
+		{"never rotated", "", nil, 1},
+		{"within the grace window", "old", &later, 2},
+		{"grace window over", "old", &earlier, 1},
+		{"expiring now", "old", &now, 1},
+		{"no expiry recorded", "old", nil, 1},
+	}
+	for _, tt := range tests {
+		got := scoreWebhookSecrets(models.Game{
+			WebhookSecret:                  "new",
+			PreviousWebhookSecret:          tt.previous,
+			PreviousWebhookSecretExpiresAt: tt.expires,
+		}, now)
+		if len(got) != tt.want || got[0] != "new" || (tt.want == 2 && got[1] != "old") {
+			t.Errorf("%s: secrets = %v, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestReceiveScoreWebhook checks signature, timestamp and nonce handling. Payloads name a round
+// that does not exist, so a request that gets past them is dead-lettered as a rejected score.
+func TestReceiveScoreWebhook(t *testing.T) {
+	db := openTestDB(t, &models.Game{}, &models.Tournament{}, &models.TournamentSubscription{},
+		&models.WaitlistEntry{}, &models.ScoreWebhookNonce{}, &models.ScoreWebhookDeadLetter{})
+	const secret = "test-webhook-secret"
+	tournament := createTestTournament(t, db, models.Tournament{})
+	other := createTestTournament(t, db, models.Tournament{})
+	if err := db.Model(&models.Game{}).Where("id = ?", tournament.GameID).Updates(map[string]interface{}{
+		"webhook_secret":                     secret,
+		"previous_webhook_secret":            "rotated-out-secret",
+		"previous_webhook_secret_expires_at": time.Now().Add(time.Hour),
+	}).Error; err != nil {
+		t.Fatalf("set webhook secret: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("game_id = ?", tournament.GameID).Delete(&models.ScoreWebhookNonce{})
+		db.Where("game_id = ?", tournament.GameID).Delete(&models.ScoreWebhookDeadLetter{})
+	})
+
+	service := &TournamentService{DB: db}
+	app := fiber.New()
+	app.Post("/webhooks/games/:game_id/scores", service.ReceiveScoreWebhook)
+
+	payload := func(tournamentID string) []byte {
+		body, _ := json.Marshal(ScoreWebhookPayload{
+			TournamentID: tournamentID,
+			RoundID:      uuid.NewString(),
+			UserID:       uuid.NewString(),
+			Score:        100,
+		})
+		return body
+	}
+	replayed := uuid.NewString()
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	stale := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name           string
+		gameID         string
+		body           []byte
+		secret         string // signs the request; empty sends no signature
+		timestamp      string
+		nonce          string
+		wantStatus     int
+		wantDeadLetter bool
+	}{
+		{"unknown game", uuid.NewString(), payload(tournament.ID), secret, now, uuid.NewString(), 404, false},
+		{"webhook not enabled", other.GameID, payload(other.ID), secret, now, uuid.NewString(), 403, false},
+		{"missing signature", tournament.GameID, payload(tournament.ID), "", now, uuid.NewString(), 401, false},
+		{"missing nonce", tournament.GameID, payload(tournament.ID), secret, now, "", 401, false},
+		{"nonce too long", tournament.GameID, payload(tournament.ID), secret, now, strings.Repeat("n", 129), 400, false},
+		{"timestamp not a number", tournament.GameID, payload(tournament.ID), secret, "yesterday", uuid.NewString(), 401, false},
+		{"stale timestamp", tournament.GameID, payload(tournament.ID), secret, stale, uuid.NewString(), 401, false},
+		{"wrong secret", tournament.GameID, payload(tournament.ID), "guess", now, uuid.NewString(), 401, false},
+		{"previous secret during its grace window", tournament.GameID, payload(tournament.ID), "rotated-out-secret", now, uuid.NewString(), 404, true},
+		{"signed invalid JSON", tournament.GameID, []byte("{"), secret, now, uuid.NewString(), 400, true},
+		{"tournament of another game", tournament.GameID, payload(other.ID), secret, now, uuid.NewString(), 403, true},
+		{"signed score for an unknown round", tournament.GameID, payload(tournament.ID), secret, now, replayed, 404, true},
+		{"replayed nonce", tournament.GameID, payload(tournament.ID), secret, now, replayed, 409, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var before int64
+			db.Model(&models.ScoreWebhookDeadLetter{}).Where("game_id = ?", tt.gameID).Count(&before)
+
+			req := httptest.NewRequest("POST", "/webhooks/games/"+tt.gameID+"/scores", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set(ScoreWebhookTimestampHeader, tt.timestamp)
+			req.Header.Set(ScoreWebhookNonceHeader, tt.nonce)
+			if tt.secret != "" {
+				req.Header.Set(ScoreWebhookSignatureHeader, signScoreWebhook(tt.secret, tt.timestamp, tt.nonce, tt.body))
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			var after int64
+			db.Model(&models.ScoreWebhookDeadLetter{}).Where("game_id = ?", tt.gameID).Count(&after)
+			if got := after > before; got != tt.wantDeadLetter {
+				t.Errorf("dead-lettered = %v, want %v", got, tt.wantDeadLetter)
+			}
+		})
+	}
+
+	// A nonce that already produced a score replays as the same entry rather than a new one
+	entryID := uuid.NewString()
+	if err := db.Model(&models.ScoreWebhookNonce{}).Where("game_id = ? AND nonce = ?", tournament.GameID, replayed).
+		Update("entry_id", entryID).Error; err != nil {
+		t.Fatalf("set nonce entry: %v", err)
+	}
+	body := payload(tournament.ID)
+	req := httptest.NewRequest("POST", "/webhooks/games/"+tournament.GameID+"/scores", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ScoreWebhookTimestampHeader, now)
+	req.Header.Set(IdempotencyKeyHeader, replayed)
+	req.Header.Set(ScoreWebhookSignatureHeader, signScoreWebhook(secret, now, replayed, body))
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatalf("request: %v", err)
+	}
+	defer resp.Body.Close()
+	var got struct {
+		Duplicate bool   `json:"duplicate"`
+		EntryID   string `json:"entry_id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.StatusCode != 200 || !got.Duplicate || got.EntryID != entryID {
+		t.Errorf("retry = %d %+v, want 200 duplicate of %s", resp.StatusCode, got, entryID)
+	}
+}
@@ -0,0 +1,488 @@
+package services
+
+import (
+	"errors"
+	"game-publish-system/models"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Seeding methods stored on PlayerSeeding
+const (
+	SeedingMethodAutomatic = "AUTOMATIC"
+	SeedingMethodManual    = "MANUAL"
+)
+
+var (
+	ErrSeedingLocked   = errors.New("seeding is locked once a pairing has been approved")
+	ErrInvalidSeed     = errors.New("seed_number must be 1 or higher")
+	ErrSeedTaken       = errors.New("seed number is already held by another manual override")
+	ErrNotEntrant      = errors.New("player is not entered in this tournament")
+	ErrSeedingNotFound = errors.New("no seeding found for this player")
+)
+
+// entrantID is who a subscription competes as: the team for team entries, otherwise the player
+func entrantID(sub models.TournamentSubscription) string {
+	if sub.TeamID != "" {
+		return sub.TeamID
+	}
+	return sub.ExternalUserID
+}
+
+// seedingLocked reports whether any pairing of the tournament has been approved or published.
+// From then on seeds are part of a bracket/lobby layout and must not move.
+func seedingLocked(db *gorm.DB, tournamentID string) (bool, error) {
+	var count int64
+	err := db.Model(&models.MatchPairing{}).
+		Where("tournament_id = ? AND status IN ?", tournamentID, []string{"approved", "published"}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// seedingStats is the history one entrant is seeded from
+type seedingStats struct {
+	sub          models.TournamentSubscription
+	userID       string
+	rating       float64
+	deviation    float64
+	wins         int
+	losses       int
+	previousRank int
+	totalScore   int64
+	averageScore float64
+	hasScores    bool
+	score        float64
+}
+
+// seedScore combines an entrant's history into one number, higher = better seed:
+//   - the conservative Glicko estimate (rating - 2·RD), so unproven players sit below proven ones
+//   - up to +200 for the last final rank in this game (200 / rank)
+//   - up to +100 for the average leaderboard score, as a percentile among this field
+func seedScore(st seedingStats, scorePercentile float64) float64 {
+	score := st.rating - 2*st.deviation
+	if st.previousRank > 0 {
+		score += 200 / float64(st.previousRank)
+	}
+	return score + 100*scorePercentile
+}
+
+// collectSeedingStats loads ratings, past final ranks and leaderboard history for the game.
+// Team entries are rated as the team; rank and score history belongs to the subscribing captain.
+func (ps *PairingService) collectSeedingStats(t models.Tournament, subs []models.TournamentSubscription) ([]seedingStats, error) {
+	stats := make([]seedingStats, len(subs))
+	entrantIDs := make([]string, len(subs))
+	historyIDs := make([]string, len(subs))
+	for i, sub := range subs {
+		entrantIDs[i] = entrantID(sub)
+		historyIDs[i] = sub.ExternalUserID
+		stats[i] = seedingStats{
+			sub:       sub,
+			userID:    entrantIDs[i],
+			rating:    models.DefaultRating,
+			deviation: models.DefaultRatingDeviation,
+		}
+	}
+
+	var ratings []models.PlayerRating
+	if err := ps.DB.Where("game_id = ? AND user_id IN ?", t.GameID, entrantIDs).Find(&ratings).Error; err != nil {
+		return nil, err
+	}
+	ratingByUser := make(map[string]models.PlayerRating, len(ratings))
+	for _, r := range ratings {
+		ratingByUser[r.UserID] = r
+	}
+
+	// Most recent ranked finish per player in an earlier tournament of the same game
+	var ranks []struct {
+		ExternalUserID string
+		FinalRank      int
+	}
+	if err := ps.DB.Table("tournament_participations").
+		Select("tournament_participations.external_user_id, tournament_participations.final_rank").
+		Joins("JOIN tournaments ON tournaments.id = tournament_participations.tournament_id").
+		Where("tournaments.game_id = ? AND tournaments.id <> ? AND tournament_participations.final_rank > 0 AND tournament_participations.external_user_id IN ?",
+			t.GameID, t.ID, historyIDs).
+		Order("tournaments.end_time DESC").
+		Scan(&ranks).Error; err != nil {
+		return nil, err
+	}
+	rankByUser := make(map[string]int, len(ranks))
+	for _, r := range ranks {
+		if _, seen := rankByUser[r.ExternalUserID]; !seen {
+			rankByUser[r.ExternalUserID] = r.FinalRank
+		}
+	}
+
+	var scores []struct {
+		UserID       string
+		TotalScore   int64
+		AverageScore float64
+	}
+	if err := ps.DB.Table("leaderboard_entries").
+		Select("leaderboard_entries.user_id, SUM(leaderboard_entries.score) AS total_score, AVG(leaderboard_entries.score) AS average_score").
+		Joins("JOIN tournaments ON tournaments.id = leaderboard_entries.tournament_id").
+		Where("tournaments.game_id = ? AND tournaments.id <> ? AND leaderboard_entries.user_id IN ?", t.GameID, t.ID, historyIDs).
+		Group("leaderboard_entries.user_id").
+		Scan(&scores).Error; err != nil {
+		return nil, err
+	}
+	scoreIndex := make(map[string]int, len(scores))
+	for i, s := range scores {
+		scoreIndex[s.UserID] = i
+	}
+
+	for i := range stats {
+		if r, ok := ratingByUser[entrantIDs[i]]; ok {
+			stats[i].rating = r.Rating
+			stats[i].deviation = r.Deviation
+			stats[i].wins = r.Wins
+			stats[i].losses = r.Losses
+		}
+		stats[i].previousRank = rankByUser[historyIDs[i]]
+		if j, ok := scoreIndex[historyIDs[i]]; ok {
+			stats[i].totalScore = scores[j].TotalScore
+			stats[i].averageScore = scores[j].AverageScore
+			stats[i].hasScores = true
+		}
+	}
+
+	// Average score only counts relative to the rest of the field
+	var withScores []int
+	for i := range stats {
+		if stats[i].hasScores {
+			withScores = append(withScores, i)
+		}
+	}
+	sort.SliceStable(withScores, func(a, b int) bool {
+		return stats[withScores[a]].averageScore < stats[withScores[b]].averageScore
+	})
+	percentile := make(map[int]float64, len(withScores))
+	for pos, i := range withScores {
+		if len(withScores) == 1 {
+			percentile[i] = 1
+			continue
+		}
+		percentile[i] = float64(pos) / float64(len(withScores)-1)
+	}
+	for i := range stats {
+		stats[i].score = seedScore(stats[i], percentile[i])
+	}
+	return stats, nil
+}
+
+// assignSeeds orders automatic entrants by seed score and numbers them 1..n around the seed
+// numbers held by manual overrides.
+func assignSeeds(stats []seedingStats, manual map[string]int) map[string]int {
+	taken := make(map[int]bool, len(manual))
+	for _, seed := range manual {
+		taken[seed] = true
+	}
+
+	auto := make([]seedingStats, 0, len(stats))
+	for _, st := range stats {
+		if _, ok := manual[st.userID]; !ok {
+			auto = append(auto, st)
+		}
+	}
+	sort.SliceStable(auto, func(i, j int) bool {
+		if auto[i].score != auto[j].score {
+			return auto[i].score > auto[j].score
+		}
+		return auto[i].sub.JoinedAt.Before(auto[j].sub.JoinedAt)
+	})
+
+	seeds := make(map[string]int, len(stats))
+	for userID, seed := range manual {
+		seeds[userID] = seed
+	}
+	next := 1
+	for _, st := range auto {
+		for taken[next] {
+			next++
+		}
+		seeds[st.userID] = next
+		next++
+	}
+	return seeds
+}
+
+// tournamentSeedings lists a tournament's seeding rows, seeded players first
+func tournamentSeedings(db *gorm.DB, tournamentID string) ([]models.PlayerSeeding, error) {
+	var seedings []models.PlayerSeeding
+	err := db.Where("tournament_id = ?", tournamentID).
+		Order("CASE WHEN seed_number = 0 THEN 1 ELSE 0 END, seed_number ASC, user_name ASC").
+		Find(&seedings).Error
+	return seedings, err
+}
+
+// GenerateSeeding computes seeds for every eligible entrant from ratings, past final ranks
+// and leaderboard history in the same game. Manual overrides keep their seed number.
+func (ps *PairingService) GenerateSeeding(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	var tournament models.Tournament
+	if err := ps.DB.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch tournament", "details": err.Error()})
+	}
+
+	locked, err := seedingLocked(ps.DB, tournamentID)
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+	if locked {
+		return seedingErrorResponse(c, ErrSeedingLocked)
+	}
+
+	subs, err := ps.eligibleSubscriptions(tournamentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch eligible players"})
+	}
+	if len(subs) == 0 {
+		return c.Status(400).JSON(fiber.Map{"error": "no eligible players to seed"})
+	}
+
+	stats, err := ps.collectSeedingStats(tournament, subs)
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+
+	existing, err := tournamentSeedings(ps.DB, tournamentID)
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+	entrants := make(map[string]bool, len(stats))
+	for _, st := range stats {
+		entrants[st.userID] = true
+	}
+	manual := make(map[string]int)
+	var stale []string
+	for _, s := range existing {
+		switch {
+		case !entrants[s.UserID]:
+			stale = append(stale, s.ID)
+		case s.SeedingMethod == SeedingMethodManual && s.SeedNumber > 0:
+			manual[s.UserID] = s.SeedNumber
+		}
+	}
+	seeds := assignSeeds(stats, manual)
+
+	err = ps.DB.Transaction(func(tx *gorm.DB) error {
+		// Withdrawn players lose their seed
+		if len(stale) > 0 {
+			if err := tx.Where("id IN ?", stale).Delete(&models.PlayerSeeding{}).Error; err != nil {
+				return err
+			}
+		}
+		for _, st := range stats {
+			method := SeedingMethodAutomatic
+			if _, ok := manual[st.userID]; ok {
+				method = SeedingMethodManual
+			}
+			row := models.PlayerSeeding{
+				ID:            uuid.NewString(),
+				TournamentID:  tournamentID,
+				UserID:        st.userID,
+				UserName:      st.sub.UserName,
+				SeedNumber:    seeds[st.userID],
+				SkillRating:   st.rating,
+				PreviousRank:  st.previousRank,
+				WinCount:      st.wins,
+				LossCount:     st.losses,
+				TotalScore:    st.totalScore,
+				AverageScore:  st.averageScore,
+				SeedingMethod: method,
+			}
+			if err := tx.Omit("Tournament").Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "tournament_id"}, {Name: "user_id"}},
+				DoUpdates: clause.AssignmentColumns([]string{
+					"user_name", "seed_number", "skill_rating", "previous_rank", "win_count",
+					"loss_count", "total_score", "average_score", "seeding_method", "updated_at",
+				}),
+			}).Create(&row).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+
+	seedings, err := tournamentSeedings(ps.DB, tournamentID)
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+	log.Printf("🌱 Seeded %d entrants of tournament %s (%d manual)", len(stats), tournamentID, len(manual))
+	return c.JSON(fiber.Map{
+		"tournament_id":    tournamentID,
+		"seedings":         seedings,
+		"total":            len(seedings),
+		"manual_overrides": len(manual),
+		"generated_at":     time.Now(),
+	})
+}
+
+// GetSeeding lists a tournament's seeds and whether they can still change
+func (ps *PairingService) GetSeeding(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	seedings, err := tournamentSeedings(ps.DB, tournamentID)
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+	locked, err := seedingLocked(ps.DB, tournamentID)
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+	return c.JSON(fiber.Map{
+		"tournament_id": tournamentID,
+		"seedings":      seedings,
+		"total":         len(seedings),
+		"locked":        locked,
+	})
+}
+
+// OverrideSeed pins a player to a seed number (SeedingMethod MANUAL). Regeneration keeps the
+// pin; an automatic player already on that seed swaps to the overridden player's old seed.
+func (ps *PairingService) OverrideSeed(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	userID := c.Params("user_id")
+
+	var req struct {
+		SeedNumber int    `json:"seed_number"`
+		Notes      string `json:"notes"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+	if req.SeedNumber < 1 {
+		return seedingErrorResponse(c, ErrInvalidSeed)
+	}
+
+	subs, err := ps.eligibleSubscriptions(tournamentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch eligible players"})
+	}
+	var entrant *models.TournamentSubscription
+	for i := range subs {
+		if entrantID(subs[i]) == userID {
+			entrant = &subs[i]
+			break
+		}
+	}
+	if entrant == nil {
+		return seedingErrorResponse(c, ErrNotEntrant)
+	}
+
+	var seeding models.PlayerSeeding
+	err = ps.DB.Transaction(func(tx *gorm.DB) error {
+		locked, err := seedingLocked(tx, tournamentID)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return ErrSeedingLocked
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tournament_id = ? AND user_id = ?", tournamentID, userID).First(&seeding).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			seeding = models.PlayerSeeding{
+				ID:           uuid.NewString(),
+				TournamentID: tournamentID,
+				UserID:       userID,
+				UserName:     entrant.UserName,
+				SkillRating:  models.DefaultRating,
+			}
+			err = tx.Omit("Tournament").Create(&seeding).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		var holder models.PlayerSeeding
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tournament_id = ? AND seed_number = ? AND user_id <> ?", tournamentID, req.SeedNumber, userID).
+			First(&holder).Error
+		switch {
+		case err == nil:
+			if holder.SeedingMethod == SeedingMethodManual {
+				return ErrSeedTaken
+			}
+			if err := tx.Model(&holder).Update("seed_number", seeding.SeedNumber).Error; err != nil {
+				return err
+			}
+		case !errors.Is(err, gorm.ErrRecordNotFound):
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"seed_number":    req.SeedNumber,
+			"seeding_method": SeedingMethodManual,
+			"notes":          req.Notes,
+		}
+		if err := tx.Model(&seeding).Updates(updates).Error; err != nil {
+			return err
+		}
+		seeding.SeedNumber = req.SeedNumber
+		seeding.SeedingMethod = SeedingMethodManual
+		seeding.Notes = req.Notes
+		return nil
+	})
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+
+	log.Printf("📌 Seed %d pinned for %s in tournament %s", req.SeedNumber, userID, tournamentID)
+	return c.JSON(fiber.Map{
+		"message": "seed overridden",
+		"seeding": seeding,
+	})
+}
+
+// ClearSeedOverride hands a pinned seed back to automatic seeding; it moves on the next generate
+func (ps *PairingService) ClearSeedOverride(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	userID := c.Params("user_id")
+
+	locked, err := seedingLocked(ps.DB, tournamentID)
+	if err != nil {
+		return seedingErrorResponse(c, err)
+	}
+	if locked {
+		return seedingErrorResponse(c, ErrSeedingLocked)
+	}
+
+	res := ps.DB.Model(&models.PlayerSeeding{}).
+		Where("tournament_id = ? AND user_id = ? AND seeding_method = ?", tournamentID, userID, SeedingMethodManual).
+		Update("seeding_method", SeedingMethodAutomatic)
+	if res.Error != nil {
+		return seedingErrorResponse(c, res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return seedingErrorResponse(c, ErrSeedingNotFound)
+	}
+	return c.JSON(fiber.Map{"message": "seed override cleared"})
+}
+
+func seedingErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrNotEntrant), errors.Is(err, ErrSeedingNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrInvalidSeed):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrSeedingLocked), errors.Is(err, ErrSeedTaken):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update seeding", "details": err.Error()})
+	}
+}
@@ -0,0 +1,200 @@
+package services
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// StandingChange is pushed whenever a player's rank or score moves
+type StandingChange struct {
+	Standing
+	PreviousRank  int     `json:"previous_rank"` // 0 = new on the board
+	PreviousScore float64 `json:"previous_score"`
+}
+
+// indexStandings keys standings by user
+func indexStandings(standings []Standing) map[string]Standing {
+	idx := make(map[string]Standing, len(standings))
+	for _, st := range standings {
+		idx[st.UserID] = st
+	}
+	return idx
+}
+
+// diffStandings returns every player whose rank or score differs from previous
+func diffStandings(previous map[string]Standing, current []Standing) []StandingChange {
+	var changes []StandingChange
+	for _, st := range current {
+		prev, ok := previous[st.UserID]
+		if ok && prev.Rank == st.Rank && prev.Score == st.Score {
+			continue
+		}
+		changes = append(changes, StandingChange{
+			Standing:      st,
+			PreviousRank:  prev.Rank,
+			PreviousScore: prev.Score,
+		})
+	}
+	return changes
+}
+
+// StreamLeaderboardSSE streams rank changes for a published tournament (or one of its rounds).
+// Event IDs are the latest SubmittedAt (unix micros) included, so clients reconnecting with
+// Last-Event-ID (or ?last_event_id=) receive everything that changed while they were away.
+func (s *StandingsService) StreamLeaderboardSSE(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	if err := s.ensurePublicTournament(tournamentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found or not available"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+
+	scope, scopeID, err := parseStandingsScope(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	limit := c.QueryInt("limit", 50)
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+
+	var cursor time.Time
+	resumed := false
+	lastEventID := c.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+	if lastEventID != "" {
+		micros, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "invalid Last-Event-ID"})
+		}
+		cursor = time.UnixMicro(micros)
+		resumed = true
+	}
+
+	// SSE headers
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // nginx
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ticker := time.NewTicker(2 * time.Second)
+		defer ticker.Stop()
+
+		var previous map[string]Standing
+
+		if resumed {
+			// Rebuild the board as the client last saw it, then let the first tick send the diff
+			before, err := s.ComputeStandings(tournamentID, scope, scopeID, &cursor)
+			if err != nil {
+				log.Printf("Leaderboard SSE resume error for tournament %s: %v", tournamentID, err)
+				return
+			}
+			previous = indexStandings(before)
+		} else {
+			latest, err := s.latestSubmission(tournamentID, scope, scopeID)
+			if err != nil {
+				log.Printf("Leaderboard SSE init error for tournament %s: %v", tournamentID, err)
+				return
+			}
+			if latest != nil {
+				cursor = *latest
+			}
+			current, err := s.ComputeStandings(tournamentID, scope, scopeID, &cursor)
+			if err != nil {
+				log.Printf("Leaderboard SSE init error for tournament %s: %v", tournamentID, err)
+				return
+			}
+			previous = indexStandings(current)
+
+			top := current
+			if len(top) > limit {
+				top = top[:limit]
+			}
+			payload, _ := json.Marshal(fiber.Map{"standings": top, "total": len(current)})
+			fmt.Fprintf(w, "id: %d\nevent: snapshot\ndata: %s\n\n", leaderboardEventID(cursor), payload)
+		}
+
+		// Initial keepalive (comment event)
+		w.WriteString(":\n\n")
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+				latest, err := s.latestSubmission(tournamentID, scope, scopeID)
+				if err != nil {
+					log.Printf("Leaderboard SSE query error for tournament %s: %v", tournamentID, err)
+					continue
+				}
+				if latest == nil || !latest.After(cursor) {
+					continue
+				}
+
+				current, err := s.ComputeStandings(tournamentID, scope, scopeID, latest)
+				if err != nil {
+					log.Printf("Leaderboard SSE standings error for tournament %s: %v", tournamentID, err)
+					continue
+				}
+				cursor = *latest
+
+				changes := diffStandings(previous, current)
+				previous = indexStandings(current)
+				if len(changes) == 0 {
+					continue
+				}
+
+				payload, _ := json.Marshal(fiber.Map{"changes": changes, "total": len(current)})
+				fmt.Fprintf(w, "id: %d\nevent: rank_change\ndata: %s\n\n", leaderboardEventID(cursor), payload)
+
+				if err := w.Flush(); err != nil {
+					// Client disconnected
+					return
+				}
+
+			case <-c.Context().Done():
+				// Client closed connection
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+// leaderboardEventID encodes a cursor as an SSE event id (0 = board was empty)
+func leaderboardEventID(cursor time.Time) int64 {
+	if cursor.IsZero() {
+		return 0
+	}
+	return cursor.UnixMicro()
+}
+
+// latestSubmission returns the newest SubmittedAt in scope, or nil if there are no entries
+func (s *StandingsService) latestSubmission(tournamentID, scope, scopeID string) (*time.Time, error) {
+	query, err := s.scopedEntries(tournamentID, scope, scopeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *time.Time
+	if err := query.Select("MAX(submitted_at)").Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
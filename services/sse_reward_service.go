@@ -29,6 +29,9 @@ func (s *RewardService) StreamUserRewardsSSE(c *fiber.Ctx) error {
 		defer ticker.Stop()
 
 		var lastMaxCreatedAt time.Time
+		// Zero so the first poll replays offers still held for the user, including any made
+		// while they were disconnected; later polls only pick up newer ones
+		var lastOfferAt time.Time
 
 		// Initialize cursor
 		var latest models.Reward
@@ -61,11 +64,23 @@ func (s *RewardService) StreamUserRewardsSSE(c *fiber.Ctx) error {
 					continue
 				}
 
-				if len(newRewards) == 0 {
+				// Waitlist promotions: a slot is being held for the user
+				var offers []models.WaitlistEntry
+				if err := s.DB.
+					Where("external_user_id = ? AND status = ? AND offered_at > ?", userID, models.WaitlistStatusOffered, lastOfferAt).
+					Where("hold_expires_at IS NULL OR hold_expires_at > ?", time.Now()).
+					Order("offered_at ASC").
+					Find(&offers).Error; err != nil {
+					log.Printf("SSE waitlist query error for user %s: %v", userID, err)
+				}
+
+				if len(newRewards) == 0 && len(offers) == 0 {
 					continue
 				}
 
-				lastMaxCreatedAt = newRewards[len(newRewards)-1].CreatedAt
+				if len(newRewards) > 0 {
+					lastMaxCreatedAt = newRewards[len(newRewards)-1].CreatedAt
+				}
 
 				for _, r := range newRewards {
 					payload, _ := json.Marshal(r)
@@ -76,6 +91,16 @@ func (s *RewardService) StreamUserRewardsSSE(c *fiber.Ctx) error {
 					)
 				}
 
+				for _, o := range offers {
+					lastOfferAt = *o.OfferedAt
+					payload, _ := json.Marshal(o)
+
+					fmt.Fprintf(w,
+						"event: waitlist\ndata: %s\n\n",
+						payload,
+					)
+				}
+
 				// This is the REAL "flush"
 				if err := w.Flush(); err != nil {
 					// Client disconnected
@@ -0,0 +1,349 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Standings scopes accepted by the leaderboard endpoint
+const (
+	StandingsScopeTournament = "tournament"
+	StandingsScopeBatch      = "batch"
+	StandingsScopeMatch      = "match"
+	StandingsScopeRound      = "round"
+)
+
+type StandingsService struct {
+	DB *gorm.DB
+}
+
+func NewStandingsService(db *gorm.DB) *StandingsService {
+	return &StandingsService{DB: db}
+}
+
+// Standing is one player's aggregated position within a scope.
+// Rounds are scored by their ScoreType; matches, batches and the tournament sum their rounds.
+type Standing struct {
+	Rank             int       `json:"rank"`
+	UserID           string    `json:"user_id"`
+	UserName         string    `json:"user_name"`
+	UserAvatarURL    *string   `json:"user_avatar_url,omitempty"`
+	Score            float64   `json:"score"`
+	BestAttempt      int64     `json:"best_attempt"`
+	Attempts         int       `json:"attempts"`
+	RoundsPlayed     int       `json:"rounds_played"`
+	ScoredAt         time.Time `json:"scored_at"`          // when the current score was reached
+	FirstSubmittedAt time.Time `json:"first_submitted_at"` // earliest attempt; earlier wins ties
+}
+
+// roundTally is one user's aggregate inside a single round
+type roundTally struct {
+	sum      int64
+	count    int
+	best     int64
+	bestAt   time.Time
+	lastAt   time.Time
+	firstAt  time.Time
+	hasEntry bool
+}
+
+func (t *roundTally) add(e models.LeaderboardEntry) {
+	if !t.hasEntry || e.Score > t.best || (e.Score == t.best && e.SubmittedAt.Before(t.bestAt)) {
+		t.best = e.Score
+		t.bestAt = e.SubmittedAt
+	}
+	if !t.hasEntry || e.SubmittedAt.After(t.lastAt) {
+		t.lastAt = e.SubmittedAt
+	}
+	if !t.hasEntry || e.SubmittedAt.Before(t.firstAt) {
+		t.firstAt = e.SubmittedAt
+	}
+	t.sum += e.Score
+	t.count++
+	t.hasEntry = true
+}
+
+// score returns the round score and the moment it was reached for a ScoreType
+func (t *roundTally) score(scoreType string) (float64, time.Time) {
+	switch scoreType {
+	case "sum":
+		return float64(t.sum), t.lastAt
+	case "average":
+		return float64(t.sum) / float64(t.count), t.lastAt
+	default: // "highest"
+		return float64(t.best), t.bestAt
+	}
+}
+
+// aggregateStandings rolls raw attempts up into ranked standings.
+// scoreTypes maps round ID → ScoreType; unknown rounds are treated as "highest".
+// Ties go to the earliest first submission, then the best single attempt.
+func aggregateStandings(entries []models.LeaderboardEntry, scoreTypes map[string]string) []Standing {
+	tallies := make(map[string]map[string]*roundTally) // user → round → tally
+	for _, e := range entries {
+		rounds, ok := tallies[e.UserID]
+		if !ok {
+			rounds = make(map[string]*roundTally)
+			tallies[e.UserID] = rounds
+		}
+		t, ok := rounds[e.RoundID]
+		if !ok {
+			t = &roundTally{}
+			rounds[e.RoundID] = t
+		}
+		t.add(e)
+	}
+
+	standings := make([]Standing, 0, len(tallies))
+	for userID, rounds := range tallies {
+		st := Standing{UserID: userID, RoundsPlayed: len(rounds)}
+		first := true
+		for roundID, t := range rounds {
+			score, at := t.score(scoreTypes[roundID])
+			st.Score += score
+			st.Attempts += t.count
+			if first || t.best > st.BestAttempt {
+				st.BestAttempt = t.best
+			}
+			if first || at.After(st.ScoredAt) {
+				st.ScoredAt = at
+			}
+			if first || t.firstAt.Before(st.FirstSubmittedAt) {
+				st.FirstSubmittedAt = t.firstAt
+			}
+			first = false
+		}
+		standings = append(standings, st)
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if !a.FirstSubmittedAt.Equal(b.FirstSubmittedAt) {
+			return a.FirstSubmittedAt.Before(b.FirstSubmittedAt)
+		}
+		if a.BestAttempt != b.BestAttempt {
+			return a.BestAttempt > b.BestAttempt
+		}
+		return a.UserID < b.UserID
+	})
+
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// scopeColumn maps a scope to the LeaderboardEntry column it filters on
+func scopeColumn(scope string) (string, error) {
+	switch scope {
+	case "", StandingsScopeTournament:
+		return "", nil
+	case StandingsScopeBatch:
+		return "batch_id", nil
+	case StandingsScopeMatch:
+		return "match_id", nil
+	case StandingsScopeRound:
+		return "round_id", nil
+	default:
+		return "", fmt.Errorf("invalid scope %q (use tournament, batch, match or round)", scope)
+	}
+}
+
+// scopedEntries builds a LeaderboardEntry query limited to a tournament scope
+func (s *StandingsService) scopedEntries(tournamentID, scope, scopeID string) (*gorm.DB, error) {
+	column, err := scopeColumn(scope)
+	if err != nil {
+		return nil, err
+	}
+	if column != "" && scopeID == "" {
+		return nil, fmt.Errorf("%s scope requires an id", scope)
+	}
+
+	query := s.DB.Model(&models.LeaderboardEntry{}).Where("tournament_id = ?", tournamentID)
+	if column != "" {
+		query = query.Where(column+" = ?", scopeID)
+	}
+	return query, nil
+}
+
+// ComputeStandings returns ranked standings for a tournament scope.
+// If until is set, only entries submitted at or before it are considered.
+func (s *StandingsService) ComputeStandings(tournamentID, scope, scopeID string, until *time.Time) ([]Standing, error) {
+	query, err := s.scopedEntries(tournamentID, scope, scopeID)
+	if err != nil {
+		return nil, err
+	}
+	if until != nil {
+		query = query.Where("submitted_at <= ?", *until)
+	}
+
+	var entries []models.LeaderboardEntry
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return []Standing{}, nil
+	}
+
+	scoreTypes, err := s.roundScoreTypes(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	standings := aggregateStandings(entries, scoreTypes)
+	if err := s.attachProfiles(tournamentID, standings); err != nil {
+		return nil, err
+	}
+	return standings, nil
+}
+
+// roundScoreTypes loads the ScoreType of every round referenced by entries
+func (s *StandingsService) roundScoreTypes(entries []models.LeaderboardEntry) (map[string]string, error) {
+	seen := make(map[string]bool)
+	var roundIDs []string
+	for _, e := range entries {
+		if !seen[e.RoundID] {
+			seen[e.RoundID] = true
+			roundIDs = append(roundIDs, e.RoundID)
+		}
+	}
+
+	var rounds []models.TournamentRound
+	if err := s.DB.Select("id", "score_type").Where("id IN ?", roundIDs).Find(&rounds).Error; err != nil {
+		return nil, err
+	}
+
+	scoreTypes := make(map[string]string, len(rounds))
+	for _, r := range rounds {
+		scoreTypes[r.ID] = r.ScoreType
+	}
+	return scoreTypes, nil
+}
+
+// attachProfiles fills denormalized names/avatars from the tournament's subscriptions
+func (s *StandingsService) attachProfiles(tournamentID string, standings []Standing) error {
+	userIDs := make([]string, len(standings))
+	for i, st := range standings {
+		userIDs[i] = st.UserID
+	}
+
+	var subs []models.TournamentSubscription
+	if err := s.DB.Select("external_user_id", "user_name", "user_avatar_url").
+		Where("tournament_id = ? AND external_user_id IN ?", tournamentID, userIDs).
+		Find(&subs).Error; err != nil {
+		return err
+	}
+
+	bySub := make(map[string]models.TournamentSubscription, len(subs))
+	for _, sub := range subs {
+		bySub[sub.ExternalUserID] = sub
+	}
+	for i := range standings {
+		if sub, ok := bySub[standings[i].UserID]; ok {
+			standings[i].UserName = sub.UserName
+			standings[i].UserAvatarURL = sub.UserAvatarURL
+		}
+	}
+	return nil
+}
+
+// ensurePublicTournament checks the tournament is visible to players
+func (s *StandingsService) ensurePublicTournament(tournamentID string) error {
+	var tournament models.Tournament
+	return s.DB.Select("id", "status").
+		Where("status IN ('published', 'active', 'scheduled', 'completed')").
+		First(&tournament, "id = ?", tournamentID).Error
+}
+
+// parseStandingsScope reads scope and scope_id (or batch_id/match_id/round_id) from the query
+func parseStandingsScope(c *fiber.Ctx) (string, string, error) {
+	scope := c.Query("scope", StandingsScopeTournament)
+	scopeID := c.Query("scope_id")
+	if scopeID == "" && scope != StandingsScopeTournament {
+		scopeID = c.Query(scope + "_id")
+	}
+
+	if _, err := scopeColumn(scope); err != nil {
+		return "", "", err
+	}
+	if scope != StandingsScopeTournament && scopeID == "" {
+		return "", "", fmt.Errorf("scope_id (or %s_id) is required for %s scope", scope, scope)
+	}
+	return scope, scopeID, nil
+}
+
+// GetTournamentLeaderboard returns paginated standings for a published tournament.
+// Query: scope=tournament|batch|match|round, scope_id (or batch_id/match_id/round_id), page, size, user_id.
+func (s *StandingsService) GetTournamentLeaderboard(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	if err := s.ensurePublicTournament(tournamentID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found or not available"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+
+	scope, scopeID, err := parseStandingsScope(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	standings, err := s.ComputeStandings(tournamentID, scope, scopeID, nil)
+	if err != nil {
+		log.Printf("ERROR computing standings for tournament %s: %v", tournamentID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "failed to compute standings"})
+	}
+
+	page := c.QueryInt("page", 1)
+	size := c.QueryInt("size", 50)
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > 100 {
+		size = 50
+	}
+
+	total := len(standings)
+	start := (page - 1) * size
+	if start > total {
+		start = total
+	}
+	end := start + size
+	if end > total {
+		end = total
+	}
+
+	resp := fiber.Map{
+		"tournament_id": tournamentID,
+		"scope":         scope,
+		"scope_id":      scopeID,
+		"data":          standings[start:end],
+		"page":          page,
+		"size":          size,
+		"total":         total,
+		"total_pages":   (total + size - 1) / size,
+	}
+
+	// "Where am I overall"
+	if userID := c.Query("user_id"); userID != "" {
+		for _, st := range standings {
+			if st.UserID == userID {
+				resp["me"] = st
+				break
+			}
+		}
+	}
+
+	return c.JSON(resp)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"game-publish-system/models"
+)
+
+func TestAggregateStandingsTies(t *testing.T) {
+	base := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	entry := func(userID, roundID string, score int64, minute int) models.LeaderboardEntry {
+		return models.LeaderboardEntry{UserID: userID, RoundID: roundID, Score: score, SubmittedAt: base.Add(time.Duration(minute) * time.Minute)}
+	}
+
+	tests := []struct {
+		name       string
+		scoreTypes map[string]string
+		entries    []models.LeaderboardEntry
+		want       []string // user IDs by rank
+	}{
+		{
+			"higher score wins whenever it was reached",
+			map[string]string{"r1": "highest"},
+			[]models.LeaderboardEntry{entry("a", "r1", 10, 1), entry("b", "r1", 20, 9)},
+			[]string{"b", "a"},
+		},
+		{
+			// b finishes later but started first
+			"sum tie goes to the earlier first attempt",
+			map[string]string{"r1": "sum"},
+			[]models.LeaderboardEntry{
+				entry("a", "r1", 50, 5), entry("a", "r1", 50, 6),
+				entry("b", "r1", 30, 1), entry("b", "r1", 70, 10),
+			},
+			[]string{"b", "a"},
+		},
+		{
+			"average tie goes to the earlier first attempt",
+			map[string]string{"r1": "average"},
+			[]models.LeaderboardEntry{
+				entry("a", "r1", 80, 2), entry("a", "r1", 80, 9),
+				entry("b", "r1", 60, 3), entry("b", "r1", 100, 4),
+			},
+			[]string{"a", "b"},
+		},
+		{
+			"highest tie goes to the earlier first attempt",
+			map[string]string{"r1": "highest"},
+			[]models.LeaderboardEntry{
+				entry("a", "r1", 90, 3),
+				entry("b", "r1", 10, 1), entry("b", "r1", 90, 8),
+			},
+			[]string{"b", "a"},
+		},
+		{
+			"same first attempt falls back to the best single attempt",
+			map[string]string{"r1": "sum"},
+			[]models.LeaderboardEntry{
+				entry("a", "r1", 40, 1), entry("a", "r1", 60, 2),
+				entry("b", "r1", 10, 1), entry("b", "r1", 90, 5),
+			},
+			[]string{"b", "a"},
+		},
+		{
+			"first attempt counts across rounds",
+			map[string]string{"r1": "highest", "r2": "sum"},
+			[]models.LeaderboardEntry{
+				entry("a", "r1", 50, 4), entry("a", "r2", 50, 5),
+				entry("b", "r2", 100, 3),
+			},
+			[]string{"b", "a"},
+		},
+		{
+			"full tie falls back to the user ID",
+			map[string]string{"r1": "sum"},
+			[]models.LeaderboardEntry{entry("b", "r1", 50, 1), entry("a", "r1", 50, 1)},
+			[]string{"a", "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := aggregateStandings(tt.entries, tt.scoreTypes)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d standings, want %d", len(got), len(tt.want))
+			}
+			for i, st := range got {
+				if st.UserID != tt.want[i] || st.Rank != i+1 {
+					t.Errorf("rank %d = %s (rank %d), want %s", i+1, st.UserID, st.Rank, tt.want[i])
+				}
+			}
+		})
+	}
+}
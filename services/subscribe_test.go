@@ -0,0 +1,165 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"game-publish-system/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to TEST_DATABASE_URL and migrates the given models; the test is skipped
+// when no test database is configured
+func openTestDB(t *testing.T, tables ...interface{}) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := db.AutoMigrate(tables...); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("db: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(40)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// createTestTournament adds a published tournament (and its game) that is removed with its
+// subscriptions and waitlist when the test ends
+func createTestTournament(t *testing.T, db *gorm.DB, tournament models.Tournament) models.Tournament {
+	t.Helper()
+	game := models.Game{ID: uuid.NewString(), Name: "test-" + uuid.NewString()[:8], Status: "draft"}
+	if err := db.Create(&game).Error; err != nil {
+		t.Fatalf("create game: %v", err)
+	}
+	tournament.ID = uuid.NewString()
+	tournament.GameID = game.ID
+	if tournament.Name == "" {
+		tournament.Name = "test tournament"
+	}
+	if tournament.Status == "" {
+		tournament.Status = models.TournamentStatusPublished
+	}
+	if tournament.StartTime.IsZero() {
+		tournament.StartTime = time.Now().Add(24 * time.Hour)
+	}
+	if err := db.Omit("Game").Create(&tournament).Error; err != nil {
+		t.Fatalf("create tournament: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("tournament_id = ?", tournament.ID).Delete(&models.WaitlistEntry{})
+		db.Where("tournament_id = ?", tournament.ID).Delete(&models.TournamentSubscription{})
+		db.Unscoped().Delete(&tournament)
+		db.Unscoped().Delete(&game)
+	})
+	return tournament
+}
+
+// TestSubscribeConcurrently fires concurrent subscribes, several per user, and checks that
+// MaxSubscribers and one subscription per user both hold
+func TestSubscribeConcurrently(t *testing.T) {
+	db := openTestDB(t, &models.Game{}, &models.Tournament{}, &models.TournamentSubscription{},
+		&models.UserWaiver{}, &models.Team{}, &models.TeamMember{}, &models.WaitlistEntry{})
+
+	tests := []struct {
+		name     string
+		users    int
+		repeats  int
+		capacity int
+	}{
+		{"more users than slots", 120, 2, 25},
+		{"fewer users than slots", 10, 3, 25},
+		{"single slot", 40, 2, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tournament := createTestTournament(t, db, models.Tournament{MaxSubscribers: tt.capacity})
+
+			service := &TournamentService{DB: db}
+			app := fiber.New()
+			app.Post("/tournaments/:id/subscribe", func(c *fiber.Ctx) error {
+				c.Locals("user_id", c.Get("X-User-ID"))
+				return c.Next()
+			}, service.SubscribeToTournament)
+
+			var (
+				mu       sync.Mutex
+				statuses = map[int]int{}
+				wg       sync.WaitGroup
+				start    = make(chan struct{})
+			)
+			for u := 0; u < tt.users; u++ {
+				userID := uuid.NewString()
+				for r := 0; r < tt.repeats; r++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						body, _ := json.Marshal(map[string]interface{}{
+							"external_user_id": userID,
+							"user_name":        "player-" + userID[:8],
+							"payment_status":   "waived",
+						})
+						req := httptest.NewRequest("POST", "/tournaments/"+tournament.ID+"/subscribe", bytes.NewReader(body))
+						req.Header.Set("Content-Type", "application/json")
+						req.Header.Set("X-User-ID", userID)
+						<-start
+						code := 0
+						if resp, err := app.Test(req, -1); err == nil {
+							code = resp.StatusCode
+							resp.Body.Close()
+						}
+						mu.Lock()
+						statuses[code]++
+						mu.Unlock()
+					}()
+				}
+			}
+			close(start)
+			wg.Wait()
+
+			var subs int64
+			if err := db.Model(&models.TournamentSubscription{}).Where("tournament_id = ?", tournament.ID).Count(&subs).Error; err != nil {
+				t.Fatalf("count subscriptions: %v", err)
+			}
+			var dupes int64
+			if err := db.Raw(`SELECT COUNT(*) FROM (SELECT external_user_id FROM tournament_subscriptions
+				WHERE tournament_id = ? GROUP BY external_user_id HAVING COUNT(*) > 1) d`, tournament.ID).Scan(&dupes).Error; err != nil {
+				t.Fatalf("count duplicates: %v", err)
+			}
+
+			if want := int64(min(tt.users, tt.capacity)); subs != want {
+				t.Errorf("subscriptions = %d, want %d (statuses %v)", subs, want, statuses)
+			}
+			if dupes > 0 {
+				t.Errorf("%d users hold more than one subscription", dupes)
+			}
+			if int64(statuses[201]) != subs {
+				t.Errorf("%d calls returned 201 but %d subscriptions exist", statuses[201], subs)
+			}
+			if statuses[500] > 0 || statuses[0] > 0 {
+				t.Errorf("%d server errors, %d transport errors", statuses[500], statuses[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Swiss scoring
+const (
+	swissWinPoints  = 1.0
+	swissDrawPoints = 0.5
+	swissByePoints  = 1.0
+
+	// swissMaxSteps bounds the no-rematch search before falling back to allowing rematches
+	swissMaxSteps = 200000
+)
+
+var ErrSwissRoundIncomplete = errors.New("previous swiss round still has unreported results")
+
+// SwissStanding is one player's record across the published rounds of a Swiss match
+type SwissStanding struct {
+	Rank            int      `json:"rank"`
+	UserID          string   `json:"user_id"`
+	UserName        string   `json:"user_name"`
+	Points          float64  `json:"points"`
+	Wins            int      `json:"wins"`
+	Draws           int      `json:"draws"`
+	Losses          int      `json:"losses"`
+	Byes            int      `json:"byes"`
+	Buchholz        float64  `json:"buchholz"`         // sum of opponents' points
+	SonnebornBerger float64  `json:"sonneborn_berger"` // points of beaten opponents + half of drawn ones
+	Opponents       []string `json:"opponents"`
+}
+
+// swissGame is one game from a player's perspective (opponent "" = bye)
+type swissGame struct {
+	opponent string
+	points   float64
+	reported bool
+}
+
+// swissHistory is everything earlier rounds tell us about a Swiss match
+type swissHistory struct {
+	rounds     int
+	games      map[string][]swissGame
+	met        map[string]map[string]bool
+	unreported int
+}
+
+func (h *swissHistory) meet(a, b string) {
+	if h.met[a] == nil {
+		h.met[a] = make(map[string]bool)
+	}
+	h.met[a][b] = true
+}
+
+// loadSwissHistory rebuilds games and points from a match's published pairings and their results.
+// Every published pairing is one round.
+func (ps *PairingService) loadSwissHistory(matchID string) (*swissHistory, error) {
+	var pairings []models.MatchPairing
+	if err := ps.DB.Where("match_id = ? AND status = ?", matchID, "published").
+		Order("published_at ASC").
+		Find(&pairings).Error; err != nil {
+		return nil, err
+	}
+
+	h := &swissHistory{
+		games: make(map[string][]swissGame),
+		met:   make(map[string]map[string]bool),
+	}
+	if len(pairings) == 0 {
+		return h, nil
+	}
+
+	pairingIDs := make([]string, len(pairings))
+	for i, p := range pairings {
+		pairingIDs[i] = p.ID
+	}
+	var results []models.PairingResult
+	if err := ps.DB.Where("pairing_id IN ?", pairingIDs).Find(&results).Error; err != nil {
+		return nil, err
+	}
+	byPair := make(map[string]models.PairingResult, len(results))
+	for _, r := range results {
+		byPair[fmt.Sprintf("%s/%d", r.PairingID, r.MatchNumber)] = r
+	}
+
+	for _, pairing := range pairings {
+		var pairs []Pair
+		if err := json.Unmarshal([]byte(pairing.PairsJSON), &pairs); err != nil {
+			return nil, err
+		}
+		h.rounds++
+
+		for _, pair := range pairs {
+			if pair.Player2ID == "" {
+				h.games[pair.Player1ID] = append(h.games[pair.Player1ID], swissGame{points: swissByePoints, reported: true})
+				continue
+			}
+			h.meet(pair.Player1ID, pair.Player2ID)
+			h.meet(pair.Player2ID, pair.Player1ID)
+
+			p1 := swissGame{opponent: pair.Player2ID}
+			p2 := swissGame{opponent: pair.Player1ID}
+			if r, ok := byPair[fmt.Sprintf("%s/%d", pairing.ID, pair.MatchNumber)]; ok {
+				p1.reported, p2.reported = true, true
+				switch r.Outcome {
+				case models.PairingOutcomePlayer1:
+					p1.points = swissWinPoints
+				case models.PairingOutcomePlayer2:
+					p2.points = swissWinPoints
+				case models.PairingOutcomeDraw:
+					p1.points, p2.points = swissDrawPoints, swissDrawPoints
+				}
+			} else {
+				h.unreported++
+			}
+			h.games[pair.Player1ID] = append(h.games[pair.Player1ID], p1)
+			h.games[pair.Player2ID] = append(h.games[pair.Player2ID], p2)
+		}
+	}
+	return h, nil
+}
+
+// swissStandings ranks players by points, then Buchholz, then Sonneborn-Berger.
+// Remaining ties keep the order players were given in (their seeding).
+func swissStandings(players []models.TournamentSubscription, h *swissHistory) []SwissStanding {
+	points := make(map[string]float64)
+	for userID, games := range h.games {
+		for _, g := range games {
+			points[userID] += g.points
+		}
+	}
+
+	standings := make([]SwissStanding, len(players))
+	for i, p := range players {
+		st := SwissStanding{UserID: p.ExternalUserID, UserName: p.UserName, Points: points[p.ExternalUserID], Opponents: []string{}}
+		for _, g := range h.games[p.ExternalUserID] {
+			if g.opponent == "" {
+				st.Byes++
+				continue
+			}
+			st.Opponents = append(st.Opponents, g.opponent)
+			st.Buchholz += points[g.opponent]
+			if !g.reported {
+				continue
+			}
+			switch g.points {
+			case swissWinPoints:
+				st.Wins++
+				st.SonnebornBerger += points[g.opponent]
+			case swissDrawPoints:
+				st.Draws++
+				st.SonnebornBerger += points[g.opponent] / 2
+			default:
+				st.Losses++
+			}
+		}
+		standings[i] = st
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Points != b.Points {
+			return a.Points > b.Points
+		}
+		if a.Buchholz != b.Buchholz {
+			return a.Buchholz > b.Buchholz
+		}
+		return a.SonnebornBerger > b.SonnebornBerger
+	})
+	for i := range standings {
+		standings[i].Rank = i + 1
+	}
+	return standings
+}
+
+// swissCandidates orders possible opponents for the top remaining player: the upper half of
+// their score group meets the lower half (1 v n/2+1), then players from lower groups float up
+func swissCandidates(player string, rest []string, points map[string]float64) []string {
+	group := 0
+	for group < len(rest) && points[rest[group]] == points[player] {
+		group++
+	}
+	half := (group+1)/2 - 1
+	if half < 0 {
+		half = 0
+	}
+
+	candidates := make([]string, 0, len(rest))
+	candidates = append(candidates, rest[half:group]...)
+	candidates = append(candidates, rest[:half]...)
+	candidates = append(candidates, rest[group:]...)
+	return candidates
+}
+
+// swissPairRound pairs players (in rank order) by backtracking so nobody meets an earlier
+// opponent. Returns nil if no such pairing exists or the search budget runs out.
+func swissPairRound(order []string, points map[string]float64, met map[string]map[string]bool, allowRematch bool) [][2]string {
+	steps := 0
+	var result [][2]string
+
+	var solve func(remaining []string) bool
+	solve = func(remaining []string) bool {
+		if len(remaining) == 0 {
+			return true
+		}
+		steps++
+		if steps > swissMaxSteps {
+			return false
+		}
+
+		player, rest := remaining[0], remaining[1:]
+		for _, opponent := range swissCandidates(player, rest, points) {
+			if !allowRematch && met[player][opponent] {
+				continue
+			}
+			next := make([]string, 0, len(rest)-1)
+			for _, r := range rest {
+				if r != opponent {
+					next = append(next, r)
+				}
+			}
+			result = append(result, [2]string{player, opponent})
+			if solve(next) {
+				return true
+			}
+			result = result[:len(result)-1]
+		}
+		return false
+	}
+
+	if solve(order) {
+		return result
+	}
+	return nil
+}
+
+// generateSwissPairs pairs the next Swiss round from the match history: players are ranked
+// by points and tiebreaks, paired within score groups without rematches, and an odd player
+// out gets a bye (the lowest-ranked player who hasn't had one yet).
+func (ps *PairingService) generateSwissPairs(players []models.TournamentSubscription, h *swissHistory) ([]Pair, map[string]interface{}) {
+	round := h.rounds + 1
+	standings := swissStandings(players, h)
+
+	names := make(map[string]string, len(standings))
+	points := make(map[string]float64, len(standings))
+	order := make([]string, 0, len(standings))
+	for _, st := range standings {
+		names[st.UserID] = st.UserName
+		points[st.UserID] = st.Points
+		order = append(order, st.UserID)
+	}
+
+	byePlayer := ""
+	if len(order)%2 != 0 {
+		byeIdx := len(order) - 1
+		for i := len(order) - 1; i >= 0; i-- {
+			if standings[i].Byes == 0 {
+				byeIdx = i
+				break
+			}
+		}
+		byePlayer = order[byeIdx]
+		order = append(order[:byeIdx:byeIdx], order[byeIdx+1:]...)
+	}
+
+	rematches := false
+	matched := swissPairRound(order, points, h.met, false)
+	if matched == nil {
+		matched = swissPairRound(order, points, h.met, true)
+		rematches = true
+	}
+
+	var pairs []Pair
+	for i, m := range matched {
+		pairs = append(pairs, Pair{
+			Player1ID:   m[0],
+			Player1Name: names[m[0]],
+			Player2ID:   m[1],
+			Player2Name: names[m[1]],
+			MatchNumber: i + 1,
+			RoundNumber: round,
+		})
+	}
+	if byePlayer != "" {
+		pairs = append(pairs, Pair{
+			Player1ID:   byePlayer,
+			Player1Name: names[byePlayer],
+			MatchNumber: len(pairs) + 1,
+			RoundNumber: round,
+		})
+	}
+
+	metadata := map[string]interface{}{
+		"format":            "swiss",
+		"round":             round,
+		"total_players":     len(players),
+		"pairing_rule":      "score_groups_no_rematch",
+		"bye_player":        byePlayer,
+		"rematches_allowed": rematches,
+		"standings":         standings,
+	}
+
+	return pairs, metadata
+}
+
+// GetSwissStandings returns points and tiebreaks for a Swiss match
+func (ps *PairingService) GetSwissStandings(c *fiber.Ctx) error {
+	matchID := c.Params("match_id")
+
+	var match models.TournamentMatch
+	if err := ps.DB.First(&match, "id = ?", matchID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "match not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "database error"})
+	}
+
+	var batch models.TournamentBatch
+	if err := ps.DB.First(&batch, "id = ?", match.BatchID).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch batch"})
+	}
+
+	players, err := ps.getEligiblePlayerList(batch.TournamentID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch eligible players"})
+	}
+
+	h, err := ps.loadSwissHistory(match.ID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to load swiss history", "details": err.Error()})
+	}
+
+	return c.JSON(fiber.Map{
+		"match_id":         match.ID,
+		"rounds_played":    h.rounds,
+		"unreported_games": h.unreported,
+		"standings":        swissStandings(players, h),
+	})
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"testing"
+)
+
+// swissRounds builds a history from played rounds the way loadSwissHistory does. Each game is
+// {player1, player2, winner}: an empty player2 is a bye and an empty winner a draw.
+func swissRounds(rounds ...[][3]string) *swissHistory {
+	h := &swissHistory{
+		games: make(map[string][]swissGame),
+		met:   make(map[string]map[string]bool),
+	}
+	for _, games := range rounds {
+		h.rounds++
+		for _, g := range games {
+			p1, p2, winner := g[0], g[1], g[2]
+			if p2 == "" {
+				h.games[p1] = append(h.games[p1], swissGame{points: swissByePoints, reported: true})
+				continue
+			}
+			h.meet(p1, p2)
+			h.meet(p2, p1)
+			a := swissGame{opponent: p2, reported: true}
+			b := swissGame{opponent: p1, reported: true}
+			switch winner {
+			case p1:
+				a.points = swissWinPoints
+			case p2:
+				b.points = swissWinPoints
+			default:
+				a.points, b.points = swissDrawPoints, swissDrawPoints
+			}
+			h.games[p1] = append(h.games[p1], a)
+			h.games[p2] = append(h.games[p2], b)
+		}
+	}
+	return h
+}
+
+func TestGenerateSwissPairs(t *testing.T) {
+	tests := []struct {
+		name        string
+		players     int
+		history     *swissHistory
+		want        [][2]string // pairs in order; a bye is {player, ""}
+		wantRematch bool
+	}{
+		{
+			"first round pairs the top half against the bottom half",
+			4, swissRounds(),
+			[][2]string{{"p1", "p3"}, {"p2", "p4"}}, false,
+		},
+		{
+			"odd field gives the lowest seed the first bye",
+			5, swissRounds(),
+			[][2]string{{"p1", "p3"}, {"p2", "p4"}, {"p5", ""}}, false,
+		},
+		{
+			// p1 has already met both 1-point players, so it drops to p4
+			"avoidable rematch is avoided",
+			4, swissRounds(
+				[][3]string{{"p1", "p3", "p1"}, {"p2", "p4", "p2"}},
+				[][3]string{{"p1", "p2", "p1"}, {"p3", "p4", "p3"}},
+			),
+			[][2]string{{"p1", "p4"}, {"p2", "p3"}}, false,
+		},
+		{
+			"unavoidable rematch is allowed",
+			2, swissRounds([][3]string{{"p1", "p2", "p1"}}),
+			[][2]string{{"p1", "p2"}}, true,
+		},
+		{
+			// p5 is on a point from its bye; p4 is last among those without one
+			"bye skips a player who already had one",
+			5, swissRounds([][3]string{{"p1", "p3", "p1"}, {"p2", "p4", "p2"}, {"p5", "", ""}}),
+			[][2]string{{"p1", "p2"}, {"p5", "p3"}, {"p4", ""}}, false,
+		},
+		{
+			// p2 and p3 have both had a bye, so it goes up to the leader
+			"bye reaches the top when everyone below has had one",
+			3, swissRounds(
+				[][3]string{{"p1", "p2", "p1"}, {"p3", "", ""}},
+				[][3]string{{"p1", "p3", "p1"}, {"p2", "", ""}},
+			),
+			[][2]string{{"p2", "p3"}, {"p1", ""}}, false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pairs, metadata := (&PairingService{}).generateSwissPairs(testEntrants(tt.players), tt.history)
+			if len(pairs) != len(tt.want) {
+				t.Fatalf("%d pairs, want %d: %+v", len(pairs), len(tt.want), pairs)
+			}
+
+			seen := map[string]bool{}
+			for i, p := range pairs {
+				if got := [2]string{p.Player1ID, p.Player2ID}; got != tt.want[i] {
+					t.Errorf("pair %d = %v, want %v", i+1, got, tt.want[i])
+				}
+				if p.MatchNumber != i+1 || p.RoundNumber != tt.history.rounds+1 {
+					t.Errorf("pair %d numbered match %d round %d", i+1, p.MatchNumber, p.RoundNumber)
+				}
+				for _, id := range []string{p.Player1ID, p.Player2ID} {
+					if id != "" && seen[id] {
+						t.Errorf("%s is paired twice", id)
+					}
+					seen[id] = true
+				}
+				if !tt.wantRematch && tt.history.met[p.Player1ID][p.Player2ID] {
+					t.Errorf("%s and %s meet again", p.Player1ID, p.Player2ID)
+				}
+			}
+			delete(seen, "")
+			if len(seen) != tt.players {
+				t.Errorf("%d players paired, want %d", len(seen), tt.players)
+			}
+			if metadata["rematches_allowed"] != tt.wantRematch {
+				t.Errorf("rematches_allowed = %v, want %v", metadata["rematches_allowed"], tt.wantRematch)
+			}
+		})
+	}
+}
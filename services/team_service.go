@@ -0,0 +1,471 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"game-publish-system/utils"
+	"log"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTeamNotFound       = errors.New("team not found")
+	ErrTeamRequired       = errors.New("team_id is required for team-based tournaments")
+	ErrNotTeamBased       = errors.New("this tournament is not team-based")
+	ErrNotTeamCaptain     = errors.New("only the team captain can do this")
+	ErrTeamNameTaken      = errors.New("team name is already taken")
+	ErrTeamRosterSize     = errors.New("team roster size is outside the tournament limits")
+	ErrTeamRosterConflict = errors.New("a roster member is already entered with another team")
+	ErrTeamRosterLocked   = errors.New("roster is locked while the team plays an active tournament")
+	ErrAlreadyTeamMember  = errors.New("user is already on the team")
+	ErrTeamMemberNotFound = errors.New("user is not on the team")
+	ErrCaptainMustStay    = errors.New("transfer the captaincy before the captain leaves")
+)
+
+// loadTeam fetches a team with its roster
+func loadTeam(db *gorm.DB, teamID string) (*models.Team, error) {
+	var team models.Team
+	if err := db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("joined_at ASC")
+	}).First(&team, "id = ?", teamID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, err
+	}
+	return &team, nil
+}
+
+// checkTeamSize validates a roster size against a tournament's limits (0 = no limit)
+func checkTeamSize(tournament *models.Tournament, size int) error {
+	if tournament.TeamSizeMin > 0 && size < tournament.TeamSizeMin {
+		return fmt.Errorf("%w: %d players, minimum %d", ErrTeamRosterSize, size, tournament.TeamSizeMin)
+	}
+	if tournament.TeamSizeMax > 0 && size > tournament.TeamSizeMax {
+		return fmt.Errorf("%w: %d players, maximum %d", ErrTeamRosterSize, size, tournament.TeamSizeMax)
+	}
+	return nil
+}
+
+// parseTeamSettings reads is_team_based, team_size_min and team_size_max from a tournament form
+func parseTeamSettings(c *fiber.Ctx) (bool, int, int, error) {
+	isTeamBased := strings.ToLower(c.FormValue("is_team_based")) == "true"
+	sizes := [2]int{}
+	for i, key := range []string{"team_size_min", "team_size_max"} {
+		if v := c.FormValue(key); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n < 0 {
+				return false, 0, 0, fmt.Errorf("%s must be a non-negative integer", key)
+			}
+			sizes[i] = n
+		}
+	}
+	if sizes[1] > 0 && sizes[0] > sizes[1] {
+		return false, 0, 0, errors.New("team_size_min cannot exceed team_size_max")
+	}
+	return isTeamBased, sizes[0], sizes[1], nil
+}
+
+// validateTeamEntry checks that a team may enter a team-based tournament: the subscriber is
+// its captain, the roster fits the size limits, and nobody on it already plays for another
+// team in the same tournament
+func (s *TournamentService) validateTeamEntry(tournament *models.Tournament, teamID, captainID string) (*models.Team, error) {
+	if teamID == "" {
+		return nil, ErrTeamRequired
+	}
+	team, err := loadTeam(s.DB, teamID)
+	if err != nil {
+		return nil, err
+	}
+	if team.CaptainID != captainID {
+		return nil, ErrNotTeamCaptain
+	}
+	if err := checkTeamSize(tournament, len(team.Members)); err != nil {
+		return nil, err
+	}
+
+	memberIDs := make([]string, len(team.Members))
+	for i, m := range team.Members {
+		memberIDs[i] = m.ExternalUserID
+	}
+	var conflicts int64
+	if err := s.DB.Model(&models.TeamMember{}).
+		Where("external_user_id IN ?", memberIDs).
+		Where("team_id IN (?)", s.DB.Model(&models.TournamentSubscription{}).
+			Select("team_id").
+			Where("tournament_id = ? AND team_id <> '' AND team_id <> ? AND revoked_at IS NULL", tournament.ID, team.ID)).
+		Count(&conflicts).Error; err != nil {
+		return nil, err
+	}
+	if conflicts > 0 {
+		return nil, ErrTeamRosterConflict
+	}
+	return team, nil
+}
+
+// checkRosterChange makes sure a roster of newSize still fits every unfinished tournament the
+// team is entered in. Rosters are frozen while one of those tournaments is being played.
+func checkRosterChange(tx *gorm.DB, teamID string, newSize int) error {
+	var tournaments []models.Tournament
+	if err := tx.Where("id IN (?)", tx.Model(&models.TournamentSubscription{}).
+		Select("tournament_id").
+		Where("team_id = ? AND revoked_at IS NULL", teamID)).
+		Where("status NOT IN ?", []string{models.TournamentStatusCompleted, models.TournamentStatusCancelled}).
+		Find(&tournaments).Error; err != nil {
+		return err
+	}
+	for i := range tournaments {
+		if tournaments[i].Status == models.TournamentStatusActive {
+			return ErrTeamRosterLocked
+		}
+		if err := checkTeamSize(&tournaments[i], newSize); err != nil {
+			return fmt.Errorf("%w (tournament %s)", err, tournaments[i].Name)
+		}
+	}
+	return nil
+}
+
+// resultEntrant maps a user reporting a result to the side they report for: themselves, or
+// the team they captain. Returns "" if the user is on neither side.
+func resultEntrant(db *gorm.DB, userID string, result *models.MatchResult) string {
+	if userID == result.Player1ID || userID == result.Player2ID {
+		return userID
+	}
+	var team models.Team
+	if err := db.Where("id IN ? AND captain_id = ?", []string{result.Player1ID, result.Player2ID}, userID).
+		First(&team).Error; err != nil {
+		return ""
+	}
+	return team.ID
+}
+
+// uploadTeamLogo stores an optional "logo" form file on R2
+func uploadTeamLogo(c *fiber.Ctx) (string, error) {
+	logoFile, err := c.FormFile("logo")
+	if err != nil || logoFile.Size == 0 {
+		return "", nil
+	}
+	ext := filepath.Ext(logoFile.Filename)
+	if ext == "" {
+		ext = ".png"
+	}
+	return utils.UploadFileToR2(logoFile, "teams/logos/"+uuid.NewString()+ext)
+}
+
+// CreateTeam creates a team with the calling user as captain and first member
+func (s *TournamentService) CreateTeam(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(401).JSON(fiber.Map{"error": "user context required"})
+	}
+
+	name := strings.TrimSpace(c.FormValue("name"))
+	captainName := strings.TrimSpace(c.FormValue("captain_name"))
+	if name == "" || captainName == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "name and captain_name are required"})
+	}
+
+	var existing int64
+	s.DB.Model(&models.Team{}).Where("LOWER(name) = ?", strings.ToLower(name)).Count(&existing)
+	if existing > 0 {
+		return teamErrorResponse(c, ErrTeamNameTaken)
+	}
+
+	logoURL, err := uploadTeamLogo(c)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to upload team logo"})
+	}
+
+	team := models.Team{
+		ID:          uuid.NewString(),
+		Name:        name,
+		Tag:         strings.ToUpper(strings.TrimSpace(c.FormValue("tag"))),
+		LogoURL:     logoURL,
+		CaptainID:   userID,
+		CaptainName: captainName,
+	}
+	captain := models.TeamMember{
+		ID:             uuid.NewString(),
+		TeamID:         team.ID,
+		ExternalUserID: userID,
+		UserName:       captainName,
+		UserAvatarURL:  c.FormValue("captain_avatar_url"),
+		Role:           models.TeamRoleCaptain,
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Members").Create(&team).Error; err != nil {
+			return err
+		}
+		return tx.Create(&captain).Error
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to create team", "details": err.Error()})
+	}
+
+	team.Members = []models.TeamMember{captain}
+	log.Printf("👥 Team %s (%s) created by %s", team.Name, team.ID, userID)
+	return c.Status(201).JSON(team)
+}
+
+// GetTeam returns a team with its roster
+func (s *TournamentService) GetTeam(c *fiber.Ctx) error {
+	team, err := loadTeam(s.DB, c.Params("team_id"))
+	if err != nil {
+		return teamErrorResponse(c, err)
+	}
+	return c.JSON(team)
+}
+
+// GetMyTeams lists the teams the calling user is on
+func (s *TournamentService) GetMyTeams(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+
+	var teams []models.Team
+	if err := s.DB.Preload("Members").
+		Where("id IN (?)", s.DB.Model(&models.TeamMember{}).Select("team_id").Where("external_user_id = ?", userID)).
+		Order("name ASC").
+		Find(&teams).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch teams"})
+	}
+	return c.JSON(fiber.Map{"teams": teams, "count": len(teams)})
+}
+
+// UpdateTeam lets the captain rename the team or replace its tag and logo
+func (s *TournamentService) UpdateTeam(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	team, err := loadTeam(s.DB, c.Params("team_id"))
+	if err != nil {
+		return teamErrorResponse(c, err)
+	}
+	if team.CaptainID != userID {
+		return teamErrorResponse(c, ErrNotTeamCaptain)
+	}
+
+	updates := map[string]interface{}{}
+	if name := strings.TrimSpace(c.FormValue("name")); name != "" && name != team.Name {
+		var existing int64
+		s.DB.Model(&models.Team{}).Where("LOWER(name) = ? AND id <> ?", strings.ToLower(name), team.ID).Count(&existing)
+		if existing > 0 {
+			return teamErrorResponse(c, ErrTeamNameTaken)
+		}
+		updates["name"] = name
+	}
+	if tag := c.FormValue("tag"); tag != "" {
+		updates["tag"] = strings.ToUpper(strings.TrimSpace(tag))
+	}
+	logoURL, err := uploadTeamLogo(c)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to upload team logo"})
+	}
+	if logoURL != "" {
+		updates["logo_url"] = logoURL
+	}
+	if len(updates) == 0 {
+		return c.JSON(team)
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(team).Updates(updates).Error; err != nil {
+			return err
+		}
+		// Team entries carry the team name as their display name
+		if name, ok := updates["name"]; ok {
+			return tx.Model(&models.TournamentSubscription{}).
+				Where("team_id = ?", team.ID).
+				Update("user_name", name).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to update team", "details": err.Error()})
+	}
+
+	team, err = loadTeam(s.DB, team.ID)
+	if err != nil {
+		return teamErrorResponse(c, err)
+	}
+	return c.JSON(team)
+}
+
+// AddTeamMember lets the captain add a player to the roster
+func (s *TournamentService) AddTeamMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	teamID := c.Params("team_id")
+
+	var req struct {
+		ExternalUserID string `json:"external_user_id"`
+		UserName       string `json:"user_name"`
+		UserAvatarURL  string `json:"user_avatar_url,omitempty"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+	if req.ExternalUserID == "" || req.UserName == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "external_user_id and user_name are required"})
+	}
+
+	member := models.TeamMember{
+		ID:             uuid.NewString(),
+		TeamID:         teamID,
+		ExternalUserID: req.ExternalUserID,
+		UserName:       req.UserName,
+		UserAvatarURL:  req.UserAvatarURL,
+		Role:           models.TeamRoleMember,
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var team models.Team
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&team, "id = ?", teamID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		if team.CaptainID != userID {
+			return ErrNotTeamCaptain
+		}
+
+		var size, already int64
+		tx.Model(&models.TeamMember{}).Where("team_id = ?", teamID).Count(&size)
+		tx.Model(&models.TeamMember{}).Where("team_id = ? AND external_user_id = ?", teamID, req.ExternalUserID).Count(&already)
+		if already > 0 {
+			return ErrAlreadyTeamMember
+		}
+		if err := checkRosterChange(tx, teamID, int(size)+1); err != nil {
+			return err
+		}
+		return tx.Create(&member).Error
+	})
+	if err != nil {
+		return teamErrorResponse(c, err)
+	}
+
+	return c.Status(201).JSON(member)
+}
+
+// RemoveTeamMember removes a player from the roster: the captain can remove anyone else,
+// and members can remove themselves
+func (s *TournamentService) RemoveTeamMember(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	teamID := c.Params("team_id")
+	memberID := c.Params("user_id")
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var team models.Team
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&team, "id = ?", teamID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		if team.CaptainID != userID && memberID != userID {
+			return ErrNotTeamCaptain
+		}
+		if memberID == team.CaptainID {
+			return ErrCaptainMustStay
+		}
+
+		var size int64
+		tx.Model(&models.TeamMember{}).Where("team_id = ?", teamID).Count(&size)
+		if err := checkRosterChange(tx, teamID, int(size)-1); err != nil {
+			return err
+		}
+
+		res := tx.Where("team_id = ? AND external_user_id = ?", teamID, memberID).Delete(&models.TeamMember{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return ErrTeamMemberNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return teamErrorResponse(c, err)
+	}
+
+	return c.JSON(fiber.Map{"message": "member removed", "team_id": teamID, "user_id": memberID})
+}
+
+// TransferTeamCaptain hands the captaincy to another roster member
+func (s *TournamentService) TransferTeamCaptain(c *fiber.Ctx) error {
+	userID := c.Locals("user_id").(string)
+	teamID := c.Params("team_id")
+
+	var req struct {
+		ExternalUserID string `json:"external_user_id"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.ExternalUserID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "external_user_id is required"})
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var team models.Team
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&team, "id = ?", teamID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		if team.CaptainID != userID {
+			return ErrNotTeamCaptain
+		}
+
+		var next models.TeamMember
+		if err := tx.Where("team_id = ? AND external_user_id = ?", teamID, req.ExternalUserID).First(&next).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTeamMemberNotFound
+			}
+			return err
+		}
+
+		if err := tx.Model(&models.TeamMember{}).
+			Where("team_id = ? AND external_user_id = ?", teamID, team.CaptainID).
+			Update("role", models.TeamRoleMember).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&next).Update("role", models.TeamRoleCaptain).Error; err != nil {
+			return err
+		}
+		return tx.Model(&team).Updates(map[string]interface{}{
+			"captain_id":   next.ExternalUserID,
+			"captain_name": next.UserName,
+		}).Error
+	})
+	if err != nil {
+		return teamErrorResponse(c, err)
+	}
+
+	team, err := loadTeam(s.DB, teamID)
+	if err != nil {
+		return teamErrorResponse(c, err)
+	}
+	return c.JSON(team)
+}
+
+// teamErrorResponse maps team errors to HTTP responses
+func teamErrorResponse(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, ErrTeamNotFound), errors.Is(err, ErrTeamMemberNotFound):
+		return c.Status(404).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrNotTeamCaptain):
+		return c.Status(403).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrTeamRequired), errors.Is(err, ErrNotTeamBased), errors.Is(err, ErrTeamRosterSize),
+		errors.Is(err, ErrCaptainMustStay):
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, ErrTeamNameTaken), errors.Is(err, ErrTeamRosterConflict), errors.Is(err, ErrTeamRosterLocked),
+		errors.Is(err, ErrAlreadyTeamMember):
+		return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(500).JSON(fiber.Map{"error": "team operation failed", "details": err.Error()})
+	}
+}
@@ -0,0 +1,238 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"game-publish-system/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestCheckTeamSize(t *testing.T) {
+	tests := []struct {
+		min, max, size int
+		wantErr        bool
+	}{
+		{0, 0, 1, false},
+		{0, 0, 50, false},
+		{2, 0, 1, true},
+		{2, 0, 9, false},
+		{0, 5, 5, false},
+		{0, 5, 6, true},
+		{3, 5, 2, true},
+		{3, 5, 3, false},
+		{3, 5, 6, true},
+	}
+	for _, tt := range tests {
+		err := checkTeamSize(&models.Tournament{TeamSizeMin: tt.min, TeamSizeMax: tt.max}, tt.size)
+		if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrTeamRosterSize)) {
+			t.Errorf("checkTeamSize(min %d, max %d, %d) = %v, wantErr %v", tt.min, tt.max, tt.size, err, tt.wantErr)
+		}
+	}
+}
+
+func TestParseTeamSettings(t *testing.T) {
+	type settings struct {
+		TeamBased bool `json:"team_based"`
+		Min       int  `json:"min"`
+		Max       int  `json:"max"`
+	}
+	tests := []struct {
+		name    string
+		form    url.Values
+		want    settings
+		wantErr bool
+	}{
+		{"solo by default", url.Values{}, settings{false, 0, 0}, false},
+		{"team sizes", url.Values{"is_team_based": {"True"}, "team_size_min": {"3"}, "team_size_max": {"5"}}, settings{true, 3, 5}, false},
+		{"minimum only", url.Values{"is_team_based": {"true"}, "team_size_min": {"4"}}, settings{true, 4, 0}, false},
+		{"fixed size", url.Values{"team_size_min": {"5"}, "team_size_max": {"5"}}, settings{false, 5, 5}, false},
+		{"minimum above maximum", url.Values{"team_size_min": {"6"}, "team_size_max": {"5"}}, settings{}, true},
+		{"negative size", url.Values{"team_size_max": {"-1"}}, settings{}, true},
+		{"non-numeric size", url.Values{"team_size_min": {"three"}}, settings{}, true},
+	}
+
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		teamBased, minSize, maxSize, err := parseTeamSettings(c)
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.JSON(settings{teamBased, minSize, maxSize})
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/", strings.NewReader(tt.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+			if tt.wantErr {
+				if resp.StatusCode != 400 {
+					t.Errorf("status = %d, want 400", resp.StatusCode)
+				}
+				return
+			}
+			var got settings
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("decode (status %d): %v", resp.StatusCode, err)
+			}
+			if got != tt.want {
+				t.Errorf("settings = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// createTestTeam adds a team captained by the first of members, removed when the test ends
+func createTestTeam(t *testing.T, db *gorm.DB, members ...string) models.Team {
+	t.Helper()
+	team := models.Team{
+		ID:          uuid.NewString(),
+		Name:        "team-" + uuid.NewString()[:8],
+		CaptainID:   members[0],
+		CaptainName: "captain",
+	}
+	for i, userID := range members {
+		role := models.TeamRoleMember
+		if i == 0 {
+			role = models.TeamRoleCaptain
+		}
+		team.Members = append(team.Members, models.TeamMember{
+			ID:             uuid.NewString(),
+			ExternalUserID: userID,
+			UserName:       "player",
+			Role:           role,
+		})
+	}
+	if err := db.Create(&team).Error; err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Where("team_id = ?", team.ID).Delete(&models.TeamMember{})
+		db.Delete(&team)
+	})
+	return team
+}
+
+func TestValidateTeamEntry(t *testing.T) {
+	db := openTestDB(t, &models.Game{}, &models.Tournament{}, &models.TournamentSubscription{},
+		&models.WaitlistEntry{}, &models.Team{}, &models.TeamMember{})
+	tournament := createTestTournament(t, db, models.Tournament{IsTeamBased: true, TeamSizeMin: 2, TeamSizeMax: 3})
+	service := &TournamentService{DB: db}
+
+	captain, member, shared := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	team := createTestTeam(t, db, captain, member)
+	solo := createTestTeam(t, db, uuid.NewString())
+	crowded := createTestTeam(t, db, uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString())
+	overlapping := createTestTeam(t, db, uuid.NewString(), shared)
+	entered := createTestTeam(t, db, uuid.NewString(), shared)
+	if err := db.Create(&models.TournamentSubscription{
+		ID:             uuid.NewString(),
+		TournamentID:   tournament.ID,
+		ExternalUserID: entered.CaptainID,
+		TeamID:         entered.ID,
+		PaymentStatus:  "waived",
+	}).Error; err != nil {
+		t.Fatalf("enter team: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		teamID    string
+		captainID string
+		wantErr   error
+	}{
+		{"valid entry", team.ID, captain, nil},
+		{"no team", "", captain, ErrTeamRequired},
+		{"unknown team", uuid.NewString(), captain, ErrTeamNotFound},
+		{"entered by a member", team.ID, member, ErrNotTeamCaptain},
+		{"roster too small", solo.ID, solo.CaptainID, ErrTeamRosterSize},
+		{"roster too large", crowded.ID, crowded.CaptainID, ErrTeamRosterSize},
+		{"player already entered with another team", overlapping.ID, overlapping.CaptainID, ErrTeamRosterConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := service.validateTeamEntry(&tournament, tt.teamID, tt.captainID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && len(got.Members) != 2 {
+				t.Errorf("loaded %d members, want 2", len(got.Members))
+			}
+		})
+	}
+}
+
+func TestTransferTeamCaptain(t *testing.T) {
+	db := openTestDB(t, &models.Team{}, &models.TeamMember{})
+	service := &TournamentService{DB: db}
+	app := fiber.New()
+	app.Post("/teams/:team_id/captain", func(c *fiber.Ctx) error {
+		c.Locals("user_id", c.Get("X-User-ID"))
+		return c.Next()
+	}, service.TransferTeamCaptain)
+
+	captain, member := uuid.NewString(), uuid.NewString()
+	team := createTestTeam(t, db, captain, member)
+
+	tests := []struct {
+		name        string
+		teamID      string
+		caller      string
+		next        string
+		wantStatus  int
+		wantCaptain string
+	}{
+		{"missing new captain", team.ID, captain, "", 400, captain},
+		{"unknown team", uuid.NewString(), captain, member, 404, captain},
+		{"member cannot transfer", team.ID, member, member, 403, captain},
+		{"new captain must be on the roster", team.ID, captain, uuid.NewString(), 404, captain},
+		{"captain hands over", team.ID, captain, member, 200, member},
+		{"former captain no longer can", team.ID, captain, captain, 403, member},
+		{"and can get it back", team.ID, member, captain, 200, captain},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(map[string]string{"external_user_id": tt.next})
+			req := httptest.NewRequest("POST", "/teams/"+tt.teamID+"/captain", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("X-User-ID", tt.caller)
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			loaded, err := loadTeam(db, team.ID)
+			if err != nil {
+				t.Fatalf("load team: %v", err)
+			}
+			if loaded.CaptainID != tt.wantCaptain {
+				t.Errorf("captain = %s, want %s", loaded.CaptainID, tt.wantCaptain)
+			}
+			for _, m := range loaded.Members {
+				want := models.TeamRoleMember
+				if m.ExternalUserID == tt.wantCaptain {
+					want = models.TeamRoleCaptain
+				}
+				if m.Role != want {
+					t.Errorf("%s role = %s, want %s", m.ExternalUserID, m.Role, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrTournamentNotCompleted = errors.New("tournament is not completed")
+
+// bracketSectionOrder ranks bracket sections by how late in the event they are played
+var bracketSectionOrder = map[string]int{
+	models.BracketSectionWinners:         1,
+	models.BracketSectionLosers:          2,
+	models.BracketSectionGrandFinal:      3,
+	models.BracketSectionGrandFinalReset: 4,
+}
+
+// FinalizationResult summarises one finalization run
+type FinalizationResult struct {
+	TournamentID string     `json:"tournament_id"`
+	WinnerID     string     `json:"winner_id,omitempty"`
+	WinnerName   string     `json:"winner_name,omitempty"`
+	Recorded     int        `json:"recorded"` // participations created by this run
+	Skipped      int        `json:"skipped"`  // already finalized earlier
+	Failed       int        `json:"failed"`
+	FinalizedAt  *time.Time `json:"finalized_at,omitempty"`
+}
+
+// FinalizeTournament writes a TournamentParticipation (and awards XP) for every active
+// subscriber of a completed tournament, pays out the prize table, then records the winner.
+// Final ranks come from the last match in the format it was played in (see finalPlacings);
+// leaderboard tournaments, and entrants that match did not place, are ranked by the leaderboard.
+// It is idempotent: users that already have a participation row are skipped, and the
+// unique (tournament_id, external_user_id) index rejects concurrent duplicates.
+// FinalizedAt is only set once every subscriber has been recorded, so failures are retried.
+func (s *TournamentService) FinalizeTournament(tournamentID string) (*FinalizationResult, error) {
+	var tournament models.Tournament
+	if err := s.DB.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		return nil, err
+	}
+	if tournament.Status != models.TournamentStatusCompleted {
+		return nil, fmt.Errorf("%w (status %q)", ErrTournamentNotCompleted, tournament.Status)
+	}
+
+	result := &FinalizationResult{TournamentID: tournamentID, FinalizedAt: tournament.FinalizedAt}
+
+	var subs []models.TournamentSubscription
+	if err := s.DB.Where("tournament_id = ? AND payment_status IN ?", tournamentID, activeSubscriptionStatuses).
+		Find(&subs).Error; err != nil {
+		return nil, err
+	}
+	subsByUser := make(map[string]models.TournamentSubscription, len(subs))
+	for _, sub := range subs {
+		subsByUser[sub.ExternalUserID] = sub
+	}
+
+	standings, err := NewStandingsService(s.DB).ComputeStandings(tournamentID, StandingsScopeTournament, "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	placings, err := finalPlacings(s.DB, tournamentID)
+	if err != nil {
+		return nil, err
+	}
+	subsByEntrant := make(map[string]models.TournamentSubscription, len(subs))
+	for _, sub := range subs {
+		subsByEntrant[entrantID(sub)] = sub
+	}
+
+	// Rank among active subscribers only (revoked/refunded players keep their entries but lose
+	// their place). Ties share a rank and the next rank skips past them.
+	finalRanks := make(map[string]int, len(subs))
+	ranked := 0
+	place := func(userIDs []string) {
+		rank := ranked + 1
+		for _, userID := range userIDs {
+			finalRanks[userID] = rank
+			if rank == 1 && result.WinnerID == "" {
+				result.WinnerID = userID
+				result.WinnerName = subsByUser[userID].UserName
+			}
+		}
+		ranked += len(userIDs)
+	}
+	for _, group := range placings {
+		var userIDs []string
+		for _, id := range group {
+			if sub, ok := subsByEntrant[id]; ok && finalRanks[sub.ExternalUserID] == 0 {
+				userIDs = append(userIDs, sub.ExternalUserID)
+			}
+		}
+		place(userIDs)
+	}
+
+	bestScores := make(map[string]int64, len(standings))
+	for _, st := range standings {
+		if _, ok := subsByUser[st.UserID]; !ok {
+			continue
+		}
+		bestScores[st.UserID] = st.BestAttempt
+		if finalRanks[st.UserID] == 0 {
+			place([]string{st.UserID})
+		}
+	}
+
+	var matchCounts []struct {
+		UserID  string
+		Matches int64
+	}
+	if err := s.DB.Model(&models.LeaderboardEntry{}).
+		Select("user_id, COUNT(DISTINCT match_id) AS matches").
+		Where("tournament_id = ?", tournamentID).
+		Group("user_id").
+		Scan(&matchCounts).Error; err != nil {
+		return nil, err
+	}
+	matchesPlayed := make(map[string]int64, len(matchCounts))
+	for _, mc := range matchCounts {
+		matchesPlayed[mc.UserID] = mc.Matches
+	}
+
+	var existing []string
+	if err := s.DB.Model(&models.TournamentParticipation{}).
+		Where("tournament_id = ?", tournamentID).
+		Pluck("external_user_id", &existing).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[string]bool, len(existing))
+	for _, userID := range existing {
+		done[userID] = true
+	}
+
+	progression := NewProgressionService(s.DB)
+	for _, sub := range subs {
+		if done[sub.ExternalUserID] {
+			result.Skipped++
+			continue
+		}
+
+		if _, err := progression.EnsureProgressRecord(sub.ExternalUserID); err != nil {
+			log.Printf("❌ Finalize %s: progress record for %s: %v", tournamentID, sub.ExternalUserID, err)
+			result.Failed++
+			continue
+		}
+
+		tp := &models.TournamentParticipation{
+			ID:                 uuid.NewString(),
+			ExternalUserID:     sub.ExternalUserID,
+			TournamentID:       tournamentID,
+			SubscriptionID:     sub.ID,
+			TotalMatchesPlayed: matchesPlayed[sub.ExternalUserID],
+			BestScore:          bestScores[sub.ExternalUserID],
+			FinalRank:          finalRanks[sub.ExternalUserID],
+			Status:             "completed",
+		}
+		if err := progression.RecordTournamentParticipation(tp); err != nil {
+			// A concurrent run may have inserted the row first; the unique index rolls this one back
+			var count int64
+			s.DB.Model(&models.TournamentParticipation{}).
+				Where("tournament_id = ? AND external_user_id = ?", tournamentID, sub.ExternalUserID).
+				Count(&count)
+			if count > 0 {
+				result.Skipped++
+				continue
+			}
+			log.Printf("❌ Finalize %s: participation for %s: %v", tournamentID, sub.ExternalUserID, err)
+			result.Failed++
+			continue
+		}
+		result.Recorded++
+	}
+
+	if result.Failed > 0 {
+		return result, fmt.Errorf("%d participations failed to record", result.Failed)
+	}
+
+	if err := s.payoutTournamentPrizes(&tournament); err != nil {
+		return result, err
+	}
+	if err := s.closeTournamentPool(tournamentID); err != nil {
+		return result, err
+	}
+
+	if tournament.FinalizedAt == nil {
+		now := time.Now()
+		if err := s.DB.Model(&models.Tournament{}).
+			Where("id = ? AND finalized_at IS NULL", tournamentID).
+			Updates(map[string]interface{}{
+				"winner_id":    result.WinnerID,
+				"winner_name":  result.WinnerName,
+				"finalized_at": now,
+			}).Error; err != nil {
+			return result, err
+		}
+		result.FinalizedAt = &now
+	}
+
+	log.Printf("🏁 Tournament %s finalized: winner=%q recorded=%d skipped=%d",
+		tournamentID, result.WinnerID, result.Recorded, result.Skipped)
+	return result, nil
+}
+
+// finalPlacings orders the entrants (entrantID) of a tournament's last match best first, from the
+// results of the format it was played in: the bracket, the Swiss or round-robin table, or lobby
+// placements. Each group holds entrants tied on the same place. It returns nothing for
+// leaderboard matches or when no result has been recorded, leaving the leaderboard to decide.
+func finalPlacings(db *gorm.DB, tournamentID string) ([][]string, error) {
+	var last models.TournamentMatch
+	err := db.Joins("JOIN tournament_batches ON tournament_batches.id = tournament_matches.batch_id").
+		Where("tournament_batches.tournament_id = ?", tournamentID).
+		Order("tournament_batches.sort_order DESC, tournament_matches.sort_order DESC").
+		First(&last).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := loadMatchTypeConfig(db, last.MatchType)
+	if errors.Is(err, ErrUnknownMatchType) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case cfg.IsElimination():
+		var bracket models.Bracket
+		err := db.Preload("Nodes").Where("match_id = ?", last.ID).First(&bracket).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return bracketPlacings(&bracket), nil
+
+	case cfg.ProgressionType == models.ProgressionSwiss || cfg.ProgressionType == models.ProgressionRoundRobin:
+		h, err := NewPairingService(db).loadSwissHistory(last.ID)
+		if err != nil {
+			return nil, err
+		}
+		var players []models.TournamentSubscription
+		for id := range h.games {
+			players = append(players, models.TournamentSubscription{ExternalUserID: id})
+		}
+		sort.Slice(players, func(i, j int) bool { return players[i].ExternalUserID < players[j].ExternalUserID })
+		var placings [][]string
+		for _, st := range swissStandings(players, h) {
+			placings = append(placings, []string{st.UserID})
+		}
+		return placings, nil
+
+	case cfg.IsLobbies():
+		var lobbies []models.Lobby
+		if err := db.Preload("Players").
+			Where("match_id = ? AND status = ?", last.ID, models.LobbyStatusCompleted).
+			Find(&lobbies).Error; err != nil {
+			return nil, err
+		}
+		return lobbyPlacings(lobbies), nil
+	}
+	return nil, nil
+}
+
+// bracketPlacings ranks a bracket's players: the champion first, then everyone else by how late
+// they were knocked out (their last loss). Players who went out in the same round tie.
+func bracketPlacings(bracket *models.Bracket) [][]string {
+	const alive = math.MaxInt
+	wentOut := make(map[string]int)
+	for _, n := range bracket.Nodes {
+		for _, id := range []string{n.Player1ID, n.Player2ID} {
+			if _, seen := wentOut[id]; id != "" && !seen {
+				wentOut[id] = alive
+			}
+		}
+	}
+	for _, n := range bracket.Nodes {
+		if n.Status != models.BracketNodeCompleted || n.LoserID == "" {
+			continue
+		}
+		stage := bracketSectionOrder[n.Section]*1000 + n.Round
+		if wentOut[n.LoserID] == alive || stage > wentOut[n.LoserID] {
+			wentOut[n.LoserID] = stage
+		}
+	}
+
+	var placings [][]string
+	if bracket.ChampionID != "" {
+		placings = append(placings, []string{bracket.ChampionID})
+		delete(wentOut, bracket.ChampionID)
+	}
+	byStage := make(map[int][]string)
+	var stages []int
+	for id, stage := range wentOut {
+		if byStage[stage] == nil {
+			stages = append(stages, stage)
+		}
+		byStage[stage] = append(byStage[stage], id)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(stages)))
+	for _, stage := range stages {
+		sort.Strings(byStage[stage])
+		placings = append(placings, byStage[stage])
+	}
+	return placings
+}
+
+// lobbyPlacings ranks the players of completed lobbies by placement, then score. Players with
+// the same placement and score (e.g. the winners of parallel lobbies on equal points) tie.
+func lobbyPlacings(lobbies []models.Lobby) [][]string {
+	var players []models.LobbyPlayer
+	for _, l := range lobbies {
+		for _, p := range l.Players {
+			if p.Placement > 0 {
+				players = append(players, p)
+			}
+		}
+	}
+	sort.SliceStable(players, func(i, j int) bool {
+		if players[i].Placement != players[j].Placement {
+			return players[i].Placement < players[j].Placement
+		}
+		if players[i].Score != players[j].Score {
+			return players[i].Score > players[j].Score
+		}
+		return players[i].UserID < players[j].UserID
+	})
+
+	var placings [][]string
+	for i, p := range players {
+		if i > 0 && p.Placement == players[i-1].Placement && p.Score == players[i-1].Score {
+			placings[len(placings)-1] = append(placings[len(placings)-1], p.UserID)
+			continue
+		}
+		placings = append(placings, []string{p.UserID})
+	}
+	return placings
+}
+
+// finalizePendingTournaments retries finalization for completed tournaments not yet finalized
+func (s *TournamentService) finalizePendingTournaments() {
+	var ids []string
+	if err := s.DB.Model(&models.Tournament{}).
+		Where("status = ? AND finalized_at IS NULL", models.TournamentStatusCompleted).
+		Pluck("id", &ids).Error; err != nil {
+		log.Printf("[Scheduler] DB error loading unfinalized tournaments: %v", err)
+		return
+	}
+	for _, id := range ids {
+		if _, err := s.FinalizeTournament(id); err != nil {
+			log.Printf("[Scheduler] Failed to finalize tournament %s: %v", id, err)
+		}
+	}
+}
+
+// FinalizeTournamentEndpoint lets an admin (re)run finalization for a completed tournament
+func (s *TournamentService) FinalizeTournamentEndpoint(c *fiber.Ctx) error {
+	result, err := s.FinalizeTournament(c.Params("id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
+		case errors.Is(err, ErrTournamentNotCompleted):
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to finalize tournament", "details": err.Error(), "result": result})
+	}
+	return c.JSON(result)
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidTransition = errors.New("invalid status transition")
+
+// stageDateFloor filters out zero (unset) match/round dates, which GORM stores as 0001-01-01
+var stageDateFloor = time.Unix(0, 0)
+
+// tournamentMatchIDs selects the IDs of every match in a tournament (used as a subquery)
+func tournamentMatchIDs(db *gorm.DB, tournamentID string) *gorm.DB {
+	return db.Model(&models.TournamentMatch{}).
+		Select("tournament_matches.id").
+		Joins("JOIN tournament_batches ON tournament_matches.batch_id = tournament_batches.id").
+		Where("tournament_batches.tournament_id = ?", tournamentID)
+}
+
+// transitionTournament moves t to status `to` if the state machine allows it.
+// The update is conditional on the current status so concurrent writers can't skip a step.
+func transitionTournament(tx *gorm.DB, t *models.Tournament, to string, extra map[string]interface{}) error {
+	from := t.Status
+	if !models.CanTransitionTournament(from, to) {
+		return fmt.Errorf("%w: tournament cannot move from %q to %q", ErrInvalidTransition, from, to)
+	}
+
+	updates := map[string]interface{}{"status": to}
+	for k, v := range extra {
+		updates[k] = v
+	}
+
+	res := tx.Model(&models.Tournament{}).
+		Where("id = ? AND status = ?", t.ID, from).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("%w: tournament %s is no longer %q", ErrInvalidTransition, t.ID, from)
+	}
+
+	t.Status = to
+	return nil
+}
+
+// publishTournament publishes a draft or scheduled tournament and, if its start time
+// has already passed, activates it straight away
+func publishTournament(tx *gorm.DB, t *models.Tournament, now time.Time) error {
+	if err := transitionTournament(tx, t, models.TournamentStatusPublished, map[string]interface{}{
+		"published_at":     now,
+		"publish_schedule": nil,
+	}); err != nil {
+		return err
+	}
+	if !t.StartTime.After(now) {
+		return transitionTournament(tx, t, models.TournamentStatusActive, nil)
+	}
+	return nil
+}
+
+// afterTournamentTransition runs side effects once a status change has been committed
+func (s *TournamentService) afterTournamentTransition(t *models.Tournament, from string) {
+	if from == t.Status {
+		return
+	}
+	log.Printf("🔁 Tournament %s: %s → %s", t.ID, from, t.Status)
+
+	switch t.Status {
+	case models.TournamentStatusCompleted, models.TournamentStatusCancelled:
+		if err := s.closeOpenStages(t.ID, t.Status); err != nil {
+			log.Printf("❌ Failed to close matches/rounds for tournament %s: %v", t.ID, err)
+		}
+	}
+
+	if t.Status == models.TournamentStatusCancelled {
+		// Failed refunds stay on the ledger; RefundAllSubscriptions re-runs the rest
+		refunded, failed, err := s.refundTournament(t.ID, RefundOptions{
+			Reason:     "tournament cancelled",
+			RefundedBy: "system",
+		})
+		if err != nil {
+			log.Printf("❌ Failed to refund subscribers of cancelled tournament %s: %v", t.ID, err)
+		} else {
+			log.Printf("💸 Cancelled tournament %s: %d subscription(s) refunded, %d failed", t.ID, refunded, failed)
+		}
+	}
+
+	if t.Status == models.TournamentStatusActive {
+		if err := s.backfillSkillRatings(t); err != nil {
+			log.Printf("❌ Failed to backfill skill ratings for tournament %s: %v", t.ID, err)
+		}
+	}
+
+	if t.Status == models.TournamentStatusCompleted {
+		// Failures are retried by the lifecycle scheduler (finalized_at stays NULL)
+		if _, err := s.FinalizeTournament(t.ID); err != nil {
+			log.Printf("❌ Failed to finalize tournament %s: %v", t.ID, err)
+		}
+	}
+}
+
+// closeOpenStages completes (or cancels) every match and round still pending or active
+func (s *TournamentService) closeOpenStages(tournamentID, tournamentStatus string) error {
+	stageStatus := models.StageStatusCompleted
+	if tournamentStatus == models.TournamentStatusCancelled {
+		stageStatus = models.StageStatusCancelled
+	}
+	open := []string{models.StageStatusPending, models.StageStatusActive}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TournamentRound{}).
+			Where("match_id IN (?) AND status IN ?", tournamentMatchIDs(tx, tournamentID), open).
+			Update("status", stageStatus).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.TournamentMatch{}).
+			Where("id IN (?) AND status IN ?", tournamentMatchIDs(tx, tournamentID), open).
+			Update("status", stageStatus).Error
+	})
+}
+
+// advanceTournamentLifecycles is run by the scheduler: it publishes on PublishSchedule,
+// activates at StartTime, completes at EndTime, moves matches/rounds on their own dates,
+// and retries finalization of completed tournaments.
+func (s *TournamentService) advanceTournamentLifecycles(now time.Time) {
+	steps := []struct {
+		from  string
+		to    string
+		query func(db *gorm.DB) *gorm.DB
+	}{
+		{models.TournamentStatusScheduled, models.TournamentStatusPublished, func(db *gorm.DB) *gorm.DB {
+			return db.Where("publish_schedule IS NOT NULL AND publish_schedule <= ?", now)
+		}},
+		{models.TournamentStatusPublished, models.TournamentStatusActive, func(db *gorm.DB) *gorm.DB {
+			return db.Where("start_time <= ?", now)
+		}},
+		{models.TournamentStatusActive, models.TournamentStatusCompleted, func(db *gorm.DB) *gorm.DB {
+			return db.Where("end_time > ? AND end_time <= ?", stageDateFloor, now)
+		}},
+	}
+
+	for _, step := range steps {
+		var due []models.Tournament
+		if err := step.query(s.DB.Where("status = ?", step.from)).Find(&due).Error; err != nil {
+			log.Printf("[Scheduler] DB error loading %s tournaments: %v", step.from, err)
+			continue
+		}
+
+		for i := range due {
+			t := &due[i]
+			extra := map[string]interface{}{}
+			if step.to == models.TournamentStatusPublished {
+				extra["published_at"] = *t.PublishSchedule
+			}
+			if err := transitionTournament(s.DB, t, step.to, extra); err != nil {
+				log.Printf("[Scheduler] Failed to move tournament %s to %s: %v", t.ID, step.to, err)
+				continue
+			}
+			s.afterTournamentTransition(t, step.from)
+		}
+	}
+
+	s.advanceStages(now)
+	s.finalizePendingTournaments()
+}
+
+// advanceStages opens and closes matches and rounds of active tournaments by their dates
+func (s *TournamentService) advanceStages(now time.Time) {
+	activeMatchIDs := s.DB.Model(&models.TournamentMatch{}).
+		Select("tournament_matches.id").
+		Joins("JOIN tournament_batches ON tournament_matches.batch_id = tournament_batches.id").
+		Joins("JOIN tournaments ON tournament_batches.tournament_id = tournaments.id").
+		Where("tournaments.status = ?", models.TournamentStatusActive)
+
+	for _, table := range []struct {
+		model   interface{}
+		idField string
+	}{
+		{&models.TournamentMatch{}, "id"},
+		{&models.TournamentRound{}, "match_id"},
+	} {
+		// pending → active once started
+		if err := s.DB.Model(table.model).
+			Where(table.idField+" IN (?) AND status = ? AND start_date <= ?", activeMatchIDs, models.StageStatusPending, now).
+			Update("status", models.StageStatusActive).Error; err != nil {
+			log.Printf("[Scheduler] Failed to activate stages: %v", err)
+		}
+		// active → completed once ended
+		if err := s.DB.Model(table.model).
+			Where(table.idField+" IN (?) AND status = ? AND end_date > ? AND end_date <= ?", activeMatchIDs, models.StageStatusActive, stageDateFloor, now).
+			Update("status", models.StageStatusCompleted).Error; err != nil {
+			log.Printf("[Scheduler] Failed to complete stages: %v", err)
+		}
+	}
+}
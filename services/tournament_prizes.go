@@ -0,0 +1,443 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"game-publish-system/models"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PrizeTableRequest replaces a tournament's prize table
+type PrizeTableRequest struct {
+	Funding string       `json:"funding"` // sponsored (default) or pool
+	Prizes  []PrizeInput `json:"prizes"`
+}
+
+// PrizeInput is one prize table row as sent by the client, with Amount in currency units
+type PrizeInput struct {
+	RankFrom    int     `json:"rank_from"`
+	RankTo      int     `json:"rank_to"`
+	PrizeType   string  `json:"prize_type"`
+	Title       string  `json:"title"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	ItemDetails string  `json:"item_details"`
+	BadgeCode   string  `json:"badge_code"`
+	ImageURL    string  `json:"image_url"`
+	Emoji       string  `json:"emoji"`
+}
+
+// cashPrizeTotals is the sum paid out per currency, in minor units, if every cash rank is
+// filled. Prizes without a currency are in fallback, the tournament's.
+func cashPrizeTotals(prizes []models.TournamentPrize, fallback string) map[string]int64 {
+	totals := map[string]int64{}
+	for _, p := range prizes {
+		if p.PrizeType == models.PrizeTypeCash {
+			currency := p.Currency
+			if currency == "" {
+				currency = fallback
+			}
+			totals[currency] += p.Amount * int64(p.Winners())
+		}
+	}
+	return totals
+}
+
+// buildPrizes turns the request rows into prizes. Cash prizes default to the tournament's
+// currency and their amount must fit its precision. Pool-funded prizes are paid from entry fees,
+// so they must be in the tournament's currency.
+func buildPrizes(inputs []PrizeInput, tournament *models.Tournament, funding string) ([]models.TournamentPrize, error) {
+	prizes := make([]models.TournamentPrize, len(inputs))
+	for i, in := range inputs {
+		prizes[i] = models.TournamentPrize{
+			RankFrom:    in.RankFrom,
+			RankTo:      in.RankTo,
+			PrizeType:   in.PrizeType,
+			Title:       in.Title,
+			ItemDetails: in.ItemDetails,
+			BadgeCode:   in.BadgeCode,
+			ImageURL:    in.ImageURL,
+			Emoji:       in.Emoji,
+		}
+		if in.PrizeType != models.PrizeTypeCash {
+			continue
+		}
+		currency := tournament.Currency
+		if in.Currency != "" {
+			var err error
+			if currency, err = parseCurrency(in.Currency); err != nil {
+				return nil, fmt.Errorf("prize %d: %w", i+1, err)
+			}
+		}
+		if funding == models.PrizeFundingPool && currency != tournament.Currency {
+			return nil, fmt.Errorf("prize %d: pool-funded prizes are paid in the tournament's currency (%s)", i+1, tournament.Currency)
+		}
+		if err := checkAmountPrecision(in.Amount, currency); err != nil {
+			return nil, fmt.Errorf("prize %d: %w", i+1, err)
+		}
+		prizes[i].Amount = minorUnits(in.Amount, currency)
+		prizes[i].Currency = currency
+	}
+	return prizes, nil
+}
+
+// validatePrizeTable checks rank ranges and per-type fields. Cash and item ranges may not
+// overlap each other; badge rows may overlap anything (a winner can get cash and a badge).
+func validatePrizeTable(prizes []models.TournamentPrize) error {
+	type span struct{ from, to int }
+	var payouts []span
+
+	for i, p := range prizes {
+		if p.RankFrom < 1 || p.RankTo < p.RankFrom {
+			return fmt.Errorf("prize %d: invalid rank range %d-%d", i+1, p.RankFrom, p.RankTo)
+		}
+		switch p.PrizeType {
+		case models.PrizeTypeCash:
+			if p.Amount <= 0 {
+				return fmt.Errorf("prize %d: cash prizes need a positive amount", i+1)
+			}
+		case models.PrizeTypeItem:
+			if p.ItemDetails == "" {
+				return fmt.Errorf("prize %d: item prizes need item_details", i+1)
+			}
+		case models.PrizeTypeBadge:
+			if p.BadgeCode == "" {
+				return fmt.Errorf("prize %d: badge prizes need badge_code", i+1)
+			}
+			continue
+		default:
+			return fmt.Errorf("prize %d: unsupported prize_type %q (use cash, item or badge)", i+1, p.PrizeType)
+		}
+		payouts = append(payouts, span{p.RankFrom, p.RankTo})
+	}
+
+	sort.Slice(payouts, func(i, j int) bool { return payouts[i].from < payouts[j].from })
+	for i := 1; i < len(payouts); i++ {
+		if payouts[i].from <= payouts[i-1].to {
+			return fmt.Errorf("prize ranks %d-%d and %d-%d overlap",
+				payouts[i-1].from, payouts[i-1].to, payouts[i].from, payouts[i].to)
+		}
+	}
+	return nil
+}
+
+// SetTournamentPrizes replaces the prize table (admin). Pool-funded tables must fit in
+// EntryFee × MaxSubscribers; they are checked again against collected fees at payout.
+func (s *TournamentService) SetTournamentPrizes(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	var req PrizeTableRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
+	}
+	if req.Funding == "" {
+		req.Funding = models.PrizeFundingSponsored
+	}
+	if req.Funding != models.PrizeFundingSponsored && req.Funding != models.PrizeFundingPool {
+		return c.Status(400).JSON(fiber.Map{"error": "funding must be sponsored or pool"})
+	}
+
+	var tournament models.Tournament
+	if err := s.DB.First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	if tournament.FinalizedAt != nil || models.IsTerminalTournamentStatus(tournament.Status) {
+		return c.Status(409).JSON(fiber.Map{"error": "prize table is locked once the tournament has ended"})
+	}
+	prizes, err := buildPrizes(req.Prizes, &tournament, req.Funding)
+	if err == nil {
+		err = validatePrizeTable(prizes)
+	}
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	if req.Funding == models.PrizeFundingPool {
+		if tournament.EntryFee <= 0 || tournament.MaxSubscribers <= 0 {
+			return c.Status(400).JSON(fiber.Map{"error": "pool-funded prizes need an entry fee and max_subscribers"})
+		}
+		maxRevenue := tournament.EntryFee * int64(tournament.MaxSubscribers)
+		if total := cashPrizeTotals(prizes, tournament.Currency)[tournament.Currency]; total > maxRevenue {
+			return c.Status(400).JSON(fiber.Map{
+				"error":       "cash prizes exceed the maximum entry-fee pool",
+				"currency":    tournament.Currency,
+				"prize_total": majorUnits(total, tournament.Currency),
+				"max_pool":    majorUnits(maxRevenue, tournament.Currency),
+			})
+		}
+	}
+
+	for i := range prizes {
+		prizes[i].ID = uuid.NewString()
+		prizes[i].TournamentID = tournamentID
+	}
+
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tournament_id = ?", tournamentID).Delete(&models.TournamentPrize{}).Error; err != nil {
+			return err
+		}
+		if len(prizes) > 0 {
+			if err := tx.Create(&prizes).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.Tournament{}).Where("id = ?", tournamentID).
+			Update("prize_funding", req.Funding).Error
+	})
+	if err != nil {
+		log.Printf("❌ Failed to save prize table for tournament %s: %v", tournamentID, err)
+		return c.Status(500).JSON(fiber.Map{"error": "failed to save prize table", "details": err.Error()})
+	}
+
+	totals := cashPrizeTotals(prizes, tournament.Currency)
+	return c.JSON(fiber.Map{
+		"tournament_id": tournamentID,
+		"funding":       req.Funding,
+		"currency":      tournament.Currency,
+		"prizes":        prizes,
+		"cash_total":    majorUnits(totals[tournament.Currency], tournament.Currency),
+		"cash_totals":   majorTotals(totals),
+	})
+}
+
+// GetTournamentPrizes returns the prize table (admin: any tournament)
+func (s *TournamentService) GetTournamentPrizes(c *fiber.Ctx) error {
+	return s.prizeTableResponse(c, s.DB)
+}
+
+// GetPublishedTournamentPrizes returns the prize table of a published tournament
+func (s *TournamentService) GetPublishedTournamentPrizes(c *fiber.Ctx) error {
+	return s.prizeTableResponse(c, s.DB.Where("status IN ('published', 'active', 'scheduled', 'completed')"))
+}
+
+func (s *TournamentService) prizeTableResponse(c *fiber.Ctx, query *gorm.DB) error {
+	tournamentID := c.Params("id")
+
+	var tournament models.Tournament
+	if err := query.Select("id", "prize_funding", "entry_fee_minor", "currency").First(&tournament, "id = ?", tournamentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found or not available"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+
+	var prizes []models.TournamentPrize
+	if err := s.DB.Where("tournament_id = ?", tournamentID).
+		Order("rank_from ASC, prize_type ASC").
+		Find(&prizes).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch prizes"})
+	}
+
+	totals := cashPrizeTotals(prizes, tournament.Currency)
+	return c.JSON(fiber.Map{
+		"tournament_id": tournamentID,
+		"funding":       tournament.PrizeFunding,
+		"currency":      tournament.Currency,
+		"prizes":        prizes,
+		"cash_total":    majorUnits(totals[tournament.Currency], tournament.Currency),
+		"cash_totals":   majorTotals(totals),
+	})
+}
+
+// collectedEntryFees sums what active subscribers actually paid, in minor units of currency
+func (s *TournamentService) collectedEntryFees(tournamentID, currency string) (int64, error) {
+	var total int64
+	err := s.DB.Model(&models.TournamentSubscription{}).
+		Where("tournament_id = ? AND currency = ? AND payment_status IN ?", tournamentID, currency, activeSubscriptionStatuses).
+		Select("COALESCE(SUM(payment_amount_minor), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// payoutTournamentPrizes issues Reward rows for every ranked participation covered by the
+// prize table. Each participation is paid inside its own transaction and stamped with
+// PrizePaidAt, so reruns skip players that were already paid.
+// Pool-funded cash prizes are scaled down pro rata if collected fees fall short of the table.
+func (s *TournamentService) payoutTournamentPrizes(tournament *models.Tournament) error {
+	var prizes []models.TournamentPrize
+	if err := s.DB.Where("tournament_id = ?", tournament.ID).Find(&prizes).Error; err != nil {
+		return err
+	}
+	if len(prizes) == 0 {
+		return nil
+	}
+
+	scale := 1.0
+	if tournament.PrizeFunding == models.PrizeFundingPool {
+		collected, err := s.collectedEntryFees(tournament.ID, tournament.Currency)
+		if err != nil {
+			return err
+		}
+		total := cashPrizeTotals(prizes, tournament.Currency)[tournament.Currency]
+		if total > collected {
+			scale = float64(collected) / float64(total)
+			log.Printf("⚠️ Tournament %s: pool %s short of prize table %s, scaling cash prizes by %.4f",
+				tournament.ID, formatAmount(collected, tournament.Currency),
+				formatAmount(total, tournament.Currency), scale)
+		}
+	}
+
+	var participations []models.TournamentParticipation
+	if err := s.DB.Where("tournament_id = ? AND final_rank > 0 AND prize_paid_at IS NULL", tournament.ID).
+		Order("final_rank ASC").
+		Find(&participations).Error; err != nil {
+		return err
+	}
+
+	failed := 0
+	for i := range participations {
+		if err := s.payoutParticipation(tournament, &participations[i], prizes, scale); err != nil {
+			log.Printf("❌ Prize payout failed for %s in tournament %s: %v",
+				participations[i].ExternalUserID, tournament.ID, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d prize payouts failed", failed)
+	}
+	return nil
+}
+
+// payoutParticipation issues every prize covering one participation's final rank
+func (s *TournamentService) payoutParticipation(tournament *models.Tournament, tp *models.TournamentParticipation, prizes []models.TournamentPrize, scale float64) error {
+	var won []models.TournamentPrize
+	for _, p := range prizes {
+		if tp.FinalRank >= p.RankFrom && tp.FinalRank <= p.RankTo {
+			won = append(won, p)
+		}
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.TournamentParticipation
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&locked, "id = ?", tp.ID).Error; err != nil {
+			return err
+		}
+		if locked.PrizePaidAt != nil {
+			return nil
+		}
+
+		var earned, badges []string
+		for _, p := range won {
+			title := p.Title
+			if title == "" {
+				title = fmt.Sprintf("%s — %s place", tournament.Name, ordinal(tp.FinalRank))
+			}
+			reward := models.Reward{
+				ID:       uuid.NewString(),
+				Title:    title,
+				Category: models.RewardCategoryTournamentPrize,
+				ImageURL: p.ImageURL,
+				Emoji:    p.Emoji,
+				Excerpt:  fmt.Sprintf("Finished %s in %s", ordinal(tp.FinalRank), tournament.Name),
+				UserID:   tp.ExternalUserID,
+				Status:   models.RewardStatusPublished,
+			}
+			if reward.Emoji == "" {
+				reward.Emoji = "🏆"
+			}
+
+			switch p.PrizeType {
+			case models.PrizeTypeCash:
+				// Rows saved before prizes carried a currency are in the tournament's
+				currency := p.Currency
+				if currency == "" {
+					currency = tournament.Currency
+				}
+				amount := int64(math.Floor(float64(p.Amount) * scale))
+				reward.Type = models.RewardTypeCash
+				reward.Amount = majorUnits(amount, currency)
+				reward.Currency = currency
+				earned = append(earned, formatAmount(amount, currency))
+				funding := ledgerPrizeExpense
+				if tournament.PrizeFunding == models.PrizeFundingPool {
+					funding = poolLedgerAccount(tournament.ID)
+				}
+				if err := postLedger(tx, ledgerEntry{
+					Kind:         models.LedgerKindPrizePayout,
+					Currency:     currency,
+					TournamentID: tournament.ID,
+					Description:  fmt.Sprintf("%s prize: %s", ordinal(tp.FinalRank), title),
+					Actor:        "system",
+					Legs: []ledgerLeg{
+						debit(funding, amount),
+						credit(userLedgerAccount(tp.ExternalUserID), amount),
+					},
+				}); err != nil {
+					return err
+				}
+			case models.PrizeTypeItem:
+				reward.Type = models.RewardTypeItem
+				reward.ItemDetails = p.ItemDetails
+				earned = append(earned, p.ItemDetails)
+			case models.PrizeTypeBadge:
+				reward.Type = models.RewardTypeItem
+				reward.ItemDetails = "badge:" + p.BadgeCode
+				badges = append(badges, p.BadgeCode)
+				if err := awardBadgeByCode(tx, tp.ExternalUserID, p.BadgeCode, tournament.ID, tp.FinalRank); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Create(&reward).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.TournamentParticipation{}).Where("id = ?", tp.ID).
+			Updates(map[string]interface{}{
+				"prize_earned":  strings.Join(earned, ", "),
+				"badge_awarded": strings.Join(badges, ", "),
+				"prize_paid_at": time.Now(),
+			}).Error
+	})
+}
+
+// awardBadgeByCode grants a UserBadge if the badge type exists; unknown codes only get the Reward row
+func awardBadgeByCode(tx *gorm.DB, externalUserID, code, tournamentID string, finalRank int) error {
+	var badge models.BadgeType
+	if err := tx.Where("code = ?", code).First(&badge).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("⚠️ Badge %s not found; issuing prize reward only", code)
+			return nil
+		}
+		return err
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{"tournament_id": tournamentID, "final_rank": finalRank})
+	return tx.Create(&models.UserBadge{
+		ID:             uuid.NewString(),
+		ExternalUserID: externalUserID,
+		BadgeTypeID:    badge.ID,
+		Metadata:       string(metadata),
+	}).Error
+}
+
+// ordinal formats 1 → "1st", 2 → "2nd", 11 → "11th"
+func ordinal(n int) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
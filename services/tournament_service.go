@@ -7,6 +7,7 @@ import (
 	"game-publish-system/utils"
 	"log"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -18,11 +19,19 @@ import (
 )
 
 type TournamentService struct {
-	DB *gorm.DB
+	DB       *gorm.DB
+	Payments map[string]PaymentProvider // keyed by provider name; see paymentProvidersFromEnv
 }
 
+// Subscription outcomes settled inside the subscribe transaction
+var (
+	ErrAlreadySubscribed     = errors.New("user already subscribed")
+	ErrTeamAlreadySubscribed = errors.New("team already subscribed")
+	ErrTournamentFull        = errors.New("tournament is full")
+)
+
 func NewTournamentService(db *gorm.DB) *TournamentService {
-	return &TournamentService{DB: db}
+	return &TournamentService{DB: db, Payments: paymentProvidersFromEnv(db)}
 }
 
 func (s *TournamentService) CreateTournament(c *fiber.Ctx) error {
@@ -85,15 +94,33 @@ func (s *TournamentService) CreateTournament(c *fiber.Ctx) error {
 		}
 	}
 
-	entryFee := 0.0
+	currency, err := parseCurrency(c.FormValue("currency"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	var entryFee int64
 	if entryFeeStr != "" {
-		if f, err := strconv.ParseFloat(entryFeeStr, 64); err == nil && f >= 0 {
-			entryFee = f
-		} else {
-			return c.Status(400).JSON(fiber.Map{"error": "entry_fee must be a non-negative number"})
+		if entryFee, err = parseMinorUnits(entryFeeStr, currency); err != nil {
+			return c.Status(400).JSON(fiber.Map{"error": "entry_fee must be a non-negative amount", "details": err.Error()})
 		}
 	}
 
+	isTeamBased, teamSizeMin, teamSizeMax, err := parseTeamSettings(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	checkInRequired, checkInOpens, checkInCloses, autoForfeit, err := parseCheckInSettings(c, models.Tournament{CheckInOpensMinutes: 30})
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	waitlistHold, err := parseWaitlistHold(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+
 	startTime, err := time.Parse(time.RFC3339, startTimeStr)
 	if err != nil {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid start_time (use RFC3339)"})
@@ -165,10 +192,19 @@ func (s *TournamentService) CreateTournament(c *fiber.Ctx) error {
 		GenreTags:       c.FormValue("genre_tags"),
 		MaxSubscribers:  maxSubscribers,
 		EntryFee:        entryFee,
+		Currency:        currency,
 		MainPhotoURL:    mainPhotoURL,
 		StartTime:       startTime,
 		EndTime:         endTime,
 		AcceptsWaivers:  acceptsWaivers,
+		IsTeamBased:     isTeamBased,
+		TeamSizeMin:     teamSizeMin,
+		TeamSizeMax:     teamSizeMax,
+		CheckInRequired:      checkInRequired,
+		CheckInOpensMinutes:  checkInOpens,
+		CheckInClosesMinutes: checkInCloses,
+		AutoForfeitNoShows:   autoForfeit,
+		WaitlistHoldMinutes:  waitlistHold,
 		PrizePool:       prizePool,
 		Requirements:    processedRequirements,
 		SponsorName:     sponsorName,
@@ -273,6 +309,7 @@ func (s *TournamentService) UpdateTournament(c *fiber.Ctx) error {
     }
 
     // --- Prepare updates map ---
+    oldMaxSubscribers := existingTournament.MaxSubscribers
     updates := map[string]interface{}{
         "start_time": parsedStartTime,
         "name":        strings.TrimSpace(c.FormValue("name")),
@@ -289,20 +326,96 @@ func (s *TournamentService) UpdateTournament(c *fiber.Ctx) error {
             }
             return 0
         }(),
-        "entry_fee": func() float64 {
-            if v := c.FormValue("entry_fee"); v != "" {
-                if val, err := strconv.ParseFloat(v, 64); err == nil {
-                    return val
-                }
-            }
-            return 0.0
-        }(),
         "prize_pool":      c.FormValue("prize_pool"),
         "requirements":    c.FormValue("requirements"),
         "sponsor_name":    c.FormValue("sponsor_name"),
         "is_featured":     c.FormValue("is_featured") == "true",
         "accepts_waivers": c.FormValue("accepts_waivers") == "true",
-        "status":          c.FormValue("status"),
+    }
+
+    // Entry fee in the tournament's currency. Subscribers have paid the current fee in the current
+    // currency, so both are locked once anyone has joined.
+    var subscribers int64
+    if err := s.DB.Model(&models.TournamentSubscription{}).Where("tournament_id = ?", id).Count(&subscribers).Error; err != nil {
+        return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "failed to count subscribers", "details": err.Error()})
+    }
+    currency := existingTournament.Currency
+    if v := c.FormValue("currency"); v != "" {
+        parsed, err := parseCurrency(v)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+        if parsed != currency && subscribers > 0 {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot change currency once entrants have subscribed"})
+        }
+        currency = parsed
+        updates["currency"] = currency
+    }
+    var entryFee int64
+    if v := c.FormValue("entry_fee"); v != "" {
+        if entryFee, err = parseMinorUnits(v, currency); err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "entry_fee must be a non-negative amount", "details": err.Error()})
+        }
+    }
+    if entryFee != existingTournament.EntryFee && subscribers > 0 {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "cannot change entry_fee once entrants have subscribed"})
+    }
+    updates["entry_fee_minor"] = entryFee
+
+    // Status changes go through the lifecycle state machine (empty = unchanged)
+    fromStatus := existingTournament.Status
+    newStatus := c.FormValue("status")
+    if newStatus == fromStatus {
+        newStatus = ""
+    }
+    if newStatus != "" && !models.CanTransitionTournament(fromStatus, newStatus) {
+        return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+            "error": fmt.Sprintf("tournament cannot move from %q to %q", fromStatus, newStatus),
+        })
+    }
+
+    // Team settings (only when sent; entrants can't switch between players and teams)
+    if c.FormValue("is_team_based") != "" {
+        isTeamBased, teamSizeMin, teamSizeMax, err := parseTeamSettings(c)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+        if isTeamBased != existingTournament.IsTeamBased {
+            var subs int64
+            s.DB.Model(&models.TournamentSubscription{}).Where("tournament_id = ?", id).Count(&subs)
+            if subs > 0 {
+                return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+                    "error": "is_team_based cannot change once the tournament has subscribers",
+                })
+            }
+        }
+        updates["is_team_based"] = isTeamBased
+        updates["team_size_min"] = teamSizeMin
+        updates["team_size_max"] = teamSizeMax
+    }
+
+    if c.FormValue("waitlist_hold_minutes") != "" {
+        hold, err := parseWaitlistHold(c)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+        updates["waitlist_hold_minutes"] = hold
+    }
+
+    // Check-in settings (only when sent; fields left out keep their stored values)
+    for _, key := range checkInSettingKeys {
+        if c.FormValue(key) == "" {
+            continue
+        }
+        required, opens, closes, autoForfeit, err := parseCheckInSettings(c, existingTournament)
+        if err != nil {
+            return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+        }
+        updates["check_in_required"] = required
+        updates["check_in_opens_minutes"] = opens
+        updates["check_in_closes_minutes"] = closes
+        updates["auto_forfeit_no_shows"] = autoForfeit
+        break
     }
 
     if parsedEndTime != nil {
@@ -348,6 +461,12 @@ func (s *TournamentService) UpdateTournament(c *fiber.Ctx) error {
         if err := tx.Model(&existingTournament).Updates(updates).Error; err != nil {
             return err
         }
+        if newStatus != "" {
+            existingTournament.Status = fromStatus
+            if err := transitionTournament(tx, &existingTournament, newStatus, nil); err != nil {
+                return err
+            }
+        }
 
         // Step 2: Handle Photo Removals - Delete from database
         for _, sortOrder := range removedIndices {
@@ -419,8 +538,18 @@ func (s *TournamentService) UpdateTournament(c *fiber.Ctx) error {
 
     if err != nil {
         log.Printf("ERROR: Transaction failed for updating tournament %s: %v", id, err)
+        if errors.Is(err, ErrInvalidTransition) {
+            return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+        }
         return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update tournament", "details": err.Error()})
     }
+    if newStatus != "" {
+        s.afterTournamentTransition(&existingTournament, fromStatus)
+    }
+    // A raised (or removed) cap opens slots for the waitlist
+    if updates["max_subscribers"] != oldMaxSubscribers {
+        s.releaseSlot(existingTournament.ID)
+    }
 
     // Fetch the *fully updated* tournament with ALL associations
     if err := s.DB.
@@ -483,7 +612,9 @@ func (s *TournamentService) GetAllTournamentsMini(c *fiber.Ctx) error {
 		StartTime        time.Time  `json:"start_time"`
 		EndTime          time.Time  `json:"end_time"`
 		MainPhotoURL     string     `json:"main_photo_url"`
-		EntryFee         float64    `json:"entry_fee"`
+		EntryFee         float64    `json:"entry_fee" gorm:"-"`
+		EntryFeeMinor    int64      `json:"-"`
+		Currency         string     `json:"currency"`
 		PrizePool        string     `json:"prize_pool"`
 		SponsorName      string     `json:"sponsor_name"`
 		IsFeatured       bool       `json:"is_featured"`
@@ -514,7 +645,8 @@ func (s *TournamentService) GetAllTournamentsMini(c *fiber.Ctx) error {
             t.start_time,
             t.end_time,
             t.main_photo_url,
-            t.entry_fee,
+            t.entry_fee_minor,
+            t.currency,
             t.prize_pool,
             t.sponsor_name,
             t.is_featured,
@@ -545,6 +677,9 @@ func (s *TournamentService) GetAllTournamentsMini(c *fiber.Ctx) error {
 		log.Printf("ERROR fetching mini tournaments: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch tournaments"})
 	}
+	for i := range tournaments {
+		tournaments[i].EntryFee = majorUnits(tournaments[i].EntryFeeMinor, tournaments[i].Currency)
+	}
 
 	return c.JSON(tournaments)
 }
@@ -559,9 +694,11 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 		WaiverAmount   float64 `json:"waiver_amount,omitempty"`
 		PaymentID      string  `json:"payment_id,omitempty"`
 		PaymentAmount  float64 `json:"payment_amount,omitempty"`
-		PaymentStatus  string  `json:"payment_status" validate:"oneof=pending paid failed refunded waived"`
+		PaymentStatus  string  `json:"payment_status" validate:"oneof=pending waived"`
 		TransactionID  string  `json:"transaction_id,omitempty"`
 		PaymentMethod  string  `json:"payment_method,omitempty"`
+		TeamID         string  `json:"team_id,omitempty"` // team-based tournaments: the captain subscribes the team
+		Region         string  `json:"region,omitempty"`  // e.g. "EU", "NA"; used by separate_regions pairing
 	}
 
 	tournamentID := c.Params("id")
@@ -574,13 +711,36 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON", "details": err.Error()})
 	}
 
-	if req.ExternalUserID == "" || req.UserName == "" {
-		return c.Status(400).JSON(fiber.Map{"error": "external_user_id and user_name are required"})
+	// Callers subscribe themselves: the team captain and waiver owner checks below run against
+	// the authenticated user, never an ID from the body
+	userID := c.Locals("user_id").(string)
+	if userID == "" {
+		return c.Status(401).JSON(fiber.Map{"error": "authentication required"})
+	}
+	if req.ExternalUserID == "" {
+		req.ExternalUserID = userID
+	}
+	if req.ExternalUserID != userID {
+		return c.Status(403).JSON(fiber.Map{"error": "external_user_id must be the authenticated user"})
+	}
+	if req.UserName == "" && req.TeamID == "" {
+		return c.Status(400).JSON(fiber.Map{"error": "user_name is required"})
+	}
+	req.Region = strings.ToUpper(strings.TrimSpace(req.Region))
+	if len(req.Region) > 32 {
+		return c.Status(400).JSON(fiber.Map{"error": "region must be at most 32 characters"})
 	}
 
 	if req.PaymentStatus == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "payment_status is required"})
 	}
+	// Payments are never taken on the client's word: a subscription starts pending and only
+	// becomes paid through VerifySubscriptionPayment
+	if req.PaymentStatus != "pending" && req.PaymentStatus != "waived" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "payment_status must be 'pending' or 'waived'; confirm payments via POST /tournaments/:id/verify-payment",
+		})
+	}
 
 	// Fetch tournament
 	var tournament models.Tournament
@@ -591,10 +751,24 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "DB error fetching tournament"})
 	}
 
-	// 🔑 WAIVER LOGIC
-	var effectiveEntryFee float64 = tournament.EntryFee
+	// 👥 Team entry: the captain pays (or applies a waiver) for the whole roster
+	var team *models.Team
+	if tournament.IsTeamBased {
+		t, err := s.validateTeamEntry(&tournament, req.TeamID, userID)
+		if err != nil {
+			return teamErrorResponse(c, err)
+		}
+		team = t
+	} else if req.TeamID != "" {
+		return teamErrorResponse(c, ErrNotTeamBased)
+	}
+
+	// 🔑 WAIVER LOGIC (amounts are worked out in minor units of the tournament's currency)
+	currency := tournament.Currency
+	entryFee := tournament.EntryFee
+	effectiveEntryFee := entryFee
 	var waiverToUse *models.UserWaiver
-	var amountToApply float64 = 0.0
+	var amountToApply int64
 
 	if req.WaiverCode != "" {
 		if !tournament.AcceptsWaivers {
@@ -603,7 +777,7 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 
 		codeUpper := strings.ToUpper(req.WaiverCode)
 		var w models.UserWaiver
-		if err := s.DB.Where("user_id = ? AND UPPER(code) = ?", req.ExternalUserID, codeUpper).First(&w).Error; err != nil {
+		if err := s.DB.Where("user_id = ? AND UPPER(code) = ?", userID, codeUpper).First(&w).Error; err != nil {
 			if err == gorm.ErrRecordNotFound {
 				return c.Status(400).JSON(fiber.Map{"error": "waiver code not found or not owned by user"})
 			}
@@ -616,95 +790,57 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 		if w.ExpiresAt != nil && w.ExpiresAt.Before(time.Now()) {
 			return c.Status(400).JSON(fiber.Map{"error": "waiver has expired"})
 		}
+		if w.Currency != currency {
+			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("waiver is in %s but the tournament charges %s", w.Currency, currency)})
+		}
 
 		remaining := w.Amount - w.UsedAmount
 		if remaining <= 0 {
 			return c.Status(400).JSON(fiber.Map{"error": "waiver is fully used and has no remaining balance"})
 		}
 
-		amountToApply = req.WaiverAmount
-		if amountToApply <= 0 {
-			amountToApply = remaining
-		}
-		if amountToApply > remaining {
-			amountToApply = remaining
+		apply := minorUnits(req.WaiverAmount, currency)
+		if apply <= 0 || apply > remaining {
+			apply = remaining
 		}
 
 		waiverToUse = &w
-		effectiveEntryFee = tournament.EntryFee - amountToApply
-		if effectiveEntryFee < 0 {
-			effectiveEntryFee = 0
-		}
+		amountToApply = apply
+		effectiveEntryFee = max(entryFee-apply, 0)
 
-		log.Printf("Applying waiver %s: $%.2f (remaining $%.2f), effective fee: $%.2f", w.Code, amountToApply, remaining, effectiveEntryFee)
-	}
-
-	// Check already subscribed
-	var existingSub models.TournamentSubscription
-	if err := s.DB.Where("tournament_id = ? AND external_user_id = ?", tournamentID, req.ExternalUserID).
-		First(&existingSub).Error; err == nil {
-		return c.Status(409).JSON(fiber.Map{
-			"error":        "user already subscribed",
-			"subscription": existingSub,
-		})
-	}
-
-	// Enforce max subscribers
-	if tournament.MaxSubscribers > 0 {
-		var count int64
-		s.DB.Model(&models.TournamentSubscription{}).
-			Where("tournament_id = ?", tournamentID).
-			Count(&count)
-		if int(count) >= tournament.MaxSubscribers {
-			return c.Status(403).JSON(fiber.Map{"error": "tournament is full"})
-		}
+		log.Printf("Applying waiver %s: %s (remaining %s), effective fee: %s", w.Code,
+			formatAmount(amountToApply, currency), formatAmount(remaining, currency), formatAmount(effectiveEntryFee, currency))
 	}
 
 	// 🔐 Payment validation
-	paymentAmount := req.PaymentAmount
+	paymentAmount := minorUnits(req.PaymentAmount, currency)
 	paymentID := req.PaymentID
-	transactionID := req.TransactionID
 	paymentMethod := req.PaymentMethod
-	var paymentAt *time.Time
+
+	// Nothing left to pay (free entry or a waiver covering it all): a pending entry would never be
+	// paid and would just sit there until it expired
+	if req.PaymentStatus == "pending" && effectiveEntryFee <= 0 {
+		req.PaymentStatus = "waived"
+	}
 
 	switch req.PaymentStatus {
 	case "waived":
 		if effectiveEntryFee > 0 {
 			return c.Status(400).JSON(fiber.Map{"error": "payment_status 'waived' invalid when effective fee > 0"})
 		}
-		paymentAmount = 0.0
+		paymentAmount = 0
 		if paymentID == "" {
 			paymentID = "waived-" + uuid.NewString()
 		}
-	case "paid":
-		if paymentAmount <= 0 {
-			return c.Status(400).JSON(fiber.Map{"error": "payment_amount must be > 0 for 'paid'"})
-		}
-		if paymentAmount != effectiveEntryFee {
-			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("payment_amount ($%.2f) must match effective fee ($%.2f) for 'paid'", paymentAmount, effectiveEntryFee)})
-		}
-		if paymentID == "" {
-			return c.Status(400).JSON(fiber.Map{"error": "payment_id required for 'paid'"})
-		}
-		now := time.Now()
-		paymentAt = &now
-	case "refunded", "failed":
-		if paymentID == "" {
-			return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("payment_id required for '%s'", req.PaymentStatus)})
-		}
-		if req.PaymentStatus == "refunded" && paymentAmount <= 0 {
-			return c.Status(400).JSON(fiber.Map{"error": "payment_amount must be > 0 for 'refunded'"})
-		}
-		now := time.Now()
-		paymentAt = &now
 	case "pending":
 		if effectiveEntryFee > 0 && paymentID == "" {
 			paymentID = "pending-" + uuid.NewString()
 		}
 		if paymentAmount != effectiveEntryFee {
-			log.Printf("Warning: payment_amount ($%.2f) does not match effective fee ($%.2f) for 'pending'. Using effective fee.", paymentAmount, effectiveEntryFee)
-			paymentAmount = effectiveEntryFee
+			log.Printf("Warning: payment_amount (%s) does not match effective fee (%s) for 'pending'. Using effective fee.",
+				formatAmount(paymentAmount, currency), formatAmount(effectiveEntryFee, currency))
 		}
+		paymentAmount = effectiveEntryFee
 	}
 
 	// ✅ Create subscription
@@ -713,26 +849,83 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 		subUserAvatarURL = &req.UserAvatarURL
 	}
 
+	userName := req.UserName
+	teamID := ""
+	if team != nil {
+		userName = team.Name
+		teamID = team.ID
+		if team.LogoURL != "" {
+			subUserAvatarURL = &team.LogoURL
+		}
+	}
+
 	sub := models.TournamentSubscription{
 		ID:               uuid.NewString(),
 		TournamentID:     tournamentID,
 		ExternalUserID:   req.ExternalUserID,
-		UserName:         req.UserName,
+		UserName:         userName,
 		UserAvatarURL:    subUserAvatarURL,
+		TeamID:           teamID,
+		Region:           req.Region,
 		JoinedAt:         time.Now(),
 		PaymentID:        paymentID,
 		PaymentAmount:    paymentAmount,
+		Currency:         currency,
 		PaymentStatus:    req.PaymentStatus,
-		TransactionID:    transactionID,
 		PaymentMethod:    paymentMethod,
-		PaymentAt:        paymentAt,
 		WaiverCodeUsed:   req.WaiverCode,
 		WaiverAmountUsed: amountToApply,
 	}
 
-	// 🔁 Atomic Waiver Update and Subscription Creation
-	if waiverToUse != nil && amountToApply > 0 {
-		err := s.DB.Transaction(func(tx *gorm.DB) error {
+	// 🔁 Capacity, uniqueness and the waiver debit are settled in one transaction. Locking the
+	// tournament row serializes concurrent subscribes; the unique indexes on (tournament, user)
+	// and (tournament, team) back that up.
+	var existingSub models.TournamentSubscription
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&tournament, "id = ?", tournamentID).Error; err != nil {
+			return fmt.Errorf("failed to lock tournament: %w", err)
+		}
+		// The fee and waiver were worked out from the fee and currency read above
+		if tournament.Currency != currency || tournament.EntryFee != entryFee {
+			return fmt.Errorf("tournament entry fee changed to %s; retry", formatAmount(tournament.EntryFee, tournament.Currency))
+		}
+
+		// Check already subscribed. A subscription whose payment failed or expired is reused.
+		resubscribe := false
+		err := tx.Where("tournament_id = ? AND external_user_id = ?", tournamentID, req.ExternalUserID).
+			First(&existingSub).Error
+		if err == nil {
+			if !slices.Contains(retryableSubscriptionStatuses, existingSub.PaymentStatus) {
+				return ErrAlreadySubscribed
+			}
+			resubscribe = true
+			sub.ID = existingSub.ID
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if team != nil {
+			err := tx.Where("tournament_id = ? AND team_id = ? AND id <> ?", tournamentID, team.ID, sub.ID).First(&existingSub).Error
+			if err == nil {
+				return ErrTeamAlreadySubscribed
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+
+		// Enforce max subscribers. A promoted waitlist entry's held slot counts as free for its owner.
+		if tournament.MaxSubscribers > 0 {
+			count, err := countOccupiedSlots(tx, tournamentID, req.ExternalUserID)
+			if err != nil {
+				return err
+			}
+			if int(count) >= tournament.MaxSubscribers {
+				return ErrTournamentFull
+			}
+		}
+
+		if waiverToUse != nil && amountToApply > 0 {
 			var wLocked models.UserWaiver
 			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 				Where("id = ?", waiverToUse.ID).
@@ -742,7 +935,7 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 
 			remainingInTx := wLocked.Amount - wLocked.UsedAmount
 			if remainingInTx < amountToApply {
-				return fmt.Errorf("insufficient waiver balance at transaction time (have $%.2f, need $%.2f)", remainingInTx, amountToApply)
+				return fmt.Errorf("insufficient waiver balance at transaction time (have %s, need %s)", formatAmount(remainingInTx, currency), formatAmount(amountToApply, currency))
 			}
 
 			newUsed := wLocked.UsedAmount + amountToApply
@@ -753,28 +946,67 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 			if err := tx.Model(&wLocked).
 				Where("id = ?", wLocked.ID).
 				Updates(map[string]interface{}{
-					"used_amount": newUsed,
-					"is_redeemed": true,
+					"used_amount_minor": newUsed,
+					"is_redeemed":       true,
 				}).Error; err != nil {
 				return fmt.Errorf("failed to update waiver used amount: %w", err)
 			}
 
 			sub.WaiverIDUsed = wLocked.ID
-
-			if err := tx.Create(&sub).Error; err != nil {
-				return fmt.Errorf("failed to create subscription: %w", err)
+			if err := postLedger(tx, ledgerEntry{
+				Kind:           models.LedgerKindWaiverApplied,
+				Currency:       currency,
+				TournamentID:   tournamentID,
+				SubscriptionID: sub.ID,
+				Description:    fmt.Sprintf("waiver %s applied to entry fee", wLocked.Code),
+				Actor:          req.ExternalUserID,
+				Legs: []ledgerLeg{
+					debit(ledgerWaiverLiability, amountToApply),
+					credit(poolLedgerAccount(tournamentID), amountToApply),
+				},
+			}); err != nil {
+				return err
 			}
+		}
 
-			return nil
+		if resubscribe {
+			if err := tx.Save(&sub).Error; err != nil {
+				return fmt.Errorf("failed to renew subscription: %w", err)
+			}
+		} else if err := tx.Create(&sub).Error; err != nil {
+			if errors.Is(err, gorm.ErrDuplicatedKey) {
+				return ErrAlreadySubscribed
+			}
+			return fmt.Errorf("failed to create subscription: %w", err)
+		}
+		return markWaitlistEnrolled(tx, tournamentID, req.ExternalUserID)
+	})
+	switch {
+	case errors.Is(err, ErrAlreadySubscribed):
+		return c.Status(409).JSON(fiber.Map{
+			"error":        "user already subscribed",
+			"subscription": existingSub,
 		})
+	case errors.Is(err, ErrTeamAlreadySubscribed):
+		return c.Status(409).JSON(fiber.Map{
+			"error":        "team already subscribed",
+			"subscription": existingSub,
+		})
+	case errors.Is(err, ErrTournamentFull):
+		// A full tournament queues the user on its waitlist instead
+		entry, err := s.joinWaitlist(tournamentID, req.ExternalUserID, sub.UserName, sub.UserAvatarURL, sub.TeamID)
 		if err != nil {
-			log.Printf("Transaction failed for subscription with waiver: %v", err)
-			return c.Status(500).JSON(fiber.Map{"error": "subscription with waiver failed", "details": err.Error()})
-		}
-	} else {
-		if err := s.DB.Create(&sub).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "failed to create subscription", "details": err.Error()})
+			return c.Status(500).JSON(fiber.Map{"error": "failed to join waitlist", "details": err.Error()})
 		}
+		position, _ := waitlistPosition(s.DB, entry)
+		return c.Status(202).JSON(fiber.Map{
+			"message":  "tournament is full; added to the waitlist",
+			"waitlist": entry,
+			"position": position,
+		})
+	case err != nil:
+		log.Printf("Transaction failed for subscription: %v", err)
+		return c.Status(500).JSON(fiber.Map{"error": "subscription failed", "details": err.Error()})
 	}
 
 	return c.Status(201).JSON(fiber.Map{
@@ -785,11 +1017,13 @@ func (s *TournamentService) SubscribeToTournament(c *fiber.Ctx) error {
 			"external_user_id":   sub.ExternalUserID,
 			"user_name":          sub.UserName,
 			"user_avatar_url":    sub.UserAvatarURL,
+			"team_id":            sub.TeamID,
 			"joined_at":          sub.JoinedAt,
 			"payment_status":     sub.PaymentStatus,
-			"payment_amount":     sub.PaymentAmount,
+			"payment_amount":     majorUnits(sub.PaymentAmount, sub.Currency),
+			"currency":           sub.Currency,
 			"waiver_code_used":   sub.WaiverCodeUsed,
-			"waiver_amount_used": sub.WaiverAmountUsed,
+			"waiver_amount_used": majorUnits(sub.WaiverAmountUsed, sub.Currency),
 		},
 	})
 }
@@ -830,13 +1064,15 @@ func (s *TournamentService) SuspendSubscription(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{"message": "subscription suspended", "subscription": sub})
 }
 
-// RevokeSubscription revokes a user's subscription
+// RevokeSubscription revokes a user's subscription. Paid and waived entries are refunded (see
+// refundSubscription); an unpaid entry gives back the waiver balance it was holding.
 func (s *TournamentService) RevokeSubscription(c *fiber.Ctx) error {
 	tournamentID := c.Params("tournament_id")
 	userID := c.Params("user_id")
 
 	type Req struct {
-		Reason string `json:"reason"`
+		Reason     string `json:"reason"`
+		AsCashback bool   `json:"as_cashback,omitempty"` // refund a paid entry as a cashback waiver
 	}
 
 	var req Req
@@ -852,31 +1088,59 @@ func (s *TournamentService) RevokeSubscription(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
 	}
 
-	if sub.PaymentStatus == "paid" {
-		log.Printf("INFO: Subscription for user %s in tournament %s was paid ($%.2f). Refund logic needed.", userID, tournamentID, sub.PaymentAmount)
-	} else if sub.WaiverCodeUsed != "" && sub.WaiverAmountUsed > 0 {
-		log.Printf("INFO: Subscription for user %s in tournament %s used waiver '%s' ($%.2f). Waiver benefit lost.", userID, tournamentID, sub.WaiverCodeUsed, sub.WaiverAmountUsed)
-	}
-
-	now := time.Now()
-	updates := map[string]interface{}{
-		"payment_status": "revoked",
-		"revoked_at":     &now,
-		"revoked_reason": req.Reason,
-	}
-
-	if err := s.DB.Model(&sub).Updates(updates).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "revoke failed"})
+	var refund *models.SubscriptionRefund
+	switch sub.PaymentStatus {
+	case "revoked":
+		return refundErrorResponse(c, ErrAlreadyRevoked)
+	case "paid", "waived":
+		var err error
+		refund, err = s.refundSubscription(sub.ID, RefundOptions{
+			Reason:     req.Reason,
+			RefundedBy: c.Locals("user_id").(string),
+			AsCashback: req.AsCashback,
+			Revoke:     true,
+		})
+		if err != nil {
+			return refundErrorResponse(c, err)
+		}
+	default:
+		err := s.DB.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			res := tx.Model(&models.TournamentSubscription{}).
+				Where("id = ? AND payment_status = ?", sub.ID, sub.PaymentStatus).
+				Updates(map[string]interface{}{
+					"payment_status": "revoked",
+					"revoked_at":     &now,
+					"revoked_reason": req.Reason,
+				})
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return ErrAlreadyRevoked
+			}
+			// Only a pending entry still holds its waiver; refunds and expiry already gave it back
+			if sub.PaymentStatus != "pending" {
+				return nil
+			}
+			return restoreWaiverBalance(tx, sub, "revoked: "+req.Reason)
+		})
+		if err != nil {
+			return refundErrorResponse(c, err)
+		}
 	}
+	s.releaseSlot(tournamentID)
 
-	return c.JSON(fiber.Map{"message": "subscription revoked", "subscription": sub})
+	s.DB.First(&sub, "id = ?", sub.ID)
+	return c.JSON(fiber.Map{"message": "subscription revoked", "subscription": sub, "refund": refund})
 }
 
-// RefundSubscription updates a subscription to 'refunded' status
+// RefundSubscription refunds a paid or waived subscription: the waiver balance it used is
+// restored and the paid amount goes back through the provider, or as a cashback waiver
 func (s *TournamentService) RefundSubscription(c *fiber.Ctx) error {
 	type Req struct {
 		RefundReason string `json:"refund_reason,omitempty"`
-		RefundedBy   string `json:"refunded_by,omitempty"`
+		AsCashback   bool   `json:"as_cashback,omitempty"` // credit a cashback waiver instead of cash
 	}
 
 	tournamentID := c.Params("tournament_id")
@@ -900,83 +1164,23 @@ func (s *TournamentService) RefundSubscription(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
 	}
 
-	if sub.PaymentStatus == "refunded" {
-		return c.Status(400).JSON(fiber.Map{"error": "already refunded"})
-	}
-
-	if sub.PaymentStatus != "paid" {
-		return c.Status(400).JSON(fiber.Map{
-			"error":   "only 'paid' subscriptions can be refunded",
-			"current": sub.PaymentStatus,
-		})
-	}
-
-	now := time.Now()
-	updates := map[string]interface{}{
-		"payment_status": "refunded",
-		"payment_at":     now,
-	}
-
-	if err := s.DB.Model(&sub).Updates(updates).Error; err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "refund failed"})
+	refund, err := s.refundSubscription(sub.ID, RefundOptions{
+		Reason:     req.RefundReason,
+		RefundedBy: c.Locals("user_id").(string),
+		AsCashback: req.AsCashback,
+	})
+	if err != nil {
+		return refundErrorResponse(c, err)
 	}
+	s.releaseSlot(tournamentID)
 
 	s.DB.First(&sub, "id = ?", sub.ID)
+	s.DB.First(refund, "id = ?", refund.ID)
 
 	return c.JSON(fiber.Map{
 		"message":      "refund processed",
 		"subscription": sub,
-	})
-}
-
-// GetSupportedMatchTypes returns all supported match types
-func (s *TournamentService) GetSupportedMatchTypes(c *fiber.Ctx) error {
-	matchTypes := []map[string]interface{}{
-		{
-			"id":          "SINGLE_ELIMINATION_1V1",
-			"name":        "Single Elimination 1v1",
-			"description": "Head-to-head knockout tournament",
-			"default_player_count_min": 2,
-			"default_player_count_max": 128,
-			"supports_pairing": true,
-		},
-		{
-			"id":          "DOUBLE_ELIMINATION_1V1",
-			"name":        "Double Elimination 1v1",
-			"description": "Double elimination bracket",
-			"default_player_count_min": 2,
-			"default_player_count_max": 128,
-			"supports_pairing": true,
-		},
-		{
-			"id":          "ROUND_ROBIN_1V1",
-			"name":        "Round Robin 1v1",
-			"description": "Every player plays every other player",
-			"default_player_count_min": 2,
-			"default_player_count_max": 16,
-			"supports_pairing": true,
-		},
-		{
-			"id":          "LEADERBOARD_CHALLENGE",
-			"name":        "Leaderboard Challenge",
-			"description": "Players compete for highest score",
-			"default_player_count_min": 1,
-			"default_player_count_max": 1000,
-			"supports_pairing": false,
-		},
-		{
-			"id":          "SWISS_SYSTEM",
-			"name":        "Swiss System",
-			"description": "Players with similar records face each other",
-			"default_player_count_min": 4,
-			"default_player_count_max": 128,
-			"supports_pairing": true,
-		},
-	}
-	
-	return c.JSON(fiber.Map{
-		"match_types": matchTypes,
-		"count":       len(matchTypes),
+		"refund":       refund,
 	})
 }
 
@@ -1075,6 +1279,10 @@ func (s *TournamentService) CreateBatchWithMatchesAndRounds(c *fiber.Ctx) error
 	// Prepare matches and rounds
 	var matches []models.TournamentMatch
 	for _, matchReq := range req.Matches {
+		if err := validateMatchType(s.DB, matchReq.MatchType); err != nil {
+			return matchTypeErrorResponse(c, err)
+		}
+
 		// Parse match dates
 		var matchStartDate, matchEndDate time.Time
 		if matchReq.StartDate != "" {
@@ -1166,6 +1374,8 @@ func (s *TournamentService) CreateBatchWithMatchesAndRounds(c *fiber.Ctx) error
 			// Create rounds for this match
 			for j := range batch.Matches[i].Rounds {
 				batch.Matches[i].Rounds[j].MatchID = batch.Matches[i].ID
+				batch.Matches[i].Rounds[j].BatchID = batch.ID
+				batch.Matches[i].Rounds[j].TournamentID = tournamentID
 				if err := tx.Create(&batch.Matches[i].Rounds[j]).Error; err != nil {
 					return err
 				}
@@ -1320,6 +1530,7 @@ func (s *TournamentService) CreateMatch(c *fiber.Ctx) error {
 		EndDate     string `json:"end_date"`
 		MatchType   string `json:"match_type" validate:"required"`
 		BatchID     string `json:"batch_id" validate:"required"`
+		CheckInRequired bool `json:"check_in_required"`
 	}
 
 	var req Req
@@ -1343,6 +1554,10 @@ func (s *TournamentService) CreateMatch(c *fiber.Ctx) error {
 	}
 	// If we reach here, the batch is valid and belongs to the tournament.
 
+	if err := validateMatchType(s.DB, req.MatchType); err != nil {
+		return matchTypeErrorResponse(c, err)
+	}
+
 	// Parse dates from the request body
 	var startDate, endDate time.Time
 	var err error
@@ -1370,6 +1585,7 @@ func (s *TournamentService) CreateMatch(c *fiber.Ctx) error {
 		EndDate:     endDate,
 		Status:      "pending", // Default status
 		MatchType:   req.MatchType,
+		CheckInRequired: req.CheckInRequired,
 		// REMOVED: Player1ID, Player1Name, Player2ID, Player2Name - will be handled by pairing service
 	}
 
@@ -1401,6 +1617,7 @@ func (s *TournamentService) UpdateMatch(c *fiber.Ctx) error {
 		StartDate   *string `json:"start_date,omitempty"` // RFC3339 string
 		EndDate     *string `json:"end_date,omitempty"`   // RFC3339 string
 		MatchType   *string `json:"match_type,omitempty"`
+		CheckInRequired *bool `json:"check_in_required,omitempty"`
 	}
 
 	var req Req
@@ -1434,11 +1651,20 @@ func (s *TournamentService) UpdateMatch(c *fiber.Ctx) error {
 		updates["sort_order"] = *req.SortOrder
 	}
 	if req.Status != nil {
+		if !models.CanTransitionStage(existingMatch.Status, *req.Status) {
+			return c.Status(409).JSON(fiber.Map{"error": fmt.Sprintf("match cannot move from %q to %q", existingMatch.Status, *req.Status)})
+		}
 		updates["status"] = *req.Status
 	}
 	if req.MatchType != nil { 
+		if err := validateMatchType(s.DB, *req.MatchType); err != nil {
+			return matchTypeErrorResponse(c, err)
+		}
 		updates["match_type"] = *req.MatchType
 	}
+	if req.CheckInRequired != nil {
+		updates["check_in_required"] = *req.CheckInRequired
+	}
 	// Handle date updates with validation
 	var newStartDate, newEndDate time.Time
 	if req.StartDate != nil {
@@ -1659,6 +1885,9 @@ func (s *TournamentService) UpdateRound(c *fiber.Ctx) error {
 		updates["sort_order"] = *req.SortOrder
 	}
 	if req.Status != nil {
+		if !models.CanTransitionStage(existingRound.Status, *req.Status) {
+			return c.Status(409).JSON(fiber.Map{"error": fmt.Sprintf("round cannot move from %q to %q", existingRound.Status, *req.Status)})
+		}
 		updates["status"] = *req.Status
 	}
 	if req.DurationMins != nil {
@@ -1780,7 +2009,11 @@ func (s *TournamentService) GetTournamentByID(c *fiber.Ctx) error {
 		Count(&leaderboardCount)
 
 	// Calculate available slots
-	availableSlots := int64(tournament.MaxSubscribers) - subsCount
+	occupied, _ := countOccupiedSlots(s.DB, tournament.ID, "")
+	availableSlots := int64(tournament.MaxSubscribers) - occupied
+	if availableSlots < 0 {
+		availableSlots = 0
+	}
 	if tournament.MaxSubscribers <= 0 {
 		availableSlots = -1
 	}
@@ -1790,6 +2023,14 @@ func (s *TournamentService) GetTournamentByID(c *fiber.Ctx) error {
 	tournament.ActiveSubscribersCount = activeSubsCount
 	tournament.AvailableSlots = availableSlots
 
+	if display, err := displayCurrency(c); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	} else if display != "" {
+		if tournament.EntryFeeDisplay, err = convertForDisplay(s.DB, majorUnits(tournament.EntryFee, tournament.Currency), tournament.Currency, display); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to convert entry fee", "details": err.Error()})
+		}
+	}
+
 	return c.JSON(tournament)
 }
 
@@ -1854,57 +2095,43 @@ func (s *TournamentService) UpdateTournamentStatus(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "invalid JSON"})
 	}
 
-	var updates map[string]interface{}
-	switch req.Status {
-	case "publish":
-		var tournament models.Tournament
-		if err := s.DB.First(&tournament, "id = ?", id).Error; err != nil {
-			if err == gorm.ErrRecordNotFound {
-				return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
-			}
-			return c.Status(500).JSON(fiber.Map{"error": "DB error"})
-		}
-
-		finalStatus := "published"
-		publishedAt := time.Now()
-		if tournament.PublishSchedule != nil {
-			if tournament.PublishSchedule.Before(time.Now()) {
-				finalStatus = "active"
-				publishedAt = *tournament.PublishSchedule
-			} else {
-				finalStatus = "published"
-				publishedAt = *tournament.PublishSchedule
-			}
-		} else {
-			finalStatus = "active"
+	var tournament models.Tournament
+	if err := s.DB.First(&tournament, "id = ?", id).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
 		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
 
-		updates = map[string]interface{}{
-			"status":       finalStatus,
-			"published_at": publishedAt,
-		}
-	case "unpublish":
-		updates = map[string]interface{}{
-			"status":       "draft",
-			"published_at": nil,
+	// All status writes go through the lifecycle state machine
+	from := tournament.Status
+	now := time.Now()
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		switch req.Status {
+		case "publish":
+			return publishTournament(tx, &tournament, now)
+		case "unpublish":
+			return transitionTournament(tx, &tournament, models.TournamentStatusDraft, map[string]interface{}{
+				"published_at": nil,
+			})
+		case models.TournamentStatusPublished:
+			return transitionTournament(tx, &tournament, req.Status, map[string]interface{}{
+				"published_at": now,
+			})
+		case models.TournamentStatusDraft, models.TournamentStatusActive,
+			models.TournamentStatusCompleted, models.TournamentStatusCancelled:
+			return transitionTournament(tx, &tournament, req.Status, nil)
+		default:
+			return fmt.Errorf("%w: unknown status %q", ErrInvalidTransition, req.Status)
 		}
-	case "draft", "published", "active", "completed", "cancelled":
-		updates = map[string]interface{}{
-			"status": req.Status,
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
 		}
-	default:
-		return c.Status(400).JSON(fiber.Map{"error": "invalid status"})
-	}
-
-	result := s.DB.Model(&models.Tournament{}).
-		Where("id = ?", id).
-		Updates(updates)
-	if result.Error != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "DB update failed"})
 	}
-	if result.RowsAffected == 0 {
-		return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
-	}
+	s.afterTournamentTransition(&tournament, from)
 
 	// Return updated tournament
 	var updated models.Tournament
@@ -2089,16 +2316,23 @@ func (s *TournamentService) SchedulePublish(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
 	}
 
-	// Update tournament with publish schedule and set status to "scheduled"
-	updates := map[string]interface{}{
+	// Move to "scheduled"; the lifecycle scheduler publishes it at publish_schedule
+	from := tournament.Status
+	if tournament.Status == models.TournamentStatusScheduled {
+		// Rescheduling: only the time changes
+		if err := s.DB.Model(&tournament).Update("publish_schedule", publishTime).Error; err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to schedule publish"})
+		}
+	} else if err := transitionTournament(s.DB, &tournament, models.TournamentStatusScheduled, map[string]interface{}{
 		"publish_schedule": publishTime,
-		"status":           "scheduled",
 		"published_at":     nil, // Clear published_at since it's not published yet
-	}
-
-	if err := s.DB.Model(&tournament).Updates(updates).Error; err != nil {
+	}); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to schedule publish"})
 	}
+	s.afterTournamentTransition(&tournament, from)
 
 	// Fetch the updated tournament
 	err = s.DB.
@@ -2143,33 +2377,17 @@ func (s *TournamentService) PublishNow(c *fiber.Ctx) error {
 		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
 	}
 
-	// Determine final status based on publish schedule
-	finalStatus := "active"
-	publishedAt := time.Now()
-
-	// If there's a publish schedule that's in the future, clear it
-	if tournament.PublishSchedule != nil && tournament.PublishSchedule.After(time.Now()) {
-		// Clear the schedule since we're publishing now
-		updates := map[string]interface{}{
-			"status":           finalStatus,
-			"published_at":     publishedAt,
-			"publish_schedule": nil,
-		}
-
-		if err := s.DB.Model(&tournament).Updates(updates).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "failed to publish tournament"})
-		}
-	} else {
-		// No schedule or schedule has passed, just publish
-		updates := map[string]interface{}{
-			"status":       finalStatus,
-			"published_at": publishedAt,
-		}
-
-		if err := s.DB.Model(&tournament).Updates(updates).Error; err != nil {
-			return c.Status(500).JSON(fiber.Map{"error": "failed to publish tournament"})
+	// Publish (and activate if the start time has already passed)
+	from := tournament.Status
+	if err := s.DB.Transaction(func(tx *gorm.DB) error {
+		return publishTournament(tx, &tournament, time.Now())
+	}); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
 		}
+		return c.Status(500).JSON(fiber.Map{"error": "failed to publish tournament"})
 	}
+	s.afterTournamentTransition(&tournament, from)
 
 	// Fetch the updated tournament
 	err := s.DB.
@@ -2219,14 +2437,15 @@ func (s *TournamentService) CancelScheduledPublish(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "tournament is not scheduled for future publishing"})
 	}
 
-	updates := map[string]interface{}{
-		"status":           "draft",
+	if err := transitionTournament(s.DB, &tournament, models.TournamentStatusDraft, map[string]interface{}{
 		"publish_schedule": nil,
-	}
-
-	if err := s.DB.Model(&tournament).Updates(updates).Error; err != nil {
+	}); err != nil {
+		if errors.Is(err, ErrInvalidTransition) {
+			return c.Status(409).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "failed to cancel scheduled publish"})
 	}
+	s.afterTournamentTransition(&tournament, models.TournamentStatusScheduled)
 
 	// Fetch the updated tournament
 	err := s.DB.
@@ -2268,7 +2487,9 @@ func (s *TournamentService) GetAllPublishedTournaments(c *fiber.Ctx) error {
 		StartTime        time.Time  `json:"start_time"`
 		EndTime          time.Time  `json:"end_time"`
 		MainPhotoURL     string     `json:"main_photo_url"`
-		EntryFee         float64    `json:"entry_fee"`
+		EntryFee         float64    `json:"entry_fee" gorm:"-"`
+		EntryFeeMinor    int64      `json:"-"`
+		Currency         string     `json:"currency"`
 		PrizePool        string     `json:"prize_pool"`
 		SponsorName      string     `json:"sponsor_name"`
 		IsFeatured       bool       `json:"is_featured"`
@@ -2287,6 +2508,13 @@ func (s *TournamentService) GetAllPublishedTournaments(c *fiber.Ctx) error {
 		Guidelines       string     `json:"guidelines,omitempty"`
 		AcceptsWaivers   bool       `json:"accepts_waivers"`
 		PublishSchedule  *time.Time `json:"publish_schedule,omitempty"`
+		// EntryFee in ?display_currency=, when a rate for the pair is configured
+		EntryFeeDisplay *models.DisplayAmount `json:"entry_fee_display,omitempty" gorm:"-"`
+	}
+
+	display, err := displayCurrency(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
 	}
 
 	var tournaments []TournamentMini
@@ -2302,7 +2530,8 @@ func (s *TournamentService) GetAllPublishedTournaments(c *fiber.Ctx) error {
             t.start_time,
             t.end_time,
             t.main_photo_url,
-            t.entry_fee,
+            t.entry_fee_minor,
+            t.currency,
             t.prize_pool,
             t.sponsor_name,
             t.is_featured,
@@ -2334,12 +2563,23 @@ func (s *TournamentService) GetAllPublishedTournaments(c *fiber.Ctx) error {
             t.created_at DESC
     `
 
-	err := s.DB.Raw(query).Scan(&tournaments).Error
+	err = s.DB.Raw(query).Scan(&tournaments).Error
 	if err != nil {
 		log.Printf("ERROR fetching published tournaments: %v", err)
 		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch tournaments"})
 	}
 
+	for i := range tournaments {
+		t := &tournaments[i]
+		t.EntryFee = majorUnits(t.EntryFeeMinor, t.Currency)
+		if display == "" {
+			continue
+		}
+		if t.EntryFeeDisplay, err = convertForDisplay(s.DB, t.EntryFee, t.Currency, display); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to convert entry fees", "details": err.Error()})
+		}
+	}
+
 	return c.JSON(tournaments)
 }
 
@@ -2389,7 +2629,11 @@ func (s *TournamentService) GetPublishedTournamentByID(c *fiber.Ctx) error {
 		Where("tournament_id = ? AND payment_status = 'paid'", id).
 		Count(&activeSubsCount)
 
-	availableSlots := int64(tournament.MaxSubscribers) - subsCount
+	occupied, _ := countOccupiedSlots(s.DB, tournament.ID, "")
+	availableSlots := int64(tournament.MaxSubscribers) - occupied
+	if availableSlots < 0 {
+		availableSlots = 0
+	}
 	if tournament.MaxSubscribers <= 0 {
 		availableSlots = -1
 	}
@@ -2398,6 +2642,14 @@ func (s *TournamentService) GetPublishedTournamentByID(c *fiber.Ctx) error {
 	tournament.ActiveSubscribersCount = activeSubsCount
 	tournament.AvailableSlots = availableSlots
 
+	if display, err := displayCurrency(c); err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	} else if display != "" {
+		if tournament.EntryFeeDisplay, err = convertForDisplay(s.DB, majorUnits(tournament.EntryFee, tournament.Currency), tournament.Currency, display); err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "failed to convert entry fee", "details": err.Error()})
+		}
+	}
+
 	return c.JSON(tournament)
 }
 
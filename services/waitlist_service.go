@@ -0,0 +1,296 @@
+package services
+
+import (
+	"errors"
+	"game-publish-system/models"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// freedSubscriptionStatuses no longer hold a tournament slot
+var freedSubscriptionStatuses = []string{"refunded", "failed", "revoked", "expired"}
+
+var (
+	ErrNotOnWaitlist = errors.New("not on the waitlist for this tournament")
+)
+
+// parseWaitlistHold reads waitlist_hold_minutes from a tournament form (default 30)
+func parseWaitlistHold(c *fiber.Ctx) (int, error) {
+	v := c.FormValue("waitlist_hold_minutes")
+	if v == "" {
+		return 30, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		return 0, errors.New("waitlist_hold_minutes must be a positive integer")
+	}
+	return n, nil
+}
+
+// countOccupiedSlots counts subscriptions holding a slot plus slots held for promoted waitlist
+// entries. exceptUserID's own hold is left out so they can take it.
+func countOccupiedSlots(db *gorm.DB, tournamentID, exceptUserID string) (int64, error) {
+	var subs int64
+	if err := db.Model(&models.TournamentSubscription{}).
+		Where("tournament_id = ? AND payment_status NOT IN ? AND forfeited_at IS NULL", tournamentID, freedSubscriptionStatuses).
+		Count(&subs).Error; err != nil {
+		return 0, err
+	}
+
+	var holds int64
+	if err := db.Model(&models.WaitlistEntry{}).
+		Where("tournament_id = ? AND status = ? AND hold_expires_at > ? AND external_user_id <> ?",
+			tournamentID, models.WaitlistStatusOffered, time.Now(), exceptUserID).
+		Count(&holds).Error; err != nil {
+		return 0, err
+	}
+	return subs + holds, nil
+}
+
+// waitlistPosition is the entry's 1-based place among those still waiting (0 once offered)
+func waitlistPosition(db *gorm.DB, entry *models.WaitlistEntry) (int64, error) {
+	if entry.Status != models.WaitlistStatusWaiting {
+		return 0, nil
+	}
+	var ahead int64
+	err := db.Model(&models.WaitlistEntry{}).
+		Where("tournament_id = ? AND status = ? AND queued_at <= ?", entry.TournamentID, models.WaitlistStatusWaiting, entry.QueuedAt).
+		Count(&ahead).Error
+	return ahead, err
+}
+
+// joinWaitlist queues a user for a full tournament. Someone already queued keeps their place;
+// an expired or cancelled entry goes to the back of the queue.
+func (s *TournamentService) joinWaitlist(tournamentID, userID, userName string, avatarURL *string, teamID string) (*models.WaitlistEntry, error) {
+	now := time.Now()
+	entry := models.WaitlistEntry{
+		ID:             uuid.NewString(),
+		TournamentID:   tournamentID,
+		ExternalUserID: userID,
+		UserName:       userName,
+		UserAvatarURL:  avatarURL,
+		TeamID:         teamID,
+		Status:         models.WaitlistStatusWaiting,
+		QueuedAt:       now,
+	}
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+		if res.Error != nil || res.RowsAffected > 0 {
+			return res.Error
+		}
+		if err := tx.Where("tournament_id = ? AND external_user_id = ?", tournamentID, userID).First(&entry).Error; err != nil {
+			return err
+		}
+		if entry.Status == models.WaitlistStatusWaiting || entry.Status == models.WaitlistStatusOffered {
+			return nil
+		}
+		updates := map[string]interface{}{
+			"status":          models.WaitlistStatusWaiting,
+			"user_name":       userName,
+			"user_avatar_url": avatarURL,
+			"team_id":         teamID,
+			"queued_at":       now,
+			"offered_at":      nil,
+			"hold_expires_at": nil,
+			"enrolled_at":     nil,
+		}
+		if err := tx.Model(&entry).Updates(updates).Error; err != nil {
+			return err
+		}
+		return tx.First(&entry, "id = ?", entry.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("⏳ %s joined the waitlist for tournament %s", userID, tournamentID)
+	return &entry, nil
+}
+
+// markWaitlistEnrolled closes a user's waitlist entry once they hold a subscription
+func markWaitlistEnrolled(db *gorm.DB, tournamentID, userID string) error {
+	now := time.Now()
+	return db.Model(&models.WaitlistEntry{}).
+		Where("tournament_id = ? AND external_user_id = ? AND status IN ?", tournamentID, userID,
+			[]string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}).
+		Updates(map[string]interface{}{"status": models.WaitlistStatusEnrolled, "enrolled_at": &now}).Error
+}
+
+// promoteWaitlist offers every free slot to the longest-waiting entries. An offered entry holds
+// its slot for WaitlistHoldMinutes while the user subscribes and pays.
+func (s *TournamentService) promoteWaitlist(tournamentID string) (int, error) {
+	promoted := 0
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var t models.Tournament
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&t, "id = ?", tournamentID).Error; err != nil {
+			return err
+		}
+		if models.IsTerminalTournamentStatus(t.Status) {
+			return nil
+		}
+
+		query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("tournament_id = ? AND status = ?", tournamentID, models.WaitlistStatusWaiting).
+			Order("queued_at ASC")
+		if t.MaxSubscribers > 0 {
+			occupied, err := countOccupiedSlots(tx, tournamentID, "")
+			if err != nil {
+				return err
+			}
+			free := int64(t.MaxSubscribers) - occupied
+			if free <= 0 {
+				return nil
+			}
+			query = query.Limit(int(free))
+		}
+
+		var entries []models.WaitlistEntry
+		if err := query.Find(&entries).Error; err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+
+		now := time.Now()
+		expires := now.Add(time.Duration(t.WaitlistHoldMinutes) * time.Minute)
+		ids := make([]string, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID
+		}
+		if err := tx.Model(&models.WaitlistEntry{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":          models.WaitlistStatusOffered,
+				"offered_at":      &now,
+				"hold_expires_at": &expires,
+			}).Error; err != nil {
+			return err
+		}
+		promoted = len(entries)
+		return nil
+	})
+	if err == nil && promoted > 0 {
+		log.Printf("🎟️ Offered %d waitlist slot(s) in tournament %s", promoted, tournamentID)
+	}
+	return promoted, err
+}
+
+// releaseSlot is called whenever a subscription stops holding a slot
+func (s *TournamentService) releaseSlot(tournamentID string) {
+	if _, err := s.promoteWaitlist(tournamentID); err != nil {
+		log.Printf("❌ Failed to promote waitlist for tournament %s: %v", tournamentID, err)
+	}
+}
+
+// processWaitlists expires lapsed holds and fills free slots for every tournament with a queue
+func (s *TournamentService) processWaitlists(now time.Time) {
+	if err := s.DB.Model(&models.WaitlistEntry{}).
+		Where("status = ? AND hold_expires_at <= ?", models.WaitlistStatusOffered, now).
+		Update("status", models.WaitlistStatusExpired).Error; err != nil {
+		log.Printf("[Waitlist] DB error: %v", err)
+		return
+	}
+
+	var tournamentIDs []string
+	if err := s.DB.Model(&models.WaitlistEntry{}).
+		Where("status = ?", models.WaitlistStatusWaiting).
+		Distinct().Pluck("tournament_id", &tournamentIDs).Error; err != nil {
+		log.Printf("[Waitlist] DB error: %v", err)
+		return
+	}
+	for _, id := range tournamentIDs {
+		s.releaseSlot(id)
+	}
+}
+
+// GetWaitlist lists a tournament's queue in order, with held offers first
+func (s *TournamentService) GetWaitlist(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+
+	var entries []models.WaitlistEntry
+	if err := s.DB.Where("tournament_id = ? AND status IN ?", tournamentID,
+		[]string{models.WaitlistStatusOffered, models.WaitlistStatusWaiting}).
+		Order("CASE WHEN status = 'offered' THEN 0 ELSE 1 END, queued_at ASC").
+		Find(&entries).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to fetch waitlist", "details": err.Error()})
+	}
+
+	list := make([]fiber.Map, len(entries))
+	position := 0
+	for i, e := range entries {
+		entry := fiber.Map{"entry": e}
+		if e.Status == models.WaitlistStatusWaiting {
+			position++
+			entry["position"] = position
+		}
+		list[i] = entry
+	}
+	return c.JSON(fiber.Map{
+		"tournament_id": tournamentID,
+		"waitlist":      list,
+		"waiting":       position,
+		"offered":       len(entries) - position,
+	})
+}
+
+// LeaveWaitlist takes the caller off the queue; a held slot goes to the next in line
+func (s *TournamentService) LeaveWaitlist(c *fiber.Ctx) error {
+	tournamentID := c.Params("id")
+	userID := c.Locals("user_id").(string)
+
+	var entry models.WaitlistEntry
+	if err := s.DB.Where("tournament_id = ? AND external_user_id = ? AND status IN ?", tournamentID, userID,
+		[]string{models.WaitlistStatusWaiting, models.WaitlistStatusOffered}).
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": ErrNotOnWaitlist.Error()})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error", "details": err.Error()})
+	}
+
+	if err := s.DB.Model(&entry).Update("status", models.WaitlistStatusCancelled).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "failed to leave waitlist", "details": err.Error()})
+	}
+	if entry.Status == models.WaitlistStatusOffered {
+		s.releaseSlot(tournamentID)
+	}
+	return c.JSON(fiber.Map{"message": "left the waitlist"})
+}
+
+// FailSubscriptionPayment marks a pending subscription's payment as failed, freeing its slot
+func (s *TournamentService) FailSubscriptionPayment(c *fiber.Ctx) error {
+	tournamentID := c.Params("tournament_id")
+	userID := c.Params("user_id")
+
+	var sub models.TournamentSubscription
+	if err := s.DB.Where("tournament_id = ? AND external_user_id = ?", tournamentID, userID).First(&sub).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "subscription not found"})
+		}
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
+	if sub.PaymentStatus != "pending" {
+		return c.Status(400).JSON(fiber.Map{
+			"error":   "only 'pending' subscriptions can fail payment",
+			"current": sub.PaymentStatus,
+		})
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"payment_status": "failed",
+		"payment_at":     &now,
+	}
+	if err := s.DB.Model(&sub).Updates(updates).Error; err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "update failed"})
+	}
+	s.releaseSlot(tournamentID)
+
+	s.DB.First(&sub, "id = ?", sub.ID)
+	return c.JSON(fiber.Map{"message": "payment marked as failed", "subscription": sub})
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"io"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"game-publish-system/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+)
+
+func TestParseWaitlistHold(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"", 30, false},
+		{"5", 5, false},
+		{"1440", 1440, false},
+		{"0", 0, true},
+		{"-10", 0, true},
+		{"half an hour", 0, true},
+	}
+
+	app := fiber.New()
+	app.Post("/", func(c *fiber.Ctx) error {
+		n, err := parseWaitlistHold(c)
+		if err != nil {
+			return c.Status(400).SendString(err.Error())
+		}
+		return c.SendString(strconv.Itoa(n))
+	})
+	for _, tt := range tests {
+		t.Run("waitlist_hold_minutes="+tt.value, func(t *testing.T) {
+			form := url.Values{}
+			if tt.value != "" {
+				form.Set("waitlist_hold_minutes", tt.value)
+			}
+			req := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			defer resp.Body.Close()
+			if tt.wantErr {
+				if resp.StatusCode != 400 {
+					t.Errorf("status = %d, want 400", resp.StatusCode)
+				}
+				return
+			}
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if got := string(body); got != strconv.Itoa(tt.want) {
+				t.Errorf("hold = %s, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWaitlistLifecycle walks entries through waiting, offered, expired, re-queued and enrolled
+// as a full tournament's slot frees up
+func TestWaitlistLifecycle(t *testing.T) {
+	db := openTestDB(t, &models.Game{}, &models.Tournament{}, &models.TournamentSubscription{}, &models.WaitlistEntry{})
+	tournament := createTestTournament(t, db, models.Tournament{MaxSubscribers: 1, WaitlistHoldMinutes: 15})
+	service := &TournamentService{DB: db}
+
+	holder := models.TournamentSubscription{
+		ID:             uuid.NewString(),
+		TournamentID:   tournament.ID,
+		ExternalUserID: uuid.NewString(),
+		PaymentStatus:  "paid",
+	}
+	if err := db.Create(&holder).Error; err != nil {
+		t.Fatalf("create subscription: %v", err)
+	}
+
+	users := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	for _, u := range users {
+		if _, err := service.joinWaitlist(tournament.ID, u, "player", nil, ""); err != nil {
+			t.Fatalf("join: %v", err)
+		}
+		time.Sleep(time.Millisecond) // distinct queued_at
+	}
+
+	load := func(userID string) models.WaitlistEntry {
+		t.Helper()
+		var entry models.WaitlistEntry
+		if err := db.Where("tournament_id = ? AND external_user_id = ?", tournament.ID, userID).First(&entry).Error; err != nil {
+			t.Fatalf("load entry: %v", err)
+		}
+		return entry
+	}
+	// expect checks each user's status and queue position, in users order
+	expect := func(step string, statuses []string, positions []int64) {
+		t.Helper()
+		for i, u := range users {
+			entry := load(u)
+			pos, err := waitlistPosition(db, &entry)
+			if err != nil {
+				t.Fatalf("%s: position: %v", step, err)
+			}
+			if entry.Status != statuses[i] || pos != positions[i] {
+				t.Errorf("%s: user %d = %s at %d, want %s at %d", step, i, entry.Status, pos, statuses[i], positions[i])
+			}
+		}
+	}
+	const (
+		waiting  = models.WaitlistStatusWaiting
+		offered  = models.WaitlistStatusOffered
+		expired  = models.WaitlistStatusExpired
+		enrolled = models.WaitlistStatusEnrolled
+	)
+
+	expect("queued", []string{waiting, waiting, waiting}, []int64{1, 2, 3})
+
+	if _, err := service.joinWaitlist(tournament.ID, users[0], "player", nil, ""); err != nil {
+		t.Fatalf("rejoin: %v", err)
+	}
+	expect("rejoined while waiting", []string{waiting, waiting, waiting}, []int64{1, 2, 3})
+
+	if n, err := service.promoteWaitlist(tournament.ID); err != nil || n != 0 {
+		t.Fatalf("promote while full = %d, %v; want 0", n, err)
+	}
+
+	if err := db.Model(&holder).Update("payment_status", "refunded").Error; err != nil {
+		t.Fatalf("free slot: %v", err)
+	}
+	if n, err := service.promoteWaitlist(tournament.ID); err != nil || n != 1 {
+		t.Fatalf("promote = %d, %v; want 1", n, err)
+	}
+	expect("first offered", []string{offered, waiting, waiting}, []int64{0, 1, 2})
+
+	if occupied, err := countOccupiedSlots(db, tournament.ID, ""); err != nil || occupied != 1 {
+		t.Errorf("occupied = %d, %v; want the hold counted", occupied, err)
+	}
+	if occupied, err := countOccupiedSlots(db, tournament.ID, users[0]); err != nil || occupied != 0 {
+		t.Errorf("occupied for the offered user = %d, %v; want their hold left out", occupied, err)
+	}
+
+	service.processWaitlists(time.Now().Add(time.Hour))
+	expect("hold lapsed", []string{expired, offered, waiting}, []int64{0, 0, 1})
+
+	if _, err := service.joinWaitlist(tournament.ID, users[0], "player", nil, ""); err != nil {
+		t.Fatalf("rejoin after expiry: %v", err)
+	}
+	expect("re-queued at the back", []string{waiting, offered, waiting}, []int64{2, 0, 1})
+	if entry := load(users[0]); entry.OfferedAt != nil || entry.HoldExpiresAt != nil {
+		t.Errorf("re-queued entry kept its old offer: %v / %v", entry.OfferedAt, entry.HoldExpiresAt)
+	}
+
+	if err := markWaitlistEnrolled(db, tournament.ID, users[1]); err != nil {
+		t.Fatalf("enroll: %v", err)
+	}
+	expect("offer taken", []string{waiting, enrolled, waiting}, []int64{2, 0, 1})
+	if entry := load(users[1]); entry.EnrolledAt == nil {
+		t.Error("enrolled_at not set")
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"game-publish-system/models"
 	"log"
-	"math"
 	"strings"
 	"time"
 	"github.com/gofiber/fiber/v2"
@@ -19,6 +18,7 @@ type CreateWaiverRequest struct {
 	UserID        string     `json:"user_id" validate:"required"`
 	Code          string     `json:"code" validate:"required"`
 	Amount        float64    `json:"amount" validate:"required,gt=0"`
+	Currency      string     `json:"currency,omitempty"` // defaults to USD
 	Description   string     `json:"description,omitempty"`
 	Title         string     `json:"title,omitempty"`
 	Type          string     `json:"type,omitempty"`
@@ -79,6 +79,13 @@ func (s *TournamentService) CreateWaiver(c *fiber.Ctx) error {
 			"error": "code too long (max 64 characters)",
 		})
 	}
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err := checkAmountPrecision(req.Amount, currency); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
 	// Ensure code uniqueness
 	var count int64
 	if err := s.DB.Model(&models.UserWaiver{}).
@@ -139,8 +146,9 @@ func (s *TournamentService) CreateWaiver(c *fiber.Ctx) error {
 		ID:            uuid.NewString(),  // Generate new UUID for the waiver
 		UserID:        tournamentUser.ID, // ✅ Link to the *local* TournamentUser.ID
 		Code:          code,
-		Amount:        req.Amount,
-		UsedAmount:    0.0,
+		Amount:        minorUnits(req.Amount, currency),
+		Currency:      currency,
+		UsedAmount:    0,
 		Description:   req.Description,
 		Title:         req.Title,
 		Type:          req.Type,
@@ -155,7 +163,23 @@ func (s *TournamentService) CreateWaiver(c *fiber.Ctx) error {
 		ExpiresAt:     req.ExpiresAt, // Can be nil, set later on first use or as hard expiry
 		IssuedByID:    issuedByTournamentUserID, // ✅ Store the *local* TournamentUser.ID of the issuer
 	}
-	if err := s.DB.Create(waiver).Error; err != nil {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(waiver).Error; err != nil {
+			return err
+		}
+		return postLedger(tx, ledgerEntry{
+			Kind:           models.LedgerKindWaiverIssued,
+			Currency:       waiver.Currency,
+			IdempotencyKey: "waiver_issued:" + waiver.ID,
+			Description:    fmt.Sprintf("waiver %s issued", waiver.Code),
+			Actor:          issuedByTournamentUserID,
+			Legs: []ledgerLeg{
+				debit(ledgerPromotions, waiver.Amount),
+				credit(ledgerWaiverLiability, waiver.Amount),
+			},
+		})
+	})
+	if err != nil {
 		log.Printf("DB error creating waiver: %v", err) // Add logging
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error":   "failed to create waiver",
@@ -170,7 +194,7 @@ func (s *TournamentService) CreateWaiver(c *fiber.Ctx) error {
 func (s *TournamentService) getUserAvailableWaivers(userID string) ([]models.UserWaiver, error) {
 	var waivers []models.UserWaiver
 	now := time.Now()
-	query := s.DB.Where("user_id = ? AND is_active = true AND used_amount < amount", userID)
+	query := s.DB.Where("user_id = ? AND is_active = true AND used_amount_minor < amount_minor", userID)
 	query = query.Where("expires_at IS NULL OR expires_at > ?", now)
 	if err := query.Find(&waivers).Error; err != nil {
 		return nil, err
@@ -186,7 +210,7 @@ func (s *TournamentService) RedeemWaiver(c *fiber.Ctx) error {
 	}
 	// Fetch tournament to check waiver eligibility
 	var tournament models.Tournament
-	if err := s.DB.Select("id, accepts_waivers").First(&tournament, "id = ?", req.TournamentID).Error; err != nil {
+	if err := s.DB.Select("id, accepts_waivers, currency").First(&tournament, "id = ?", req.TournamentID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return c.Status(404).JSON(fiber.Map{"error": "tournament not found"})
 		}
@@ -209,13 +233,16 @@ func (s *TournamentService) RedeemWaiver(c *fiber.Ctx) error {
 	if waiver.ExpiresAt != nil && waiver.ExpiresAt.Before(time.Now()) {
 		return c.Status(400).JSON(fiber.Map{"error": "waiver has expired (hard expiry)"})
 	}
+	if waiver.Currency != tournament.Currency {
+		return c.Status(400).JSON(fiber.Map{"error": fmt.Sprintf("waiver is in %s but the tournament charges %s", waiver.Currency, tournament.Currency)})
+	}
 	// Check remaining balance
 	remaining := waiver.Amount - waiver.UsedAmount
 	if remaining <= 0 {
 		return c.Status(400).JSON(fiber.Map{"error": "waiver is fully used"})
 	}
-	// Clamp amount to remaining & to requested
-	amountToApply := math.Min(req.AmountToUse, remaining)
+	// Clamp amount to remaining & to requested, at the currency's precision
+	amountToApply := min(minorUnits(req.AmountToUse, waiver.Currency), remaining)
 
 	// 🔁 Atomic update
 	err := s.DB.Transaction(func(tx *gorm.DB) error {
@@ -234,7 +261,7 @@ func (s *TournamentService) RedeemWaiver(c *fiber.Ctx) error {
 		// Determine if this is the first use
 		isFirstUse := waiver.UsedAmount == 0 && amountToApply > 0
 		updates := map[string]interface{}{
-			"used_amount": newUsed,
+			"used_amount_minor": newUsed,
 		}
 
 		// If first use, mark as redeemed and potentially set expiry based on duration
@@ -260,7 +287,17 @@ func (s *TournamentService) RedeemWaiver(c *fiber.Ctx) error {
 			Updates(updates).Error; err != nil {
 			return err
 		}
-		return nil
+		return postLedger(tx, ledgerEntry{
+			Kind:         models.LedgerKindWaiverApplied,
+			Currency:     tournament.Currency,
+			TournamentID: tournament.ID,
+			Description:  fmt.Sprintf("waiver %s redeemed", waiver.Code),
+			Actor:        req.UserID,
+			Legs: []ledgerLeg{
+				debit(ledgerWaiverLiability, amountToApply),
+				credit(poolLedgerAccount(tournament.ID), amountToApply),
+			},
+		})
 	})
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{"error": "failed to redeem waiver", "details": err.Error()})
@@ -272,8 +309,9 @@ func (s *TournamentService) RedeemWaiver(c *fiber.Ctx) error {
 	return c.JSON(fiber.Map{
 		"message":        "waiver redeemed successfully",
 		"waiver_code":    waiver.Code,
-		"amount_applied": amountToApply,
-		"remaining":      waiver.Amount - waiver.UsedAmount,
+		"amount_applied": majorUnits(amountToApply, waiver.Currency),
+		"currency":       waiver.Currency,
+		"remaining":      majorUnits(waiver.Amount-waiver.UsedAmount, waiver.Currency),
 		"waiver":         waiver,
 	})
 }
@@ -297,6 +335,7 @@ func (s *TournamentService) GetUserAvailableWaiversEndpoint(c *fiber.Ctx) error
 		ID          string     `json:"id"`
 		Code        string     `json:"code"`
 		Amount      float64    `json:"amount"`
+		Currency    string     `json:"currency"`
 		UsedAmount  float64    `json:"used_amount"`
 		Remaining   float64    `json:"remaining"`
 		Description string     `json:"description,omitempty"`
@@ -307,21 +346,24 @@ func (s *TournamentService) GetUserAvailableWaiversEndpoint(c *fiber.Ctx) error
 		response[i] = WaiverSummary{
 			ID:          w.ID,
 			Code:        w.Code,
-			Amount:      w.Amount,
-			UsedAmount:  w.UsedAmount,
-			Remaining:   w.Amount - w.UsedAmount,
+			Amount:      majorUnits(w.Amount, w.Currency),
+			Currency:    w.Currency,
+			UsedAmount:  majorUnits(w.UsedAmount, w.Currency),
+			Remaining:   majorUnits(w.Amount-w.UsedAmount, w.Currency),
 			Description: w.Description,
 			ExpiresAt:   w.ExpiresAt,
 		}
 	}
 	hasWaiver := len(response) > 0
-	totalAvailable := 0.0
-	for _, w := range response {
-		totalAvailable += w.Remaining
+	// Balances in different currencies don't add up; total_available stays in the default currency
+	byCurrency := map[string]int64{}
+	for _, w := range waivers {
+		byCurrency[w.Currency] += w.Amount - w.UsedAmount
 	}
 	return c.JSON(fiber.Map{
 		"has_waiver":      hasWaiver,
-		"total_available": totalAvailable,
+		"total_available": majorUnits(byCurrency[models.DefaultCurrency], models.DefaultCurrency),
+		"by_currency":     majorTotals(byCurrency),
 		"count":           len(response),
 		"waivers":         response,
 	})
@@ -359,11 +401,9 @@ func (s *TournamentService) UpdateWaiver(c *fiber.Ctx) error {
 		}
 		updates["code"] = code
 	}
-	if req.Amount != nil {
-		if *req.Amount <= 0 {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "amount must be > 0"})
-		}
-		updates["amount"] = *req.Amount
+	// amount is converted to minor units once the waiver's currency is loaded below
+	if req.Amount != nil && *req.Amount <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "amount must be > 0"})
 	}
 	if req.Description != nil {
 		updates["description"] = *req.Description
@@ -398,12 +438,48 @@ func (s *TournamentService) UpdateWaiver(c *fiber.Ctx) error {
 	}
 	// Note: IsRedeemed is typically not updated directly by admin
 
-	if len(updates) == 0 {
+	if len(updates) == 0 && req.Amount == nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "no fields to update"})
 	}
 
-	// Perform the update
-	if err := s.DB.Model(&models.UserWaiver{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+	// Perform the update; an amount change adjusts waiver liability by the difference
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var before models.UserWaiver
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&before, "id = ?", id).Error; err != nil {
+			return err
+		}
+		var amount int64
+		if req.Amount != nil {
+			if err := checkAmountPrecision(*req.Amount, before.Currency); err != nil {
+				return err
+			}
+			amount = minorUnits(*req.Amount, before.Currency)
+			updates["amount_minor"] = amount
+		}
+		if err := tx.Model(&models.UserWaiver{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Amount == nil {
+			return nil
+		}
+		delta := amount - before.Amount
+		return postLedger(tx, ledgerEntry{
+			Kind:        models.LedgerKindWaiverAdjusted,
+			Currency:    before.Currency,
+			Description: fmt.Sprintf("waiver %s amount %s → %s", before.Code, formatAmount(before.Amount, before.Currency), formatAmount(amount, before.Currency)),
+			Legs: []ledgerLeg{
+				debit(ledgerPromotions, delta),
+				credit(ledgerWaiverLiability, delta),
+			},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.Status(404).JSON(fiber.Map{"error": "waiver not found"})
+		}
+		if errors.Is(err, ErrInvalidAmount) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "update failed", "details": err.Error()})
 	}
 
@@ -422,13 +498,32 @@ func (s *TournamentService) DeleteWaiver(c *fiber.Ctx) error {
 	if id == "" {
 		return c.Status(400).JSON(fiber.Map{"error": "id required"})
 	}
-	result := s.DB.Delete(&models.UserWaiver{}, "id = ?", id)
-	if result.Error != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
-	}
-	if result.RowsAffected == 0 {
+	// The unspent balance stops being owed once the waiver is gone
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var w models.UserWaiver
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&w, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&w).Error; err != nil {
+			return err
+		}
+		unspent := max(w.Amount-w.UsedAmount, 0)
+		return postLedger(tx, ledgerEntry{
+			Kind:        models.LedgerKindWaiverAdjusted,
+			Currency:    w.Currency,
+			Description: fmt.Sprintf("waiver %s deleted", w.Code),
+			Legs: []ledgerLeg{
+				debit(ledgerWaiverLiability, unspent),
+				credit(ledgerPromotions, unspent),
+			},
+		})
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return c.Status(404).JSON(fiber.Map{"error": "waiver not found"})
 	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "DB error"})
+	}
 
 	return c.JSON(fiber.Map{"message": "waiver deleted"})
 }
@@ -619,9 +714,9 @@ func (s *TournamentService) MarkWaiverAsRedeemedEndpoint(c *fiber.Ctx) error {
 	}
 
 	updates := map[string]interface{}{
-		"is_redeemed": true,
-		"used_amount": waiver.Amount, // Mark as fully used
-		"updated_at":  time.Now(),
+		"is_redeemed":       true,
+		"used_amount_minor": waiver.Amount, // Mark as fully used
+		"updated_at":        time.Now(),
 	}
 	// If not already set, set expiry based on duration if applicable
 	// This handles the case where an admin marks it as fully redeemed without the user ever redeeming it partially
@@ -683,10 +778,10 @@ func (s *TournamentService) GetUserWaiversEndpoint(c *fiber.Ctx) error {
 		switch statusFilter {
 		case "active":
 			// Active: is_active, not expired (against hard expiry), not fully used
-			query = query.Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?) AND used_amount < amount AND is_redeemed = ?", true, time.Now(), false)
+			query = query.Where("is_active = ? AND (expires_at IS NULL OR expires_at > ?) AND used_amount_minor < amount_minor AND is_redeemed = ?", true, time.Now(), false)
 		case "used", "redeemed":
 			// Redeemed: used at least once OR marked as redeemed
-			query = query.Where("used_amount > 0 OR is_redeemed = ?", true)
+			query = query.Where("used_amount_minor > 0 OR is_redeemed = ?", true)
 		case "expired":
 			// Expired: is_active, but hard expiry date passed
 			query = query.Where("is_active = ? AND expires_at IS NOT NULL AND expires_at <= ?", true, time.Now())
@@ -748,4 +843,38 @@ func (s *TournamentService) MarkWaiverAsClaimedEndpoint(c *fiber.Ctx) error {
 		"message": "waiver marked as claimed",
 		"waiver":  waiver,
 	})
-}
\ No newline at end of file
+}
+
+// restoreWaiverBalance gives back the waiver value a subscription consumed, moving it from the
+// tournament pool back to waiver liability
+func restoreWaiverBalance(tx *gorm.DB, sub models.TournamentSubscription, reason string) error {
+	amount := sub.WaiverAmountUsed
+	if sub.WaiverIDUsed == "" || amount <= 0 {
+		return nil
+	}
+	var w models.UserWaiver
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&w, "id = ?", sub.WaiverIDUsed).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	used := max(w.UsedAmount-amount, 0)
+	if err := tx.Model(&w).Updates(map[string]interface{}{
+		"used_amount_minor": used,
+		"is_redeemed":       used > 0,
+	}).Error; err != nil {
+		return err
+	}
+	return postLedger(tx, ledgerEntry{
+		Kind:           models.LedgerKindWaiverRestored,
+		Currency:       sub.Currency,
+		TournamentID:   sub.TournamentID,
+		SubscriptionID: sub.ID,
+		Description:    fmt.Sprintf("waiver %s restored: %s", w.Code, reason),
+		Legs: []ledgerLeg{
+			debit(poolLedgerAccount(sub.TournamentID), amount),
+			credit(ledgerWaiverLiability, amount),
+		},
+	})
+}
@@ -77,4 +77,17 @@ func UploadFileToR2(fileHeader *multipart.FileHeader, key string) (string, error
 	// ✅ Return public CDN URL (prefer your custom CDN if set)
 	url := fmt.Sprintf("%s/%s", cdnBaseURL, key)
 	return url, nil
+}
+
+// DeleteFileFromR2 removes an object uploaded by UploadFileToR2, e.g. when the record that
+// would have referenced it was never saved
+func DeleteFileFromR2(key string) error {
+	_, err := r2Client.DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(r2Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete from R2: %w", err)
+	}
+	return nil
 }
\ No newline at end of file